@@ -2,12 +2,14 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"io/fs"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/robfig/cron/v3"
@@ -38,6 +40,11 @@ func main() {
 		log.Fatalf("db init: %v", err)
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "import-xui" {
+		runImportXUI(cfg, os.Args[2:])
+		return
+	}
+
 	if os.Getenv("V2RAY_API_ENABLED") == "true" {
 		addr := os.Getenv("V2RAY_API_LISTEN")
 		if addr == "" {
@@ -58,6 +65,9 @@ func main() {
 		log.Printf("[stats] cron started, polling every %ds", intervalSec)
 	}
 
+	go core.RunLogRotator(context.Background(), config.LogPath(cfg.DataDir), core.LogRotationFromEnv(), time.Minute)
+	go api.RunRuleSetRefresher(context.Background(), cfg, 10*time.Minute)
+
 	secure := os.Getenv("FORCE_HTTPS") == "true" || os.Getenv("FORCE_HTTPS") == "1"
 	sm, err := session.NewManager(db.DB, secure)
 	if err != nil {
@@ -79,3 +89,19 @@ func main() {
 	log.Printf("listening on %s", cfg.Addr)
 	log.Fatal(http.ListenAndServe(cfg.Addr, handler))
 }
+
+// runImportXUI implements "import-xui <x-ui.db>": it imports an x-ui / 3x-ui database
+// into the panel database, applies the config and prints the import report as JSON.
+func runImportXUI(cfg *config.Config, args []string) {
+	if len(args) != 1 {
+		log.Fatalf("usage: %s import-xui <path to x-ui.db>", filepath.Base(os.Args[0]))
+	}
+	report, err := api.ImportXUIDatabase(cfg, args[0])
+	if err != nil {
+		log.Fatalf("import-xui: %v", err)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(report)
+	log.Printf("import-xui: config written; restart the panel or the core to apply it")
+}
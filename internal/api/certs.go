@@ -153,16 +153,14 @@ func UpdateCertificateHandler(sm *scs.SessionManager, panelCfg *config.Config) h
 			return
 		}
 		pm := core.NewProcessManagerFromConfig(panelCfg)
-		if err := core.ApplyConfig(path, cfg, pm); err != nil {
+		if err := core.ApplyConfig(path, cfg, pm, "cert update"); err != nil {
 			db.UpdateCertificate(old)
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 			return
 		}
-		if err := pm.Restart(path); err != nil {
-			// Config applied; restart failure is best-effort
-		}
+		w.Header().Set(coreApplyHeader, reloadCore(pm, path))
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(certFromDB(c))
 	}
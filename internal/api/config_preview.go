@@ -0,0 +1,253 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/s-ui/s-ui/internal/config"
+	"github.com/s-ui/s-ui/internal/core"
+	"github.com/s-ui/s-ui/internal/db"
+)
+
+// configPreviewRequest is the POST body for /api/core/config/preview. Each part is
+// optional but at least one is required; they are staged together.
+type configPreviewRequest struct {
+	Inbound     *inboundPreview     `json:"inbound"`
+	User        *userPreview        `json:"user"`
+	Certificate *certificatePreview `json:"certificate"`
+}
+
+// inboundPreview is an inbound create (id 0) or update, in the create/update body shape.
+type inboundPreview struct {
+	ID uint `json:"id"`
+	inboundCreateRequest
+}
+
+// userPreview replaces a user's inbound assignment.
+type userPreview struct {
+	ID         uint   `json:"id"`
+	InboundIDs []uint `json:"inbound_ids"`
+}
+
+// certificatePreview is a certificate edit in the update body shape.
+type certificatePreview struct {
+	ID uint `json:"id"`
+	certUpdateRequest
+}
+
+// configPreviewResponse carries the generated config, whether sing-box check accepted
+// it, and a unified diff from the live config file.
+type configPreviewResponse struct {
+	Config json.RawMessage `json:"config"`
+	Valid  bool            `json:"valid"`
+	Error  string          `json:"error,omitempty"`
+	Diff   string          `json:"diff"`
+}
+
+// previewRequestError is a validation failure with its HTTP status.
+type previewRequestError struct {
+	status int
+	msg    string
+}
+
+func (e *previewRequestError) Error() string {
+	return e.msg
+}
+
+// prepare validates the previewed inbound like Create/UpdateInboundHandler and
+// returns the row to stage.
+func (p *inboundPreview) prepare() (*db.Inbound, error) {
+	var old *db.Inbound
+	if p.ID != 0 {
+		var err error
+		if old, err = db.GetInboundByID(p.ID); err != nil {
+			return nil, &previewRequestError{http.StatusNotFound, "inbound not found"}
+		}
+	}
+	if p.Tag == "" || p.Protocol == "" {
+		return nil, &previewRequestError{http.StatusBadRequest, "tag and protocol required"}
+	}
+	if core.IsReservedInboundTag(p.Tag) {
+		return nil, &previewRequestError{http.StatusBadRequest, "tag is reserved for internal inbounds"}
+	}
+	configJSON := p.ConfigJSON
+	if old != nil {
+		var err error
+		if configJSON, err = carryInboundSecrets(old, p.Protocol, configJSON); err != nil {
+			return nil, &previewRequestError{http.StatusBadRequest, err.Error()}
+		}
+	}
+	configJSON, err := prepareInboundConfig(p.Protocol, configJSON)
+	if err != nil {
+		return nil, &previewRequestError{http.StatusBadRequest, err.Error()}
+	}
+	if err := validateInbound(p.Protocol, configJSON); err != nil {
+		return nil, &previewRequestError{http.StatusBadRequest, err.Error()}
+	}
+	if old == nil || p.Tag != old.Tag {
+		exists, err := db.InboundExistsByTag(p.Tag)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, &previewRequestError{http.StatusBadRequest, "tag already exists"}
+		}
+	}
+	listen := p.Listen
+	if listen == "" {
+		listen = "::"
+	}
+	return &db.Inbound{
+		ID:         p.ID,
+		Tag:        p.Tag,
+		Protocol:   p.Protocol,
+		Listen:     listen,
+		ListenPort: p.ListenPort,
+		ConfigJSON: configJSON,
+	}, nil
+}
+
+// prepare validates the previewed certificate like UpdateCertificateHandler.
+func (p *certificatePreview) prepare() (*db.Certificate, error) {
+	if _, err := db.GetCertificateByID(p.ID); err != nil {
+		return nil, &previewRequestError{http.StatusNotFound, "certificate not found"}
+	}
+	if strings.TrimSpace(p.FullchainPath) == "" || strings.TrimSpace(p.PrivkeyPath) == "" {
+		return nil, &previewRequestError{http.StatusBadRequest, "fullchain_path and privkey_path required"}
+	}
+	return &db.Certificate{
+		ID:            p.ID,
+		Name:          strings.TrimSpace(p.Name),
+		FullchainPath: strings.TrimSpace(p.FullchainPath),
+		PrivkeyPath:   strings.TrimSpace(p.PrivkeyPath),
+	}, nil
+}
+
+// generatePreview stages the request in a transaction that is rolled back and
+// returns the config Generate renders from it.
+func generatePreview(req *configPreviewRequest) ([]byte, error) {
+	var inbound *db.Inbound
+	var cert *db.Certificate
+	var err error
+	if req.Inbound != nil {
+		if inbound, err = req.Inbound.prepare(); err != nil {
+			return nil, err
+		}
+	}
+	if req.User != nil {
+		if _, err := db.GetUserByID(req.User.ID); err != nil {
+			return nil, &previewRequestError{http.StatusNotFound, "user not found"}
+		}
+	}
+	if req.Certificate != nil {
+		if cert, err = req.Certificate.prepare(); err != nil {
+			return nil, err
+		}
+	}
+	var out []byte
+	err = db.WithRollback(func(c db.Conn) error {
+		if inbound != nil && inbound.ID == 0 {
+			if err := c.CreateInbound(inbound); err != nil {
+				return err
+			}
+		} else if inbound != nil {
+			if err := c.UpdateInbound(inbound); err != nil {
+				return err
+			}
+		}
+		if req.User != nil {
+			if err := c.ReplaceUserInbounds(req.User.ID, req.User.InboundIDs); err != nil {
+				return err
+			}
+		}
+		if cert != nil {
+			if err := c.UpdateCertificate(cert); err != nil {
+				return err
+			}
+		}
+		gen := &core.ConfigGenerator{DB: c}
+		cfg, err := gen.Generate()
+		out = cfg
+		return err
+	})
+	return out, err
+}
+
+// checkPreview runs sing-box check on cfg from a temp file beside the live config, so
+// relative paths resolve the same way; it returns the check error, if any.
+func checkPreview(panelCfg *config.Config, cfg []byte) (checkErr, err error) {
+	dir := filepath.Dir(configPath(panelCfg))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.New("failed to create config dir")
+	}
+	f, err := os.CreateTemp(dir, "preview-*.json")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(cfg)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, checkErr = core.NewProcessManagerFromConfig(panelCfg).Check(f.Name())
+	return checkErr, nil
+}
+
+// PreviewConfigHandler handles POST /api/core/config/preview. It renders the config
+// an inbound create/update, user inbound assignment and/or certificate edit would
+// produce, without committing them or touching the live config, runs sing-box check
+// on it and diffs it against the live config file.
+func PreviewConfigHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req configPreviewRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if req.Inbound == nil && req.User == nil && req.Certificate == nil {
+			http.Error(w, "inbound, user or certificate required", http.StatusBadRequest)
+			return
+		}
+		cfg, err := generatePreview(&req)
+		var reqErr *previewRequestError
+		var overlayErr *core.OverlayError
+		switch {
+		case errors.As(err, &reqErr):
+			http.Error(w, reqErr.msg, reqErr.status)
+			return
+		case errors.As(err, &overlayErr):
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		checkErr, err := checkPreview(panelCfg, cfg)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		live, err := os.ReadFile(configPath(panelCfg))
+		if err != nil && !os.IsNotExist(err) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp := configPreviewResponse{
+			Config: cfg,
+			Valid:  checkErr == nil,
+			Diff:   core.UnifiedDiff("live", "preview", live, cfg),
+		}
+		if checkErr != nil {
+			resp.Error = checkErr.Error()
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
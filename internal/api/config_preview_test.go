@@ -0,0 +1,114 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/s-ui/s-ui/internal/db"
+	"gorm.io/datatypes"
+)
+
+func TestPreviewConfigHandler(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	dir := t.TempDir()
+	okBinary := filepath.Join(dir, "ok")
+	failBinary := filepath.Join(dir, "fail")
+	if err := os.WriteFile(okBinary, []byte("#!/bin/sh\n[ \"$1\" = check ] && exit 0\nexit 1\n"), 0755); err != nil {
+		t.Fatalf("write fake binary: %v", err)
+	}
+	if err := os.WriteFile(failBinary, []byte("#!/bin/sh\necho bad listen port\nexit 1\n"), 0755); err != nil {
+		t.Fatalf("write fake binary: %v", err)
+	}
+	existing := &db.Inbound{Tag: "vless-in", Protocol: "vless", Listen: "::", ListenPort: 443}
+	if err := db.CreateInbound(existing); err != nil {
+		t.Fatalf("CreateInbound: %v", err)
+	}
+	ss := &db.Inbound{Tag: "ss-in", Protocol: "shadowsocks", Listen: "::", ListenPort: 8388,
+		ConfigJSON: datatypes.JSON(`{"method":"2022-blake3-aes-128-gcm","password":"AAAAAAAAAAAAAAAAAAAAAA=="}`)}
+	if err := db.CreateInbound(ss); err != nil {
+		t.Fatalf("CreateInbound: %v", err)
+	}
+	user := &db.User{Name: "alice"}
+	if err := db.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	cases := []struct {
+		name       string
+		binary     string
+		body       string
+		wantStatus int
+		wantValid  bool
+		wantDiff   []string
+		wantConfig string // substring of the previewed config
+	}{
+		{name: "inbound_create", binary: okBinary,
+			body:       `{"inbound":{"tag":"vmess-in","protocol":"vmess","listen_port":8443}}`,
+			wantStatus: http.StatusOK, wantValid: true, wantDiff: []string{`+      "tag": "vmess-in"`}},
+		{name: "user_assignment", binary: okBinary,
+			body:       `{"user":{"id":` + fmt.Sprint(user.ID) + `,"inbound_ids":[` + fmt.Sprint(existing.ID) + `]}}`,
+			wantStatus: http.StatusOK, wantValid: true, wantDiff: []string{`+          "name": "alice"`}},
+		{name: "check_failure", binary: failBinary,
+			body:       `{"inbound":{"id":` + fmt.Sprint(existing.ID) + `,"tag":"vless-in","protocol":"vless","listen_port":1}}`,
+			wantStatus: http.StatusOK, wantValid: false, wantDiff: []string{`+      "listen_port": 1`}},
+		{name: "keeps_stored_psk", binary: okBinary,
+			body:       `{"inbound":{"id":` + fmt.Sprint(ss.ID) + `,"tag":"ss-in","protocol":"shadowsocks","listen_port":8389}}`,
+			wantStatus: http.StatusOK, wantValid: true, wantDiff: []string{`+      "listen_port": 8389`}, wantConfig: `"password":"AAAAAAAAAAAAAAAAAAAAAA=="`},
+		{name: "empty", binary: okBinary, body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "tag_exists", binary: okBinary, body: `{"inbound":{"tag":"vless-in","protocol":"vless"}}`, wantStatus: http.StatusBadRequest},
+		{name: "missing_user", binary: okBinary, body: `{"user":{"id":999}}`, wantStatus: http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testCoreConfig(t, tc.binary)
+			rec := httptest.NewRecorder()
+			PreviewConfigHandler(nil, cfg)(rec, httptest.NewRequest(http.MethodPost, "/api/core/config/preview", strings.NewReader(tc.body)))
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, body=%s", rec.Code, rec.Body.String())
+			}
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+			var resp configPreviewResponse
+			decodeJSON(t, rec, &resp)
+			if resp.Valid != tc.wantValid || (!resp.Valid && !strings.Contains(resp.Error, "bad listen port")) {
+				t.Fatalf("valid = %v, error = %q", resp.Valid, resp.Error)
+			}
+			if !strings.HasPrefix(resp.Diff, "--- live\n+++ preview\n") {
+				t.Fatalf("diff = %q", resp.Diff)
+			}
+			for _, want := range tc.wantDiff {
+				if !strings.Contains(resp.Diff, want) {
+					t.Fatalf("diff missing %q:\n%s", want, resp.Diff)
+				}
+			}
+			if !strings.Contains(string(resp.Config), tc.wantConfig) {
+				t.Fatalf("config missing %q: %s", tc.wantConfig, resp.Config)
+			}
+			live, _ := os.ReadFile(cfg.SingboxConfigPath)
+			if string(live) != `{"log":{}}` {
+				t.Fatalf("live config changed: %s", live)
+			}
+		})
+	}
+
+	if exists, _ := db.InboundExistsByTag("vmess-in"); exists {
+		t.Fatal("previewed inbound was committed")
+	}
+	if ib, _ := db.GetInboundByID(existing.ID); ib.ListenPort != 443 {
+		t.Fatalf("previewed inbound update was committed: port %d", ib.ListenPort)
+	}
+	if u, _ := db.GetUserByID(user.ID); len(u.Inbounds) != 0 {
+		t.Fatalf("previewed user assignment was committed: %+v", u.Inbounds)
+	}
+	if revs, _ := db.ListConfigRevisions(); len(revs) != 0 {
+		t.Fatalf("preview recorded revisions: %+v", revs)
+	}
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/s-ui/s-ui/internal/config"
+	"github.com/s-ui/s-ui/internal/core"
+	"github.com/s-ui/s-ui/internal/db"
+)
+
+// configRevisionItem is the API response shape for config revisions; the config
+// bodies are only included for a single revision.
+type configRevisionItem struct {
+	ID             uint   `json:"id"`
+	Source         string `json:"source"`
+	CreatedAt      string `json:"created_at"`
+	PreviousConfig string `json:"previous_config,omitempty"`
+	Config         string `json:"config,omitempty"`
+}
+
+func configRevisionFromDB(rev *db.ConfigRevision) configRevisionItem {
+	return configRevisionItem{
+		ID:        rev.ID,
+		Source:    rev.Source,
+		CreatedAt: rev.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// ListConfigRevisionsHandler returns GET /api/core/config/revisions handler.
+func ListConfigRevisionsHandler(sm *scs.SessionManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		revs, err := db.ListConfigRevisions()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		items := make([]configRevisionItem, len(revs))
+		for i := range revs {
+			items[i] = configRevisionFromDB(&revs[i])
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": items})
+	}
+}
+
+// GetConfigRevisionHandler returns GET /api/core/config/revisions/:id handler.
+func GetConfigRevisionHandler(sm *scs.SessionManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := parseIDParam(r)
+		if !ok {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		rev, err := db.GetConfigRevisionByID(id)
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		item := configRevisionFromDB(rev)
+		item.PreviousConfig = rev.PreviousConfig
+		item.Config = rev.Config
+		writeJSON(w, http.StatusOK, item)
+	}
+}
+
+// loadRevisionQuery reads revision id query parameter key, writing 400/404 on failure.
+func loadRevisionQuery(w http.ResponseWriter, r *http.Request, key string) (*db.ConfigRevision, bool) {
+	id, err := strconv.ParseUint(r.URL.Query().Get(key), 10, 32)
+	if err != nil {
+		http.Error(w, "invalid "+key, http.StatusBadRequest)
+		return nil, false
+	}
+	rev, err := db.GetConfigRevisionByID(uint(id))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("revision %d not found", id), http.StatusNotFound)
+		return nil, false
+	}
+	return rev, true
+}
+
+// DiffConfigRevisionsHandler handles GET /api/core/config/revisions/diff?from=&to=. It
+// returns a unified diff from revision from's config to revision to's; without from,
+// it diffs revision to against the file it replaced.
+func DiffConfigRevisionsHandler(sm *scs.SessionManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		to, ok := loadRevisionQuery(w, r, "to")
+		if !ok {
+			return
+		}
+		fromName, fromConfig := fmt.Sprintf("revision %d (previous)", to.ID), to.PreviousConfig
+		if r.URL.Query().Get("from") != "" {
+			from, ok := loadRevisionQuery(w, r, "from")
+			if !ok {
+				return
+			}
+			fromName, fromConfig = fmt.Sprintf("revision %d", from.ID), from.Config
+		}
+		diff := core.UnifiedDiff(fromName, fmt.Sprintf("revision %d", to.ID), []byte(fromConfig), []byte(to.Config))
+		writeJSON(w, http.StatusOK, map[string]string{"diff": diff})
+	}
+}
+
+// RestoreConfigRevisionHandler handles POST /api/core/config/revisions/:id/restore. The
+// revision's config is checked and applied again, recorded as a new revision; like
+// POST /api/core/config it lasts until the next panel change regenerates the config.
+func RestoreConfigRevisionHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := parseIDParam(r)
+		if !ok {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		rev, err := db.GetConfigRevisionByID(id)
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		applied, err := installConfig(panelCfg, []byte(rev.Config), fmt.Sprintf("restore #%d", rev.ID))
+		if err != nil {
+			writeApplyError(w, err)
+			return
+		}
+		w.Header().Set(coreApplyHeader, applied)
+		writeJSON(w, http.StatusOK, map[string]string{"ok": "true"})
+	}
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/s-ui/s-ui/internal/db"
+)
+
+func TestConfigRevisionHandlers(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	binary := filepath.Join(t.TempDir(), "sing-box")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\n[ \"$1\" = check ] && exit 0\nexit 1\n"), 0755); err != nil {
+		t.Fatalf("write fake binary: %v", err)
+	}
+	cfg := testCoreConfig(t, binary)
+	r := chi.NewRouter()
+	r.Post("/api/core/config", ConfigHandler(nil, cfg))
+	r.Get("/api/core/config/revisions", ListConfigRevisionsHandler(nil))
+	r.Get("/api/core/config/revisions/diff", DiffConfigRevisionsHandler(nil))
+	r.Get("/api/core/config/revisions/{id}", GetConfigRevisionHandler(nil))
+	r.Post("/api/core/config/revisions/{id}/restore", RestoreConfigRevisionHandler(nil, cfg))
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	manual := "{\n  \"log\": {\n    \"level\": \"warn\"\n  }\n}"
+	if rec := do(http.MethodPost, "/api/core/config", manual); rec.Code != http.StatusOK || rec.Header().Get(coreApplyHeader) == "" {
+		t.Fatalf("manual config status = %d, %s = %q, body=%s", rec.Code, coreApplyHeader, rec.Header().Get(coreApplyHeader), rec.Body.String())
+	}
+	if !applyGeneratedConfig(httptest.NewRecorder(), cfg, "inbound create", nil) {
+		t.Fatal("applyGeneratedConfig failed")
+	}
+
+	rec := do(http.MethodGet, "/api/core/config/revisions", "")
+	var list struct {
+		Data []configRevisionItem `json:"data"`
+	}
+	decodeJSON(t, rec, &list)
+	if len(list.Data) != 2 || list.Data[0].Source != "inbound create" || list.Data[1].Source != "manual" || list.Data[0].Config != "" {
+		t.Fatalf("revisions = %+v", list.Data)
+	}
+	first, second := list.Data[1].ID, list.Data[0].ID
+
+	var detail configRevisionItem
+	decodeJSON(t, do(http.MethodGet, "/api/core/config/revisions/"+fmt.Sprint(first), ""), &detail)
+	if detail.PreviousConfig != `{"log":{}}` || detail.Config != manual {
+		t.Fatalf("revision detail = %+v", detail)
+	}
+
+	var diff struct {
+		Diff string `json:"diff"`
+	}
+	decodeJSON(t, do(http.MethodGet, "/api/core/config/revisions/diff?from="+fmt.Sprint(first)+"&to="+fmt.Sprint(second), ""), &diff)
+	if !strings.HasPrefix(diff.Diff, "--- revision "+fmt.Sprint(first)+"\n+++ revision "+fmt.Sprint(second)+"\n@@") || !strings.Contains(diff.Diff, `-    "level": "warn"`) {
+		t.Fatalf("diff = %q", diff.Diff)
+	}
+	if rec := do(http.MethodGet, "/api/core/config/revisions/diff?to=999", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("missing revision status = %d", rec.Code)
+	}
+
+	if rec := do(http.MethodPost, "/api/core/config/revisions/"+fmt.Sprint(first)+"/restore", ""); rec.Code != http.StatusOK {
+		t.Fatalf("restore status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	written, _ := os.ReadFile(cfg.SingboxConfigPath)
+	if string(written) != manual {
+		t.Fatalf("config after restore = %s", written)
+	}
+	revs, _ := db.ListConfigRevisions()
+	if len(revs) != 3 || revs[0].Source != "restore #"+fmt.Sprint(first) {
+		t.Fatalf("revisions after restore = %+v", revs)
+	}
+}
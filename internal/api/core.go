@@ -78,6 +78,113 @@ func writeProcessError(w http.ResponseWriter, err error) {
 	writeCoreError(w, http.StatusInternalServerError, "CORE_INTERNAL_ERROR", "unexpected core error", err.Error())
 }
 
+// configRejectedError marks a generated config that ApplyConfig refused.
+type configRejectedError struct {
+	err error
+}
+
+func (e *configRejectedError) Error() string {
+	return e.err.Error()
+}
+
+// regenerateConfig regenerates the sing-box config from DB and installs it, returning
+// the X-Core-Apply value. A refused config or an overlay conflict is a *configRejectedError.
+func regenerateConfig(panelCfg *config.Config, source string) (string, error) {
+	cfg, err := generateConfig()
+	if err != nil {
+		return "", err
+	}
+	return installConfig(panelCfg, cfg, source)
+}
+
+// writeGeneratedConfig regenerates and writes the sing-box config without reloading
+// the core, for the CLI, which must not touch a core run by the panel service.
+func writeGeneratedConfig(panelCfg *config.Config, source string) error {
+	cfg, err := generateConfig()
+	if err != nil {
+		return err
+	}
+	_, err = writeConfig(panelCfg, cfg, source)
+	return err
+}
+
+// generateConfig generates the sing-box config from DB; an overlay conflict is a
+// *configRejectedError.
+func generateConfig() ([]byte, error) {
+	gen := &core.ConfigGenerator{}
+	cfg, err := gen.Generate()
+	var overlayErr *core.OverlayError
+	if errors.As(err, &overlayErr) {
+		return nil, &configRejectedError{err: err}
+	}
+	return cfg, err
+}
+
+// installConfig applies configJSON and reloads the core, returning the X-Core-Apply
+// value. A config refused by ApplyConfig is a *configRejectedError.
+func installConfig(panelCfg *config.Config, configJSON []byte, source string) (string, error) {
+	pm, err := writeConfig(panelCfg, configJSON, source)
+	if err != nil {
+		return "", err
+	}
+	return reloadCore(pm, configPath(panelCfg)), nil
+}
+
+// writeConfig checks and writes configJSON through ApplyConfig, returning the process
+// manager that checked it.
+func writeConfig(panelCfg *config.Config, configJSON []byte, source string) (*core.ProcessManager, error) {
+	path := configPath(panelCfg)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, errors.New("failed to create config dir")
+	}
+	pm := core.NewProcessManagerFromConfig(panelCfg)
+	if err := core.ApplyConfig(path, configJSON, pm, source); err != nil {
+		return nil, &configRejectedError{err: err}
+	}
+	return pm, nil
+}
+
+// coreApplyHeader reports how an applied config reached sing-box: "reload" (SIGHUP),
+// "restart" (reload unavailable or failed) or "none" (the core could not be started).
+const coreApplyHeader = "X-Core-Apply"
+
+// reloadCore puts the applied config at path into effect and returns the
+// X-Core-Apply value. Failure is best-effort since the config is already applied.
+func reloadCore(pm *core.ProcessManager, path string) string {
+	mode, err := pm.Reload(path)
+	if err != nil {
+		return "none"
+	}
+	return string(mode)
+}
+
+// writeApplyError writes a regenerateConfig failure: 400 {"error"} for a refused config, 500 otherwise.
+func writeApplyError(w http.ResponseWriter, err error) {
+	var rejected *configRejectedError
+	if errors.As(err, &rejected) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// applyGeneratedConfig regenerates the sing-box config from DB, applies it and reloads the core,
+// reporting how in the X-Core-Apply header.
+// source names the change for the config revision history, e.g. "outbound update".
+// On failure it calls rollback (which may be nil), writes the error response and returns false.
+func applyGeneratedConfig(w http.ResponseWriter, panelCfg *config.Config, source string, rollback func()) bool {
+	applied, err := regenerateConfig(panelCfg, source)
+	if err != nil {
+		if rollback != nil {
+			rollback()
+		}
+		writeApplyError(w, err)
+		return false
+	}
+	w.Header().Set(coreApplyHeader, applied)
+	return true
+}
+
 func parseLogLines(v string, defaultLines int) (int, error) {
 	if v == "" {
 		return defaultLines, nil
@@ -118,6 +225,7 @@ func StatusHandler(sm *scs.SessionManager, cfg *config.Config) http.HandlerFunc
 			"state":      snapshot.State,
 			"actions":    snapshot.Actions,
 			"lastError":  snapshot.LastError,
+			"supervisor": snapshot.Supervisor,
 			"version":    version,
 			"binaryPath": binaryPath(cfg),
 			"configPath": configPath(cfg),
@@ -198,7 +306,7 @@ func LogsHandler(sm *scs.SessionManager, cfg *config.Config) http.HandlerFunc {
 			return
 		}
 
-		logPath := filepath.Join(cfg.DataDir, "sing-box.log")
+		logPath := config.LogPath(cfg.DataDir)
 		content, err := os.ReadFile(logPath)
 		if err != nil {
 			if os.IsNotExist(err) {
@@ -230,21 +338,13 @@ func ConfigHandler(sm *scs.SessionManager, cfg *config.Config) http.HandlerFunc
 			http.Error(w, "invalid JSON", http.StatusBadRequest)
 			return
 		}
-		path := configPath(cfg)
-		dir := filepath.Dir(path)
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			http.Error(w, "failed to create config dir", http.StatusInternalServerError)
-			return
-		}
-		pm := core.NewProcessManagerFromConfig(cfg)
-		if err := core.ApplyConfig(path, body, pm); err != nil {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		applied, err := installConfig(cfg, body, "manual")
+		if err != nil {
+			writeApplyError(w, err)
 			return
 		}
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]string{"ok": "true"})
+		w.Header().Set(coreApplyHeader, applied)
+		writeJSON(w, http.StatusOK, map[string]string{"ok": "true"})
 	}
 }
 
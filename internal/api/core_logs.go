@@ -0,0 +1,153 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/s-ui/s-ui/internal/config"
+	"github.com/s-ui/s-ui/internal/core"
+)
+
+// logStreamInterval is how often LogStreamHandler checks the log for new lines; a
+// var so tests can shorten it.
+var logStreamInterval = 500 * time.Millisecond
+
+// parseLogFilter reads the level, inbound, user and pattern query parameters.
+func parseLogFilter(r *http.Request) (*core.LogFilter, error) {
+	q := r.URL.Query()
+	return core.NewLogFilter(q.Get("level"), q.Get("inbound"), q.Get("user"), q.Get("pattern"))
+}
+
+// filterLogEntries parses content and returns the last n entries passing filter.
+func filterLogEntries(content string, filter *core.LogFilter, n int) []core.LogEntry {
+	entries := []core.LogEntry{}
+	for _, line := range tailLogLines(content, 0) {
+		if entry := core.ParseLogLine(line); filter.Match(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if n > 0 && n < len(entries) {
+		entries = entries[len(entries)-n:]
+	}
+	return entries
+}
+
+// logQuery is a parsed log request together with the current log content.
+type logQuery struct {
+	path    string
+	content string
+	lines   int
+	filter  *core.LogFilter
+}
+
+// readLogQuery parses lines (defaulting to defaultLines) and the filter parameters,
+// then reads the sing-box log, writing the error response on failure.
+func readLogQuery(w http.ResponseWriter, r *http.Request, cfg *config.Config, defaultLines int) (*logQuery, bool) {
+	n, err := parseLogLines(r.URL.Query().Get("lines"), defaultLines)
+	if err != nil {
+		writeCoreError(w, http.StatusBadRequest, "CORE_INVALID_LOG_LINES", "invalid lines parameter", err.Error())
+		return nil, false
+	}
+	filter, err := parseLogFilter(r)
+	if err != nil {
+		writeCoreError(w, http.StatusBadRequest, "CORE_INVALID_LOG_FILTER", "invalid log filter", err.Error())
+		return nil, false
+	}
+	logPath := config.LogPath(cfg.DataDir)
+	content, err := os.ReadFile(logPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			writeCoreError(w, http.StatusNotFound, "CORE_LOG_NOT_FOUND", "sing-box log file not found", logPath)
+			return nil, false
+		}
+		writeCoreError(w, http.StatusInternalServerError, "CORE_LOG_READ_FAILED", "failed to read sing-box log file", err.Error())
+		return nil, false
+	}
+	return &logQuery{path: logPath, content: string(content), lines: n, filter: filter}, true
+}
+
+// LogEntriesHandler returns the latest N sing-box log entries matching the filter
+// parameters, parsed into timestamp, level, component and message.
+func LogEntriesHandler(sm *scs.SessionManager, cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		q, ok := readLogQuery(w, r, cfg, 200)
+		if !ok {
+			return
+		}
+		entries := filterLogEntries(q.content, q.filter, q.lines)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"path":    q.path,
+			"count":   len(entries),
+			"entries": entries,
+		})
+	}
+}
+
+// LogStreamHandler streams sing-box log entries matching the filter parameters over
+// SSE as they are written. With lines, the latest matching entries are sent first.
+func LogStreamHandler(sm *scs.SessionManager, cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "stream unsupported", http.StatusInternalServerError)
+			return
+		}
+		q, ok := readLogQuery(w, r, cfg, 0)
+		if !ok {
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache, no-transform")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		// Follow from the end of the last complete line; a partial one is sent once
+		// finished. Without lines, the backlog only primes the filter's connection tracking.
+		complete := q.content[:strings.LastIndexByte(q.content, '\n')+1]
+		backlog := filterLogEntries(complete, q.filter, q.lines)
+		if q.lines == 0 {
+			backlog = nil
+		}
+		for _, entry := range backlog {
+			if err := writeLogSSE(w, entry); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+
+		_ = core.FollowCoreLog(r.Context(), q.path, int64(len(complete)), logStreamInterval, func(line string) error {
+			entry := core.ParseLogLine(line)
+			if !q.filter.Match(entry) {
+				return nil
+			}
+			if err := writeLogSSE(w, entry); err != nil {
+				return err
+			}
+			flusher.Flush()
+			return nil
+		})
+	}
+}
+
+func writeLogSSE(w http.ResponseWriter, entry core.LogEntry) error {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", body)
+	return err
+}
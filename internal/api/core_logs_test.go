@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/s-ui/s-ui/internal/core"
+)
+
+const testCoreLog = `+0000 2024-05-01 12:00:00 INFO [1 0ms] inbound/vless[vless-in]: [alice] inbound connection to a.com:443
++0000 2024-05-01 12:00:00 INFO [2 0ms] inbound/vless[vless-in]: [bob] inbound connection to b.com:443
++0000 2024-05-01 12:00:01 ERROR [1 9ms] outbound/direct[direct]: dial tcp a.com:443: timeout
++0000 2024-05-01 12:00:01 ERROR [2 9ms] outbound/direct[direct]: dial tcp b.com:443: timeout
+`
+
+func TestLogEntriesHandler(t *testing.T) {
+	cfg := testCoreConfig(t, filepath.Join(t.TempDir(), "missing-sing-box"))
+	if err := os.WriteFile(filepath.Join(cfg.DataDir, "sing-box.log"), []byte(testCoreLog), 0644); err != nil {
+		t.Fatalf("write log file: %v", err)
+	}
+
+	cases := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantRaw    []string
+	}{
+		{name: "all", query: "", wantStatus: http.StatusOK, wantRaw: strings.Split(strings.TrimSpace(testCoreLog), "\n")},
+		{name: "user_connection", query: "?user=alice", wantStatus: http.StatusOK,
+			wantRaw: []string{strings.Split(testCoreLog, "\n")[0], strings.Split(testCoreLog, "\n")[2]}},
+		{name: "level_and_lines", query: "?level=error&lines=1", wantStatus: http.StatusOK,
+			wantRaw: []string{strings.Split(testCoreLog, "\n")[3]}},
+		{name: "pattern", query: "?pattern=b%5C.com.*timeout", wantStatus: http.StatusOK,
+			wantRaw: []string{strings.Split(testCoreLog, "\n")[3]}},
+		{name: "bad_level", query: "?level=loud", wantStatus: http.StatusBadRequest},
+		{name: "bad_pattern", query: "?pattern=(", wantStatus: http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			LogEntriesHandler(nil, cfg).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/core/logs/entries"+tc.query, nil))
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, body=%s", rec.Code, rec.Body.String())
+			}
+			if tc.wantStatus != http.StatusOK {
+				var got coreErrorPayload
+				decodeJSON(t, rec, &got)
+				if got.Code != "CORE_INVALID_LOG_FILTER" {
+					t.Fatalf("error code = %q", got.Code)
+				}
+				return
+			}
+			var got struct {
+				Count   int             `json:"count"`
+				Entries []core.LogEntry `json:"entries"`
+			}
+			decodeJSON(t, rec, &got)
+			if got.Count != len(tc.wantRaw) || len(got.Entries) != len(tc.wantRaw) {
+				t.Fatalf("entries = %+v, want %d", got.Entries, len(tc.wantRaw))
+			}
+			for i, want := range tc.wantRaw {
+				if got.Entries[i].Raw != want {
+					t.Fatalf("entry %d = %q, want %q", i, got.Entries[i].Raw, want)
+				}
+			}
+		})
+	}
+}
+
+// syncRecorder guards the recorder body, which the stream writes while the test reads.
+type syncRecorder struct {
+	mu sync.Mutex
+	*httptest.ResponseRecorder
+}
+
+func (r *syncRecorder) Write(b []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ResponseRecorder.Write(b)
+}
+
+func (r *syncRecorder) body() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Body.String()
+}
+
+func TestLogStreamHandlerFiltersNewLines(t *testing.T) {
+	interval := logStreamInterval
+	logStreamInterval = 10 * time.Millisecond
+	defer func() { logStreamInterval = interval }()
+
+	cfg := testCoreConfig(t, filepath.Join(t.TempDir(), "missing-sing-box"))
+	logPath := filepath.Join(cfg.DataDir, "sing-box.log")
+	// The backlog's inbound line for connection 1 primes the user filter.
+	if err := os.WriteFile(logPath, []byte(strings.Join(strings.Split(testCoreLog, "\n")[:2], "\n")+"\n"), 0644); err != nil {
+		t.Fatalf("write log file: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rec := &syncRecorder{ResponseRecorder: httptest.NewRecorder()}
+	done := make(chan struct{})
+	go func() {
+		LogStreamHandler(nil, cfg).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/core/logs/stream?user=alice&level=error", nil).WithContext(ctx))
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	f, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("open log file: %v", err)
+	}
+	f.WriteString(strings.Join(strings.Split(testCoreLog, "\n")[2:], "\n"))
+	f.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) && !strings.Contains(rec.body(), "data: ") {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("stream did not stop after cancel")
+	}
+
+	if got := rec.Header().Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("content-type = %q", got)
+	}
+	events := strings.Split(strings.TrimSpace(rec.body()), "\n\n")
+	if len(events) != 1 || !strings.HasPrefix(events[0], "data: ") {
+		t.Fatalf("events = %q, want one data event", events)
+	}
+	var entry core.LogEntry
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(events[0], "data: ")), &entry); err != nil {
+		t.Fatalf("unmarshal event: %v", err)
+	}
+	if entry.Level != "error" || entry.Connection != "1" || entry.Component != "outbound/direct[direct]" {
+		t.Fatalf("entry = %+v", entry)
+	}
+}
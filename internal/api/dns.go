@@ -0,0 +1,611 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/s-ui/s-ui/internal/config"
+	"github.com/s-ui/s-ui/internal/core"
+	"github.com/s-ui/s-ui/internal/db"
+	"gorm.io/datatypes"
+)
+
+// dnsServerItem is the API response shape for dns servers; it doubles as the POST/PUT body.
+type dnsServerItem struct {
+	ID             uint   `json:"id"`
+	Tag            string `json:"tag"`
+	Type           string `json:"type"`
+	Server         string `json:"server,omitempty"`
+	ServerPort     uint   `json:"server_port,omitempty"`
+	Path           string `json:"path,omitempty"`
+	Detour         string `json:"detour,omitempty"`
+	DomainResolver string `json:"domain_resolver,omitempty"`
+	Inet4Range     string `json:"inet4_range,omitempty"`
+	Inet6Range     string `json:"inet6_range,omitempty"`
+	CreatedAt      string `json:"created_at,omitempty"`
+}
+
+func dnsServerFromDB(s *db.DNSServer) dnsServerItem {
+	return dnsServerItem{
+		ID:             s.ID,
+		Tag:            s.Tag,
+		Type:           s.Type,
+		Server:         s.Server,
+		ServerPort:     s.ServerPort,
+		Path:           s.Path,
+		Detour:         s.Detour,
+		DomainResolver: s.DomainResolver,
+		Inet4Range:     s.Inet4Range,
+		Inet6Range:     s.Inet6Range,
+		CreatedAt:      s.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// dnsServerFromRequest builds a db.DNSServer from the request body. Fields that do
+// not apply to the server type are dropped; a "direct" detour is the default and
+// is stored empty since sing-box rejects an explicit detour to the direct outbound.
+func dnsServerFromRequest(req *dnsServerItem) *db.DNSServer {
+	s := &db.DNSServer{
+		Tag:  strings.TrimSpace(req.Tag),
+		Type: req.Type,
+	}
+	if s.Type == "fakeip" {
+		s.Inet4Range = strings.TrimSpace(req.Inet4Range)
+		s.Inet6Range = strings.TrimSpace(req.Inet6Range)
+		return s
+	}
+	s.Server = strings.TrimSpace(req.Server)
+	s.ServerPort = req.ServerPort
+	s.Path = strings.TrimSpace(req.Path)
+	s.Detour = strings.TrimSpace(req.Detour)
+	if s.Detour == core.OutboundDirect {
+		s.Detour = ""
+	}
+	s.DomainResolver = strings.TrimSpace(req.DomainResolver)
+	return s
+}
+
+// validateDNSServer checks fields and the referenced detour outbound and domain resolver.
+// The resolver chain must end at a server that does not need resolving itself.
+func validateDNSServer(s *db.DNSServer) error {
+	if s.Tag == "" || s.Type == "" {
+		return errors.New("tag and type required")
+	}
+	if err := core.ValidateDNSServer(s); err != nil {
+		return err
+	}
+	if s.Detour != "" {
+		if s.Detour == core.OutboundBlock {
+			return errors.New("dns server cannot detour to block")
+		}
+		if err := validateOutboundRef(s.Detour); err != nil {
+			return err
+		}
+	}
+	if s.DomainResolver == "" {
+		return nil
+	}
+	if s.DomainResolver == s.Tag {
+		return errors.New("dns server cannot resolve itself")
+	}
+	servers, err := db.ListDNSServers()
+	if err != nil {
+		return err
+	}
+	chain := make(map[string]string, len(servers))
+	for i := range servers {
+		chain[servers[i].Tag] = servers[i].DomainResolver
+	}
+	if _, ok := chain[s.DomainResolver]; !ok {
+		return fmt.Errorf("dns server not found: %s", s.DomainResolver)
+	}
+	for next, hops := s.DomainResolver, 0; next != "" && hops <= len(chain); hops++ {
+		if next == s.Tag {
+			return fmt.Errorf("domain_resolver %s forms a cycle", s.DomainResolver)
+		}
+		next = chain[next]
+	}
+	return nil
+}
+
+// dnsServerUsers describes everything that references dns server tag: dns rules,
+// other servers resolving through it and the dns final setting.
+func dnsServerUsers(tag string) ([]string, error) {
+	var users []string
+	resolved, err := db.DNSServersByDomainResolver(tag)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range resolved {
+		users = append(users, "dns server "+t)
+	}
+	ruleIDs, err := db.DNSRulesReferencingServer(tag)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ruleIDs {
+		users = append(users, fmt.Sprintf("dns rule #%d", id))
+	}
+	final, err := db.GetSetting(db.SettingDNSFinal)
+	if err != nil {
+		return nil, err
+	}
+	if final == tag {
+		users = append(users, "dns final")
+	}
+	return users, nil
+}
+
+// decodeDNSServerRequest decodes and validates the create/update body, writing 400 on failure.
+func decodeDNSServerRequest(w http.ResponseWriter, r *http.Request) (*db.DNSServer, bool) {
+	var req dnsServerItem
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return nil, false
+	}
+	s := dnsServerFromRequest(&req)
+	if err := validateDNSServer(s); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return s, true
+}
+
+// ListDNSServersHandler returns GET /api/dns/servers handler.
+func ListDNSServersHandler(sm *scs.SessionManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		servers, err := db.ListDNSServers()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		items := make([]dnsServerItem, len(servers))
+		for i := range servers {
+			items[i] = dnsServerFromDB(&servers[i])
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": items})
+	}
+}
+
+// CreateDNSServerHandler handles POST /api/dns/servers.
+func CreateDNSServerHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s, ok := decodeDNSServerRequest(w, r)
+		if !ok {
+			return
+		}
+		exists, err := db.DNSServerExistsByTag(s.Tag)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if exists {
+			http.Error(w, "tag already exists", http.StatusBadRequest)
+			return
+		}
+		if err := db.CreateDNSServer(s); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !applyGeneratedConfig(w, panelCfg, "dns server create", func() { db.DeleteDNSServer(s.ID) }) {
+			return
+		}
+		writeJSON(w, http.StatusCreated, dnsServerFromDB(s))
+	}
+}
+
+// UpdateDNSServerHandler handles PUT /api/dns/servers/:id. Renaming a referenced server is refused.
+func UpdateDNSServerHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := parseIDParam(r)
+		if !ok {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		old, err := db.GetDNSServerByID(id)
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		updated, ok := decodeDNSServerRequest(w, r)
+		if !ok {
+			return
+		}
+		if updated.Tag != old.Tag {
+			exists, err := db.DNSServerExistsByTag(updated.Tag)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if exists {
+				http.Error(w, "tag already exists", http.StatusBadRequest)
+				return
+			}
+			refs, err := dnsServerUsers(old.Tag)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if len(refs) > 0 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "dns server is in use by: " + strings.Join(refs, ", ")})
+				return
+			}
+		}
+		updated.ID = id
+		updated.CreatedAt = old.CreatedAt
+		if err := db.UpdateDNSServer(updated); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !applyGeneratedConfig(w, panelCfg, "dns server update", func() { db.UpdateDNSServer(old) }) {
+			return
+		}
+		writeJSON(w, http.StatusOK, dnsServerFromDB(updated))
+	}
+}
+
+// DeleteDNSServerHandler handles DELETE /api/dns/servers/:id.
+func DeleteDNSServerHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := parseIDParam(r)
+		if !ok {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		s, err := db.GetDNSServerByID(id)
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		refs, err := dnsServerUsers(s.Tag)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(refs) > 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "dns server is in use by: " + strings.Join(refs, ", ")})
+			return
+		}
+		if err := db.DeleteDNSServer(id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !applyGeneratedConfig(w, panelCfg, "dns server delete", func() { db.UpdateDNSServer(s) }) {
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// dnsRuleItem is the API response shape for dns rules.
+type dnsRuleItem struct {
+	ID        uint           `json:"id"`
+	Priority  int            `json:"priority"`
+	Remark    string         `json:"remark"`
+	Match     datatypes.JSON `json:"match"`
+	Server    string         `json:"server"`
+	Enabled   bool           `json:"enabled"`
+	CreatedAt string         `json:"created_at"`
+}
+
+func dnsRuleFromDB(rule *db.DNSRule) dnsRuleItem {
+	return dnsRuleItem{
+		ID:        rule.ID,
+		Priority:  rule.Priority,
+		Remark:    rule.Remark,
+		Match:     rule.Match,
+		Server:    rule.Server,
+		Enabled:   !rule.Disabled,
+		CreatedAt: rule.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// dnsRuleRequest is the POST/PUT body for create and update. enabled defaults to true.
+type dnsRuleRequest struct {
+	Remark  string         `json:"remark"`
+	Match   datatypes.JSON `json:"match"`
+	Server  string         `json:"server"`
+	Enabled *bool          `json:"enabled"`
+}
+
+// validateDNSServerRef checks that tag names a dns server.
+func validateDNSServerRef(tag string) error {
+	exists, err := db.DNSServerExistsByTag(tag)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("dns server not found: %s", tag)
+	}
+	return nil
+}
+
+// validateDNSRule checks match fields, referenced rule-set and outbound tags and the target server.
+func validateDNSRule(req *dnsRuleRequest) error {
+	if req.Server == "" {
+		return errors.New("server required")
+	}
+	var match map[string]any
+	if len(req.Match) > 0 {
+		if err := json.Unmarshal(req.Match, &match); err != nil {
+			return errors.New("invalid match")
+		}
+	}
+	if err := core.ValidateDNSMatch(match); err != nil {
+		return err
+	}
+	if tags, ok := match["rule_set"].([]any); ok {
+		for _, t := range tags {
+			tag, _ := t.(string)
+			exists, err := db.RuleSetExistsByTag(tag)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return fmt.Errorf("rule-set not found: %s", tag)
+			}
+		}
+	}
+	if tags, ok := match["outbound"].([]any); ok {
+		for _, t := range tags {
+			tag, _ := t.(string)
+			if err := validateOutboundRef(tag); err != nil {
+				return err
+			}
+		}
+	}
+	return validateDNSServerRef(req.Server)
+}
+
+// decodeDNSRuleRequest decodes and validates the create/update body, writing 400 on failure.
+func decodeDNSRuleRequest(w http.ResponseWriter, r *http.Request) (*dnsRuleRequest, bool) {
+	var req dnsRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return nil, false
+	}
+	req.Server = strings.TrimSpace(req.Server)
+	if err := validateDNSRule(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return &req, true
+}
+
+// ListDNSRulesHandler returns GET /api/dns/rules handler.
+func ListDNSRulesHandler(sm *scs.SessionManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rules, err := db.ListDNSRules()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		items := make([]dnsRuleItem, len(rules))
+		for i := range rules {
+			items[i] = dnsRuleFromDB(&rules[i])
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": items})
+	}
+}
+
+// CreateDNSRuleHandler handles POST /api/dns/rules. New rules are appended last.
+func CreateDNSRuleHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := decodeDNSRuleRequest(w, r)
+		if !ok {
+			return
+		}
+		rule := &db.DNSRule{
+			Remark:   req.Remark,
+			Match:    req.Match,
+			Server:   req.Server,
+			Disabled: req.Enabled != nil && !*req.Enabled,
+		}
+		if err := db.CreateDNSRule(rule); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !applyGeneratedConfig(w, panelCfg, "dns rule create", func() { db.DeleteDNSRule(rule.ID) }) {
+			return
+		}
+		writeJSON(w, http.StatusCreated, dnsRuleFromDB(rule))
+	}
+}
+
+// UpdateDNSRuleHandler handles PUT /api/dns/rules/:id. Priority is kept.
+func UpdateDNSRuleHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := parseIDParam(r)
+		if !ok {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		old, err := db.GetDNSRuleByID(id)
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		req, ok := decodeDNSRuleRequest(w, r)
+		if !ok {
+			return
+		}
+		updated := *old
+		updated.Remark = req.Remark
+		updated.Match = req.Match
+		updated.Server = req.Server
+		if req.Enabled != nil {
+			updated.Disabled = !*req.Enabled
+		}
+		if err := db.UpdateDNSRule(&updated); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !applyGeneratedConfig(w, panelCfg, "dns rule update", func() { db.UpdateDNSRule(old) }) {
+			return
+		}
+		writeJSON(w, http.StatusOK, dnsRuleFromDB(&updated))
+	}
+}
+
+// DeleteDNSRuleHandler handles DELETE /api/dns/rules/:id.
+func DeleteDNSRuleHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := parseIDParam(r)
+		if !ok {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		rule, err := db.GetDNSRuleByID(id)
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if err := db.DeleteDNSRule(id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !applyGeneratedConfig(w, panelCfg, "dns rule delete", func() { db.UpdateDNSRule(rule) }) {
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ReorderDNSRulesHandler handles PUT /api/dns/rules/order.
+// ids must list every rule exactly once, in the desired evaluation order.
+func ReorderDNSRulesHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req routeRuleOrderRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		old, err := db.ListDNSRules()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		known := make(map[uint]bool, len(old))
+		for _, rule := range old {
+			known[rule.ID] = true
+		}
+		seen := make(map[uint]bool, len(req.IDs))
+		for _, id := range req.IDs {
+			if !known[id] || seen[id] {
+				http.Error(w, "ids must list every rule exactly once", http.StatusBadRequest)
+				return
+			}
+			seen[id] = true
+		}
+		if len(seen) != len(known) {
+			http.Error(w, "ids must list every rule exactly once", http.StatusBadRequest)
+			return
+		}
+		if err := db.ReorderDNSRules(req.IDs); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rollback := func() {
+			for i := range old {
+				db.UpdateDNSRule(&old[i])
+			}
+		}
+		if !applyGeneratedConfig(w, panelCfg, "dns rule reorder", rollback) {
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"ok": "true"})
+	}
+}
+
+// dnsSettings is the GET/PUT /api/dns/settings body. Empty values keep the sing-box defaults.
+type dnsSettings struct {
+	Final        string `json:"final"`
+	Strategy     string `json:"strategy"`
+	ClientSubnet string `json:"client_subnet"`
+}
+
+// keys maps db setting keys to dnsSettings fields.
+func (s *dnsSettings) keys() map[string]*string {
+	return map[string]*string{
+		db.SettingDNSFinal:        &s.Final,
+		db.SettingDNSStrategy:     &s.Strategy,
+		db.SettingDNSClientSubnet: &s.ClientSubnet,
+	}
+}
+
+func loadDNSSettings() (*dnsSettings, error) {
+	s := &dnsSettings{}
+	for key, field := range s.keys() {
+		v, err := db.GetSetting(key)
+		if err != nil {
+			return nil, err
+		}
+		*field = v
+	}
+	return s, nil
+}
+
+func saveDNSSettings(s *dnsSettings) error {
+	for key, field := range s.keys() {
+		if err := db.SetSetting(key, *field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetDNSSettingsHandler returns GET /api/dns/settings handler.
+func GetDNSSettingsHandler(sm *scs.SessionManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s, err := loadDNSSettings()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, s)
+	}
+}
+
+// UpdateDNSSettingsHandler handles PUT /api/dns/settings (final server, strategy, client subnet).
+func UpdateDNSSettingsHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req dnsSettings
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		req.Final = strings.TrimSpace(req.Final)
+		req.ClientSubnet = strings.TrimSpace(req.ClientSubnet)
+		if req.Final != "" {
+			if err := validateDNSServerRef(req.Final); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if err := core.ValidateDNSStrategy(req.Strategy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := core.ValidateClientSubnet(req.ClientSubnet); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		old, err := loadDNSSettings()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := saveDNSSettings(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !applyGeneratedConfig(w, panelCfg, "dns settings update", func() { saveDNSSettings(old) }) {
+			return
+		}
+		writeJSON(w, http.StatusOK, req)
+	}
+}
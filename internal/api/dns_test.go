@@ -0,0 +1,110 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/s-ui/s-ui/internal/db"
+	"gorm.io/datatypes"
+)
+
+func TestValidateDNSServer(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	if err := db.CreateOutbound(&db.Outbound{Tag: "warp", Type: "direct"}); err != nil {
+		t.Fatalf("CreateOutbound: %v", err)
+	}
+	if err := db.CreateDNSServer(&db.DNSServer{Tag: "local", Type: "udp", Server: "223.5.5.5"}); err != nil {
+		t.Fatalf("CreateDNSServer: %v", err)
+	}
+	if err := db.CreateDNSServer(&db.DNSServer{Tag: "google", Type: "tls", Server: "dns.google", DomainResolver: "local"}); err != nil {
+		t.Fatalf("CreateDNSServer: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		req     dnsServerItem
+		wantErr string
+	}{
+		{name: "valid_detour", req: dnsServerItem{Tag: "warp-dns", Type: "https", Server: "1.1.1.1", Detour: "warp"}},
+		{name: "direct_detour", req: dnsServerItem{Tag: "d", Type: "udp", Server: "8.8.8.8", Detour: "direct"}},
+		{name: "resolver", req: dnsServerItem{Tag: "adg", Type: "quic", Server: "dns.adguard.com", DomainResolver: "google"}},
+		{name: "missing_tag", req: dnsServerItem{Type: "udp", Server: "8.8.8.8"}, wantErr: "tag and type required"},
+		{name: "block_detour", req: dnsServerItem{Tag: "b", Type: "udp", Server: "8.8.8.8", Detour: "block"}, wantErr: "cannot detour to block"},
+		{name: "unknown_detour", req: dnsServerItem{Tag: "u", Type: "udp", Server: "8.8.8.8", Detour: "nope"}, wantErr: "outbound not found"},
+		{name: "unknown_resolver", req: dnsServerItem{Tag: "r", Type: "tls", Server: "dns.google", DomainResolver: "nope"}, wantErr: "dns server not found"},
+		{name: "self_resolver", req: dnsServerItem{Tag: "r", Type: "tls", Server: "dns.google", DomainResolver: "r"}, wantErr: "cannot resolve itself"},
+		{name: "resolver_cycle", req: dnsServerItem{Tag: "local", Type: "tls", Server: "one.one.one.one", DomainResolver: "google"}, wantErr: "forms a cycle"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateDNSServer(dnsServerFromRequest(&tc.req))
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateDNSServer() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("validateDNSServer() = %v, want error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestDNSReferences(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	if err := db.CreateOutbound(&db.Outbound{Tag: "warp", Type: "direct"}); err != nil {
+		t.Fatalf("CreateOutbound: %v", err)
+	}
+	if err := db.CreateRuleSet(&db.RuleSet{Tag: "geosite-cn", Type: "local", Format: "binary"}); err != nil {
+		t.Fatalf("CreateRuleSet: %v", err)
+	}
+	if err := db.CreateDNSServer(&db.DNSServer{Tag: "warp-dns", Type: "udp", Server: "1.1.1.1", Detour: "warp"}); err != nil {
+		t.Fatalf("CreateDNSServer: %v", err)
+	}
+
+	if err := validateDNSRule(&dnsRuleRequest{Match: datatypes.JSON(`{"outbound":["nope"]}`), Server: "warp-dns"}); err == nil || !strings.Contains(err.Error(), "outbound not found") {
+		t.Fatalf("validateDNSRule(unknown outbound) = %v", err)
+	}
+	if err := validateDNSRule(&dnsRuleRequest{Match: datatypes.JSON(`{"domain":["a.com"]}`), Server: "nope"}); err == nil || !strings.Contains(err.Error(), "dns server not found") {
+		t.Fatalf("validateDNSRule(unknown server) = %v", err)
+	}
+	req := &dnsRuleRequest{Match: datatypes.JSON(`{"outbound":["warp"],"rule_set":["geosite-cn"]}`), Server: "warp-dns"}
+	if err := validateDNSRule(req); err != nil {
+		t.Fatalf("validateDNSRule() = %v", err)
+	}
+	rule := &db.DNSRule{Match: req.Match, Server: req.Server}
+	if err := db.CreateDNSRule(rule); err != nil {
+		t.Fatalf("CreateDNSRule: %v", err)
+	}
+	if err := db.SetSetting(db.SettingDNSFinal, "warp-dns"); err != nil {
+		t.Fatalf("SetSetting: %v", err)
+	}
+
+	users, err := outboundUsers("warp")
+	if err != nil {
+		t.Fatalf("outboundUsers: %v", err)
+	}
+	if got := strings.Join(users, ", "); got != "dns server warp-dns, dns rule #1" {
+		t.Fatalf("outboundUsers = %q", got)
+	}
+	users, err = ruleSetUsers("geosite-cn")
+	if err != nil {
+		t.Fatalf("ruleSetUsers: %v", err)
+	}
+	if got := strings.Join(users, ", "); got != "dns rule #1" {
+		t.Fatalf("ruleSetUsers = %q", got)
+	}
+	users, err = dnsServerUsers("warp-dns")
+	if err != nil {
+		t.Fatalf("dnsServerUsers: %v", err)
+	}
+	if got := strings.Join(users, ", "); got != "dns rule #1, dns final" {
+		t.Fatalf("dnsServerUsers = %q", got)
+	}
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/s-ui/s-ui/internal/config"
+	"github.com/s-ui/s-ui/internal/core"
+	"github.com/s-ui/s-ui/internal/db"
+)
+
+// importMaxSize bounds an uploaded config for the import endpoints.
+const importMaxSize = 8 << 20
+
+// ImportReport is the result of an import, returned by the import endpoints and the import-xui subcommand.
+type ImportReport struct {
+	Inbounds     []string `json:"inbounds"`
+	Users        int      `json:"users"`
+	MergedUsers  []string `json:"merged_users"`
+	Certificates int      `json:"certificates"`
+	Skipped      []string `json:"skipped"`
+
+	coreApply string // X-Core-Apply value; empty when nothing was imported
+}
+
+// filterImportPlan drops plan inbounds whose tag is taken or reserved or whose
+// config_json does not pass inbound validation, recording each in plan.Skipped.
+func filterImportPlan(plan *core.ImportPlan) error {
+	for _, item := range append([]db.ImportInbound(nil), plan.Inbounds...) {
+		ib := item.Inbound
+		if core.IsReservedInboundTag(ib.Tag) {
+			plan.Skip("inbound %s: tag is reserved for internal inbounds", ib.Tag)
+			plan.Drop(ib.Tag)
+			continue
+		}
+		exists, err := db.InboundExistsByTag(ib.Tag)
+		if err != nil {
+			return err
+		}
+		if exists {
+			plan.Skip("inbound %s: tag already exists", ib.Tag)
+			plan.Drop(ib.Tag)
+			continue
+		}
+		configJSON, err := prepareInboundConfig(ib.Protocol, ib.ConfigJSON)
+		if err == nil {
+			err = validateInbound(ib.Protocol, configJSON)
+		}
+		if err != nil {
+			plan.Skip("inbound %s: %v", ib.Tag, err)
+			plan.Drop(ib.Tag)
+			continue
+		}
+		for i := range plan.Inbounds {
+			if plan.Inbounds[i].Inbound.Tag == ib.Tag {
+				plan.Inbounds[i].Inbound.ConfigJSON = configJSON
+			}
+		}
+	}
+	return nil
+}
+
+// applyImport filters and persists plan, then applies the generated config, reloading
+// the core when reload is set; the import is undone when applying fails.
+func applyImport(panelCfg *config.Config, plan *core.ImportPlan, source string, reload bool) (*ImportReport, error) {
+	if err := filterImportPlan(plan); err != nil {
+		return nil, err
+	}
+	res, err := db.ImportRecords(&plan.ImportData)
+	if err != nil {
+		return nil, err
+	}
+	applied := ""
+	if len(res.InboundIDs) > 0 || len(res.UserIDs) > 0 {
+		if reload {
+			applied, err = regenerateConfig(panelCfg, source)
+		} else {
+			err = writeGeneratedConfig(panelCfg, source)
+		}
+		if err != nil {
+			db.UndoImport(res)
+			return nil, err
+		}
+	}
+	report := &ImportReport{
+		Inbounds:     make([]string, 0, len(plan.Inbounds)),
+		Users:        len(res.UserIDs),
+		MergedUsers:  res.MergedUsers,
+		Certificates: len(res.CertificateIDs),
+		Skipped:      plan.Skipped,
+		coreApply:    applied,
+	}
+	for _, name := range res.KeptShadowsocksKeys {
+		report.Skipped = append(report.Skipped, "user "+name+": kept the existing shadowsocks key; imported shadowsocks clients need new subscriptions")
+	}
+	for _, item := range plan.Inbounds {
+		report.Inbounds = append(report.Inbounds, item.Inbound.Tag)
+	}
+	if report.MergedUsers == nil {
+		report.MergedUsers = []string{}
+	}
+	if report.Skipped == nil {
+		report.Skipped = []string{}
+	}
+	return report, nil
+}
+
+// ImportXUIDatabase imports the x-ui / 3x-ui SQLite database at path into the panel
+// database and writes the resulting config. It backs the import-xui subcommand, so the
+// running core is left alone; the config takes effect when the core is next started or reloaded.
+func ImportXUIDatabase(panelCfg *config.Config, path string) (*ImportReport, error) {
+	plan, err := core.ParseXUIImport(path)
+	if err != nil {
+		return nil, err
+	}
+	return applyImport(panelCfg, plan, "xui import", false)
+}
+
+// ImportSingBoxHandler handles POST /api/import/singbox. The body is a full sing-box
+// config; supported inbounds and their users are created and the rest is reported.
+func ImportSingBoxHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, importMaxSize))
+		if err != nil {
+			http.Error(w, "config too large", http.StatusBadRequest)
+			return
+		}
+		plan, err := core.ParseSingBoxImport(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		report, err := applyImport(panelCfg, plan, "singbox import", true)
+		if err != nil {
+			writeApplyError(w, err)
+			return
+		}
+		if report.coreApply != "" {
+			w.Header().Set(coreApplyHeader, report.coreApply)
+		}
+		writeJSON(w, http.StatusOK, report)
+	}
+}
+
+// ImportXUIHandler handles POST /api/import/xui. The x-ui or 3x-ui database is uploaded
+// as multipart field "file"; inbounds, clients, traffic, expiry and limits are imported.
+func ImportXUIHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, importMaxSize+1<<20)
+		if err := r.ParseMultipartForm(importMaxSize); err != nil {
+			http.Error(w, "invalid upload", http.StatusBadRequest)
+			return
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "file required", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		tmp, err := os.CreateTemp("", "x-ui-*.db")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer os.Remove(tmp.Name())
+		_, err = io.Copy(tmp, file)
+		tmp.Close()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		plan, err := core.ParseXUIImport(tmp.Name())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		report, err := applyImport(panelCfg, plan, "xui import", true)
+		if err != nil {
+			writeApplyError(w, err)
+			return
+		}
+		if report.coreApply != "" {
+			w.Header().Set(coreApplyHeader, report.coreApply)
+		}
+		writeJSON(w, http.StatusOK, report)
+	}
+}
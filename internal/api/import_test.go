@@ -0,0 +1,198 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/s-ui/s-ui/internal/db"
+	"gorm.io/gorm"
+)
+
+func TestImportSingBoxHandler(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	binary := filepath.Join(t.TempDir(), "sing-box")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\n[ \"$1\" = check ] && exit 0\nexit 1\n"), 0755); err != nil {
+		t.Fatalf("write fake binary: %v", err)
+	}
+	cfg := testCoreConfig(t, binary)
+	if err := db.CreateUser(&db.User{Name: "dave", Enabled: true}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := db.CreateInbound(&db.Inbound{Tag: "taken", Protocol: "vmess", ListenPort: 1000}); err != nil {
+		t.Fatalf("CreateInbound: %v", err)
+	}
+
+	body := `{
+	  "log": {"level": "warn"},
+	  "inbounds": [
+	    {"type": "vless", "tag": "vless-in", "listen_port": 443,
+	     "users": [{"name": "alice", "uuid": "11111111-1111-1111-1111-111111111111", "flow": "xtls-rprx-vision"}],
+	     "tls": {"enabled": true, "server_name": "a.example", "certificate_path": "/etc/ssl/a.crt", "key_path": "/etc/ssl/a.key"}},
+	    {"type": "trojan", "tag": "trojan-in", "listen": "0.0.0.0", "listen_port": 8443,
+	     "users": [{"name": "alice", "password": "alice-pw"}, {"name": "carol", "password": "carol-pw"}, {"name": "dave", "password": "other"}],
+	     "tls": {"enabled": true, "certificate_path": "/etc/ssl/a.crt", "key_path": "/etc/ssl/a.key"},
+	     "transport": {"type": "ws", "path": "/ws"}},
+	    {"type": "socks", "tag": "socks-in", "listen_port": 1080},
+	    {"type": "vmess", "tag": "taken", "listen_port": 2000, "users": [{"name": "erin", "uuid": "22222222-2222-2222-2222-222222222222"}]}
+	  ],
+	  "outbounds": [{"type": "direct", "tag": "direct"}]
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/api/import/singbox", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	ImportSingBoxHandler(nil, cfg).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var report ImportReport
+	decodeJSON(t, rec, &report)
+	if strings.Join(report.Inbounds, ",") != "vless-in,trojan-in" {
+		t.Fatalf("inbounds = %v", report.Inbounds)
+	}
+	if report.Users != 2 || report.Certificates != 1 {
+		t.Fatalf("users = %d, certificates = %d, want 2 and 1", report.Users, report.Certificates)
+	}
+	if strings.Join(report.MergedUsers, ",") != "dave" {
+		t.Fatalf("merged_users = %v", report.MergedUsers)
+	}
+	skipped := strings.Join(report.Skipped, "\n")
+	for _, want := range []string{"outbounds: section is not imported", `inbound socks-in: unsupported type "socks"`, "inbound taken: tag already exists"} {
+		if !strings.Contains(skipped, want) {
+			t.Errorf("skipped = %q, want %q", skipped, want)
+		}
+	}
+
+	alice, err := db.GetUserByName("alice")
+	if err != nil {
+		t.Fatalf("GetUserByName(alice): %v", err)
+	}
+	if alice.UUID != "11111111-1111-1111-1111-111111111111" || alice.Password != "alice-pw" || len(alice.Inbounds) != 2 {
+		t.Fatalf("alice = %+v, want preserved credentials on both inbounds", alice)
+	}
+	if _, err := db.GetUserByName("erin"); err == nil {
+		t.Fatal("user of a skipped inbound was imported")
+	}
+	trojan, err := db.GetInboundByTag("trojan-in")
+	if err != nil {
+		t.Fatalf("GetInboundByTag: %v", err)
+	}
+	if tags, _ := db.InboundsReferencingCert(1); len(tags) != 2 {
+		t.Fatalf("certificate referenced by %v, want both tls inbounds", tags)
+	}
+	if trojan.Listen != "0.0.0.0" || strings.Contains(string(trojan.ConfigJSON), "certificate_path") {
+		t.Fatalf("trojan inbound = %+v", trojan)
+	}
+	users, _ := db.GetUsersForInbound(trojan.ID)
+	if len(users) != 3 {
+		t.Fatalf("trojan-in users = %d, want 3", len(users))
+	}
+}
+
+func TestImportSingBoxKeepsShadowsocksKeys(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	binary := filepath.Join(t.TempDir(), "sing-box")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\n[ \"$1\" = check ] && exit 0\nexit 1\n"), 0755); err != nil {
+		t.Fatalf("write fake binary: %v", err)
+	}
+	cfg := testCoreConfig(t, binary)
+	// frank already has panel shadowsocks clients, so his key must not change.
+	frank := &db.User{Name: "frank", Enabled: true}
+	if err := db.CreateUser(frank); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	ss := &db.Inbound{Tag: "panel-ss", Protocol: "shadowsocks", ListenPort: 1000,
+		ConfigJSON: []byte(`{"method":"2022-blake3-aes-128-gcm","password":"AAAAAAAAAAAAAAAAAAAAAA=="}`)}
+	if err := db.CreateInbound(ss); err != nil {
+		t.Fatalf("CreateInbound: %v", err)
+	}
+	if err := db.ReplaceUserInbounds(frank.ID, []uint{ss.ID}); err != nil {
+		t.Fatalf("ReplaceUserInbounds: %v", err)
+	}
+
+	const aliceKey, frankKey = "EREREREREREREREREREREQ==", "IiIiIiIiIiIiIiIiIiIiIg=="
+	body := `{"inbounds": [
+	  {"type": "shadowsocks", "tag": "ss-in", "listen_port": 8388,
+	   "method": "2022-blake3-aes-128-gcm", "password": "MzMzMzMzMzMzMzMzMzMzMw==",
+	   "users": [{"name": "alice", "password": "` + aliceKey + `"}, {"name": "frank", "password": "` + frankKey + `"}]}
+	]}`
+	rec := httptest.NewRecorder()
+	ImportSingBoxHandler(nil, cfg).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/import/singbox", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var report ImportReport
+	decodeJSON(t, rec, &report)
+	if skipped := strings.Join(report.Skipped, "\n"); !strings.Contains(skipped, "user frank: kept the existing shadowsocks key") {
+		t.Fatalf("skipped = %q, want frank's kept key reported", skipped)
+	}
+
+	alice, err := db.GetUserByName("alice")
+	if err != nil {
+		t.Fatalf("GetUserByName(alice): %v", err)
+	}
+	if alice.ShadowsocksKey != aliceKey {
+		t.Fatalf("alice shadowsocks key = %q, want the imported key", alice.ShadowsocksKey)
+	}
+	if got, _ := db.GetUserByName("frank"); got.ShadowsocksKey != frank.ShadowsocksKey {
+		t.Fatalf("frank shadowsocks key = %q, want %q", got.ShadowsocksKey, frank.ShadowsocksKey)
+	}
+	written, _ := os.ReadFile(cfg.SingboxConfigPath)
+	if !strings.Contains(string(written), `"password": "`+aliceKey+`"`) {
+		t.Fatalf("generated config does not carry alice's imported key: %s", written)
+	}
+}
+
+func TestImportXUIDatabaseLeavesCoreAlone(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	dir := t.TempDir()
+	started := filepath.Join(dir, "started")
+	binary := filepath.Join(dir, "sing-box")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\n[ \"$1\" = check ] && exit 0\ntouch "+started+"\nexit 1\n"), 0755); err != nil {
+		t.Fatalf("write fake binary: %v", err)
+	}
+	cfg := testCoreConfig(t, binary)
+
+	path := filepath.Join(dir, "x-ui.db")
+	src, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE inbounds (id INTEGER PRIMARY KEY, up INTEGER, down INTEGER, total INTEGER, enable NUMERIC, expiry_time INTEGER,
+		 listen TEXT, port INTEGER, protocol TEXT, settings TEXT, stream_settings TEXT, tag TEXT)`,
+		`INSERT INTO inbounds (up, down, total, enable, expiry_time, listen, port, protocol, settings, stream_settings, tag) VALUES
+		 (0, 0, 0, 1, 0, '', 10086, 'vmess', '{"clients":[{"id":"33333333-3333-3333-3333-333333333333","email":"gina"}]}', '', 'inbound-10086')`,
+	} {
+		if err := src.Exec(stmt).Error; err != nil {
+			t.Fatalf("exec: %v", err)
+		}
+	}
+	if sqlDB, err := src.DB(); err == nil {
+		sqlDB.Close()
+	}
+
+	report, err := ImportXUIDatabase(cfg, path)
+	if err != nil {
+		t.Fatalf("ImportXUIDatabase: %v", err)
+	}
+	if strings.Join(report.Inbounds, ",") != "inbound-10086" || report.coreApply != "" {
+		t.Fatalf("report = %+v, want inbound-10086 without a core apply", report)
+	}
+	written, _ := os.ReadFile(cfg.SingboxConfigPath)
+	if !strings.Contains(string(written), "33333333-3333-3333-3333-333333333333") {
+		t.Fatalf("config was not written: %s", written)
+	}
+	if _, err := os.Stat(started); err == nil {
+		t.Fatal("import-xui started sing-box")
+	}
+}
@@ -2,6 +2,8 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -140,6 +142,308 @@ type inboundUpdateRequest struct {
 	ConfigJSON datatypes.JSON  `json:"config_json"`
 }
 
+// supportedProtocols lists inbound protocols ConfigGenerator can emit.
+var supportedProtocols = map[string]bool{
+	"vless":       true,
+	"vmess":       true,
+	"hysteria2":   true,
+	"tuic":        true,
+	"trojan":      true,
+	"shadowsocks": true,
+	"shadowtls":   true,
+	"anytls":      true,
+	"naive":       true,
+}
+
+// v2rayTransports lists the sing-box V2Ray transport types VMess can run over.
+var v2rayTransports = map[string]bool{"http": true, "ws": true, "quic": true, "grpc": true, "httpupgrade": true}
+
+// validateInbound checks protocol support and protocol-specific config_json requirements.
+func validateInbound(protocol string, configJSON []byte) error {
+	if !supportedProtocols[protocol] {
+		return fmt.Errorf("unsupported protocol: %s", protocol)
+	}
+	var cfg map[string]any
+	if len(configJSON) > 0 {
+		if err := json.Unmarshal(configJSON, &cfg); err != nil {
+			return errors.New("invalid config_json")
+		}
+	}
+	if tls, ok := cfg["tls"].(map[string]any); ok {
+		if reality, ok := tls["reality"].(map[string]any); ok && reality != nil {
+			if err := validateReality(protocol, reality); err != nil {
+				return err
+			}
+		}
+	}
+	if flow, ok := cfg["flow"]; ok {
+		if err := validateFlow(protocol, flow, cfg); err != nil {
+			return err
+		}
+	}
+	switch protocol {
+	case "trojan", "anytls", "naive":
+		tls, _ := cfg["tls"].(map[string]any)
+		if enabled, _ := tls["enabled"].(bool); !enabled {
+			return fmt.Errorf("%s requires tls.enabled", protocol)
+		}
+	case "hysteria2":
+		if obfs, ok := cfg["obfs"].(map[string]any); ok && len(obfs) > 0 {
+			if t, _ := obfs["type"].(string); t != "" && t != "salamander" {
+				return fmt.Errorf("unsupported hysteria2 obfs type: %s", t)
+			}
+			if pw, _ := obfs["password"].(string); pw == "" {
+				return errors.New("hysteria2 obfs requires password")
+			}
+		}
+		if raw, ok := cfg["hop_ports"]; ok {
+			spec, ok := raw.(string)
+			if !ok {
+				return errors.New("hop_ports must be a string")
+			}
+			if err := core.ValidateHopPorts(spec); err != nil {
+				return err
+			}
+		}
+	case "vmess":
+		if raw, ok := cfg["transport"]; ok && raw != nil {
+			tr, ok := raw.(map[string]any)
+			if !ok {
+				return errors.New("transport must be an object")
+			}
+			if t, _ := tr["type"].(string); len(tr) > 0 && !v2rayTransports[t] {
+				return fmt.Errorf("unsupported vmess transport: %s", t)
+			}
+		}
+	case "tuic":
+		// TUIC runs over QUIC, which always needs TLS.
+		tls, _ := cfg["tls"].(map[string]any)
+		if enabled, _ := tls["enabled"].(bool); !enabled {
+			return errors.New("tuic requires tls.enabled")
+		}
+		if cc, ok := cfg["congestion_control"].(string); ok && cc != "" {
+			switch cc {
+			case "cubic", "new_reno", "bbr":
+			default:
+				return fmt.Errorf("unsupported tuic congestion_control: %s", cc)
+			}
+		}
+	case "shadowsocks":
+		method, _ := cfg["method"].(string)
+		psk, _ := cfg["password"].(string)
+		if err := core.ValidateShadowsocksPSK(method, psk); err != nil {
+			return err
+		}
+	case "shadowtls":
+		hs, _ := cfg["handshake"].(map[string]any)
+		if server, _ := hs["server"].(string); server == "" {
+			return errors.New("shadowtls requires handshake.server")
+		}
+		method, _ := cfg["method"].(string)
+		psk, _ := cfg["password"].(string)
+		if err := core.ValidateShadowsocksPSK(method, psk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateFlow checks config_json.flow: VLESS only, and xtls-rprx-vision requires
+// TLS or REALITY over plain TCP (no ws/grpc/http/httpupgrade transport).
+func validateFlow(protocol string, raw any, cfg map[string]any) error {
+	flow, ok := raw.(string)
+	if !ok {
+		return errors.New("flow must be a string")
+	}
+	if flow == "" {
+		return nil
+	}
+	if protocol != "vless" {
+		return fmt.Errorf("flow is not supported for %s", protocol)
+	}
+	if flow != core.FlowVision {
+		return fmt.Errorf("unsupported flow: %s", flow)
+	}
+	tls, _ := cfg["tls"].(map[string]any)
+	if enabled, _ := tls["enabled"].(bool); !enabled {
+		return errors.New("flow xtls-rprx-vision requires tls.enabled")
+	}
+	if tr, ok := cfg["transport"].(map[string]any); ok && len(tr) > 0 {
+		if t, _ := tr["type"].(string); t != "" && t != "tcp" {
+			return fmt.Errorf("flow xtls-rprx-vision is incompatible with %s transport", t)
+		}
+	}
+	return nil
+}
+
+// validateReality checks a tls.reality block: VLESS only, handshake server set,
+// valid x25519 private key and hex short IDs.
+func validateReality(protocol string, reality map[string]any) error {
+	if enabled, ok := reality["enabled"].(bool); ok && !enabled {
+		return nil
+	}
+	if protocol != "vless" {
+		return fmt.Errorf("reality is not supported for %s", protocol)
+	}
+	hs, _ := reality["handshake"].(map[string]any)
+	if server, _ := hs["server"].(string); server == "" {
+		return errors.New("reality requires handshake.server")
+	}
+	priv, _ := reality["private_key"].(string)
+	if _, err := core.RealityPublicKey(priv); err != nil {
+		return err
+	}
+	var ids []any
+	switch v := reality["short_id"].(type) {
+	case string:
+		ids = []any{v}
+	case []any:
+		ids = v
+	}
+	for _, id := range ids {
+		s, ok := id.(string)
+		if !ok {
+			return errors.New("reality short_id must be a string")
+		}
+		if err := core.ValidateRealityShortID(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepareInboundConfig fills server-side generated secrets into config_json before validation.
+// Shadowsocks and ShadowTLS: defaults method and generates the server PSK when absent.
+// VLESS REALITY: generates the x25519 keypair and a short ID when absent.
+func prepareInboundConfig(protocol string, configJSON datatypes.JSON) (datatypes.JSON, error) {
+	if protocol != "shadowsocks" && protocol != "shadowtls" && protocol != "vless" {
+		return configJSON, nil
+	}
+	cfg := map[string]any{}
+	if len(configJSON) > 0 {
+		if err := json.Unmarshal(configJSON, &cfg); err != nil {
+			return nil, errors.New("invalid config_json")
+		}
+	}
+	changed := false
+	switch protocol {
+	case "shadowsocks", "shadowtls":
+		method, _ := cfg["method"].(string)
+		if method == "" {
+			method = core.DefaultShadowsocksMethod
+			cfg["method"] = method
+			changed = true
+		}
+		if psk, _ := cfg["password"].(string); psk == "" {
+			psk, err := core.GenerateShadowsocksPSK(method)
+			if err != nil {
+				return nil, err
+			}
+			cfg["password"] = psk
+			changed = true
+		}
+	case "vless":
+		if tls, ok := cfg["tls"].(map[string]any); ok {
+			ok, err := core.PrepareReality(tls)
+			if err != nil {
+				return nil, err
+			}
+			changed = ok
+		}
+	}
+	if !changed {
+		return configJSON, nil
+	}
+	out, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return datatypes.JSON(out), nil
+}
+
+// carryInboundSecrets copies server-generated secrets that an update request left out of
+// config_json from the stored inbound, so editing an inbound does not rotate them and
+// break existing clients. prepareInboundConfig then only generates what is still missing.
+// Shadowsocks and ShadowTLS: method, and password while the method is unchanged.
+// VLESS REALITY: private_key (with its public_key) and short_id.
+func carryInboundSecrets(old *db.Inbound, protocol string, configJSON datatypes.JSON) (datatypes.JSON, error) {
+	if old.Protocol != protocol || (protocol != "shadowsocks" && protocol != "shadowtls" && protocol != "vless") {
+		return configJSON, nil
+	}
+	stored := map[string]any{}
+	if len(old.ConfigJSON) > 0 {
+		if err := json.Unmarshal(old.ConfigJSON, &stored); err != nil {
+			return configJSON, nil
+		}
+	}
+	cfg := map[string]any{}
+	if len(configJSON) > 0 {
+		if err := json.Unmarshal(configJSON, &cfg); err != nil {
+			return nil, errors.New("invalid config_json")
+		}
+	}
+	changed := false
+	switch protocol {
+	case "shadowsocks", "shadowtls":
+		storedMethod, _ := stored["method"].(string)
+		method, _ := cfg["method"].(string)
+		if method == "" && storedMethod != "" {
+			method = storedMethod
+			cfg["method"] = method
+			changed = true
+		}
+		if psk, _ := cfg["password"].(string); psk == "" && method == storedMethod {
+			if storedPSK, _ := stored["password"].(string); storedPSK != "" {
+				cfg["password"] = storedPSK
+				changed = true
+			}
+		}
+	case "vless":
+		changed = carryRealityKeys(cfg, stored)
+	}
+	if !changed {
+		return configJSON, nil
+	}
+	out, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return datatypes.JSON(out), nil
+}
+
+// carryRealityKeys copies the stored REALITY private_key, public_key and short_id into
+// cfg's reality block when it is enabled and leaves them out. Returns true when cfg changed.
+func carryRealityKeys(cfg, stored map[string]any) bool {
+	tls, _ := cfg["tls"].(map[string]any)
+	reality, _ := tls["reality"].(map[string]any)
+	if reality == nil {
+		return false
+	}
+	if enabled, ok := reality["enabled"].(bool); ok && !enabled {
+		return false
+	}
+	storedTLS, _ := stored["tls"].(map[string]any)
+	storedReality, _ := storedTLS["reality"].(map[string]any)
+	if storedReality == nil {
+		return false
+	}
+	changed := false
+	if priv, _ := reality["private_key"].(string); priv == "" {
+		if storedPriv, _ := storedReality["private_key"].(string); storedPriv != "" {
+			reality["private_key"] = storedPriv
+			reality["public_key"] = storedReality["public_key"]
+			changed = true
+		}
+	}
+	if _, ok := reality["short_id"]; !ok {
+		if ids, ok := storedReality["short_id"]; ok {
+			reality["short_id"] = ids
+			changed = true
+		}
+	}
+	return changed
+}
+
 // CreateInboundHandler handles POST /api/inbounds.
 func CreateInboundHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -152,6 +456,20 @@ func CreateInboundHandler(sm *scs.SessionManager, panelCfg *config.Config) http.
 			http.Error(w, "tag and protocol required", http.StatusBadRequest)
 			return
 		}
+		if core.IsReservedInboundTag(req.Tag) {
+			http.Error(w, "tag is reserved for internal inbounds", http.StatusBadRequest)
+			return
+		}
+		configJSON, err := prepareInboundConfig(req.Protocol, req.ConfigJSON)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.ConfigJSON = configJSON
+		if err := validateInbound(req.Protocol, req.ConfigJSON); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		if req.Listen == "" {
 			req.Listen = "::"
 		}
@@ -190,16 +508,14 @@ func CreateInboundHandler(sm *scs.SessionManager, panelCfg *config.Config) http.
 			return
 		}
 		pm := core.NewProcessManagerFromConfig(panelCfg)
-		if err := core.ApplyConfig(path, cfg, pm); err != nil {
+		if err := core.ApplyConfig(path, cfg, pm, "inbound create"); err != nil {
 			db.DeleteInbound(ib.ID)
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 			return
 		}
-		if err := pm.Restart(path); err != nil {
-			// Config applied; restart failure is best-effort
-		}
+		w.Header().Set(coreApplyHeader, reloadCore(pm, path))
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(inboundFromDB(ib))
@@ -230,6 +546,25 @@ func UpdateInboundHandler(sm *scs.SessionManager, panelCfg *config.Config) http.
 			http.Error(w, "tag and protocol required", http.StatusBadRequest)
 			return
 		}
+		if core.IsReservedInboundTag(req.Tag) {
+			http.Error(w, "tag is reserved for internal inbounds", http.StatusBadRequest)
+			return
+		}
+		configJSON, err := carryInboundSecrets(old, req.Protocol, req.ConfigJSON)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		configJSON, err = prepareInboundConfig(req.Protocol, configJSON)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.ConfigJSON = configJSON
+		if err := validateInbound(req.Protocol, req.ConfigJSON); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		if req.Listen == "" {
 			req.Listen = "::"
 		}
@@ -265,16 +600,14 @@ func UpdateInboundHandler(sm *scs.SessionManager, panelCfg *config.Config) http.
 			return
 		}
 		pm := core.NewProcessManagerFromConfig(panelCfg)
-		if err := core.ApplyConfig(path, cfg, pm); err != nil {
+		if err := core.ApplyConfig(path, cfg, pm, "inbound update"); err != nil {
 			db.UpdateInbound(old)
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 			return
 		}
-		if err := pm.Restart(path); err != nil {
-			// Config applied; restart failure is best-effort
-		}
+		w.Header().Set(coreApplyHeader, reloadCore(pm, path))
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(inboundFromDB(updated))
 	}
@@ -312,16 +645,14 @@ func DeleteInboundHandler(sm *scs.SessionManager, panelCfg *config.Config) http.
 			return
 		}
 		pm := core.NewProcessManagerFromConfig(panelCfg)
-		if err := core.ApplyConfig(path, cfg, pm); err != nil {
+		if err := core.ApplyConfig(path, cfg, pm, "inbound delete"); err != nil {
 			db.CreateInbound(ib)
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 			return
 		}
-		if err := pm.Restart(path); err != nil {
-			// Config applied; restart failure is best-effort
-		}
+		w.Header().Set(coreApplyHeader, reloadCore(pm, path))
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
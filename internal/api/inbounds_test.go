@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/s-ui/s-ui/internal/core"
+	"github.com/s-ui/s-ui/internal/db"
+	"gorm.io/datatypes"
+)
+
+func TestValidateInbound(t *testing.T) {
+	cases := []struct {
+		name     string
+		protocol string
+		config   string
+		wantErr  bool
+	}{
+		{name: "vless_without_config", protocol: "vless", config: "", wantErr: false},
+		{name: "unknown_protocol", protocol: "socks", config: "", wantErr: true},
+		{name: "invalid_config_json", protocol: "vless", config: "{", wantErr: true},
+		{name: "trojan_with_tls", protocol: "trojan", config: `{"tls":{"enabled":true}}`, wantErr: false},
+		{name: "trojan_without_tls", protocol: "trojan", config: `{}`, wantErr: true},
+		{name: "shadowsocks_valid_psk", protocol: "shadowsocks", config: `{"method":"2022-blake3-aes-128-gcm","password":"AAAAAAAAAAAAAAAAAAAAAA=="}`, wantErr: false},
+		{name: "shadowsocks_short_psk", protocol: "shadowsocks", config: `{"method":"2022-blake3-aes-256-gcm","password":"AAAAAAAAAAAAAAAAAAAAAA=="}`, wantErr: true},
+		{name: "reality_missing_handshake", protocol: "vless", config: `{"tls":{"enabled":true,"reality":{"enabled":true}}}`, wantErr: true},
+		{name: "reality_on_trojan", protocol: "trojan", config: `{"tls":{"enabled":true,"reality":{"enabled":true}}}`, wantErr: true},
+		{name: "vision_with_tls", protocol: "vless", config: `{"flow":"xtls-rprx-vision","tls":{"enabled":true}}`, wantErr: false},
+		{name: "vision_without_tls", protocol: "vless", config: `{"flow":"xtls-rprx-vision"}`, wantErr: true},
+		{name: "vision_with_ws", protocol: "vless", config: `{"flow":"xtls-rprx-vision","tls":{"enabled":true},"transport":{"type":"ws"}}`, wantErr: true},
+		{name: "empty_flow_with_ws", protocol: "vless", config: `{"flow":"","transport":{"type":"ws"}}`, wantErr: false},
+		{name: "unknown_flow", protocol: "vless", config: `{"flow":"xtls-rprx-direct","tls":{"enabled":true}}`, wantErr: true},
+		{name: "flow_on_vmess", protocol: "vmess", config: `{"flow":"xtls-rprx-vision","tls":{"enabled":true}}`, wantErr: true},
+		{name: "hysteria2_obfs_without_password", protocol: "hysteria2", config: `{"obfs":{"type":"salamander"}}`, wantErr: true},
+		{name: "hysteria2_bad_hop_ports", protocol: "hysteria2", config: `{"hop_ports":"30000-20000"}`, wantErr: true},
+		{name: "shadowtls_valid", protocol: "shadowtls", config: `{"handshake":{"server":"www.example.com"},"method":"2022-blake3-aes-128-gcm","password":"AAAAAAAAAAAAAAAAAAAAAA=="}`, wantErr: false},
+		{name: "shadowtls_missing_handshake", protocol: "shadowtls", config: `{"method":"2022-blake3-aes-128-gcm","password":"AAAAAAAAAAAAAAAAAAAAAA=="}`, wantErr: true},
+		{name: "anytls_with_tls", protocol: "anytls", config: `{"tls":{"enabled":true}}`, wantErr: false},
+		{name: "anytls_without_tls", protocol: "anytls", config: `{}`, wantErr: true},
+		{name: "naive_without_tls", protocol: "naive", config: `{"tls":{"enabled":false}}`, wantErr: true},
+		{name: "vmess_ws", protocol: "vmess", config: `{"transport":{"type":"ws","path":"/vm"},"tls":{"enabled":true}}`, wantErr: false},
+		{name: "vmess_unknown_transport", protocol: "vmess", config: `{"transport":{"type":"kcp"}}`, wantErr: true},
+		{name: "vmess_transport_not_object", protocol: "vmess", config: `{"transport":"ws"}`, wantErr: true},
+		{name: "tuic_with_tls", protocol: "tuic", config: `{"congestion_control":"bbr","tls":{"enabled":true}}`, wantErr: false},
+		{name: "tuic_without_tls", protocol: "tuic", config: `{"congestion_control":"bbr"}`, wantErr: true},
+		{name: "tuic_tls_disabled", protocol: "tuic", config: `{"tls":{"enabled":false}}`, wantErr: true},
+		{name: "shadowsocks_legacy_method", protocol: "shadowsocks", config: `{"method":"aes-128-gcm","password":"x"}`, wantErr: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateInbound(tc.protocol, []byte(tc.config))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("validateInbound() err = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestPrepareInboundConfigGeneratesShadowsocksPSK(t *testing.T) {
+	out, err := prepareInboundConfig("shadowsocks", nil)
+	if err != nil {
+		t.Fatalf("prepareInboundConfig: %v", err)
+	}
+	if err := validateInbound("shadowsocks", out); err != nil {
+		t.Fatalf("validateInbound after prepare: %v; config=%s", err, out)
+	}
+}
+
+func TestPrepareInboundConfigGeneratesRealityKeys(t *testing.T) {
+	in := []byte(`{"tls":{"enabled":true,"reality":{"enabled":true,"handshake":{"server":"www.example.com","server_port":443}}}}`)
+	out, err := prepareInboundConfig("vless", in)
+	if err != nil {
+		t.Fatalf("prepareInboundConfig: %v", err)
+	}
+	if err := validateInbound("vless", out); err != nil {
+		t.Fatalf("validateInbound after prepare: %v; config=%s", err, out)
+	}
+}
+
+func TestUpdateInboundKeepsSecrets(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	binary := filepath.Join(t.TempDir(), "sing-box")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\n[ \"$1\" = check ] && exit 0\nexit 1\n"), 0755); err != nil {
+		t.Fatalf("write fake binary: %v", err)
+	}
+	cfg := testCoreConfig(t, binary)
+	r := chi.NewRouter()
+	r.Put("/api/inbounds/{id}", UpdateInboundHandler(nil, cfg))
+	realityKey, _, err := core.GenerateRealityKeypair()
+	if err != nil {
+		t.Fatalf("GenerateRealityKeypair: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		protocol string
+		stored   string
+		update   string
+		keep     []string // config_json paths that must survive the update
+	}{
+		{name: "shadowsocks_password", protocol: "shadowsocks",
+			stored: `{"method":"2022-blake3-aes-128-gcm","password":"AAAAAAAAAAAAAAAAAAAAAA=="}`,
+			update: `{}`,
+			keep:   []string{"method", "password"}},
+		{name: "reality_keys", protocol: "vless",
+			stored: `{"tls":{"enabled":true,"reality":{"enabled":true,"handshake":{"server":"www.example.com","server_port":443},` +
+				`"private_key":"` + realityKey + `","short_id":["0123abcd"]}}}`,
+			update: `{"tls":{"enabled":true,"reality":{"enabled":true,"handshake":{"server":"www.example.org","server_port":443}}}}`,
+			keep:   []string{"tls.reality.private_key", "tls.reality.short_id"}},
+	}
+	for i, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ib := &db.Inbound{Tag: tc.name, Protocol: tc.protocol, Listen: "::", ListenPort: uint(10000 + i), ConfigJSON: datatypes.JSON(tc.stored)}
+			if err := db.CreateInbound(ib); err != nil {
+				t.Fatalf("CreateInbound: %v", err)
+			}
+			body := fmt.Sprintf(`{"tag":%q,"protocol":%q,"listen_port":%d,"config_json":%s}`, ib.Tag, tc.protocol, ib.ListenPort+1, tc.update)
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, fmt.Sprintf("/api/inbounds/%d", ib.ID), strings.NewReader(body)))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body=%s", rec.Code, rec.Body.String())
+			}
+			updated, err := db.GetInboundByID(ib.ID)
+			if err != nil {
+				t.Fatalf("GetInboundByID: %v", err)
+			}
+			var before, after map[string]any
+			json.Unmarshal([]byte(tc.stored), &before)
+			json.Unmarshal(updated.ConfigJSON, &after)
+			for _, path := range tc.keep {
+				if want, got := lookupJSONPath(before, path), lookupJSONPath(after, path); fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Fatalf("%s = %v, want %v; config=%s", path, got, want, updated.ConfigJSON)
+				}
+			}
+		})
+	}
+}
+
+// lookupJSONPath returns the value at a dot-separated path of nested objects.
+func lookupJSONPath(m map[string]any, path string) any {
+	var v any = m
+	for _, key := range strings.Split(path, ".") {
+		obj, _ := v.(map[string]any)
+		v = obj[key]
+	}
+	return v
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/s-ui/s-ui/internal/config"
+	"github.com/s-ui/s-ui/internal/core"
+	"github.com/s-ui/s-ui/internal/db"
+)
+
+// logSettings is the GET/PUT /api/core/log-settings body for the generated log block.
+type logSettings struct {
+	Level     string `json:"level"`
+	Timestamp bool   `json:"timestamp"`
+}
+
+func loadLogSettings() (*logSettings, error) {
+	level, err := db.GetSetting(db.SettingLogLevel)
+	if err != nil {
+		return nil, err
+	}
+	timestamp, err := db.GetSetting(db.SettingLogTimestamp)
+	if err != nil {
+		return nil, err
+	}
+	if level == "" {
+		level = "info"
+	}
+	return &logSettings{Level: level, Timestamp: timestamp != "false"}, nil
+}
+
+func saveLogSettings(s *logSettings) error {
+	if err := db.SetSetting(db.SettingLogLevel, s.Level); err != nil {
+		return err
+	}
+	return db.SetSetting(db.SettingLogTimestamp, strconv.FormatBool(s.Timestamp))
+}
+
+// GetLogSettingsHandler returns GET /api/core/log-settings handler.
+func GetLogSettingsHandler(sm *scs.SessionManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s, err := loadLogSettings()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, s)
+	}
+}
+
+// UpdateLogSettingsHandler handles PUT /api/core/log-settings (sing-box log level and timestamps).
+func UpdateLogSettingsHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := logSettings{Level: "info", Timestamp: true}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := core.ValidateLogLevel(req.Level); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Level == "" {
+			req.Level = "info"
+		}
+		old, err := loadLogSettings()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := saveLogSettings(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !applyGeneratedConfig(w, panelCfg, "log settings update", func() { saveLogSettings(old) }) {
+			return
+		}
+		writeJSON(w, http.StatusOK, req)
+	}
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/s-ui/s-ui/internal/core"
+	"github.com/s-ui/s-ui/internal/db"
+)
+
+// groupDelayTimeout bounds each member probe of POST /api/outbounds/:id/delay.
+const groupDelayTimeout = 5 * time.Second
+
+// groupMemberItem is one member of an outbound group with its last measured delay
+// in ms; 0 means untested or unreachable.
+type groupMemberItem struct {
+	Tag   string `json:"tag"`
+	Delay int    `json:"delay"`
+}
+
+// loadOutboundGroup reads the {id} group, writing 404/400 when it is missing or not a group.
+func loadOutboundGroup(w http.ResponseWriter, r *http.Request) (*db.Outbound, bool) {
+	id, ok := parseIDParam(r)
+	if !ok {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return nil, false
+	}
+	ob, err := db.GetOutboundByID(id)
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return nil, false
+	}
+	if !core.IsOutboundGroupType(ob.Type) {
+		http.Error(w, "outbound is not a selector or urltest group", http.StatusBadRequest)
+		return nil, false
+	}
+	return ob, true
+}
+
+// GetOutboundGroupHandler handles GET /api/outbounds/:id/group. It reads the running
+// group from the Clash API: the active member and each member's last delay.
+func GetOutboundGroupHandler(sm *scs.SessionManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ob, ok := loadOutboundGroup(w, r)
+		if !ok {
+			return
+		}
+		proxies, err := core.NewClashClientFromEnv().Proxies(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+			return
+		}
+		group, ok := proxies[ob.Tag]
+		if !ok {
+			writeJSON(w, http.StatusBadGateway, map[string]string{"error": "group not loaded by sing-box: " + ob.Tag})
+			return
+		}
+		members := make([]groupMemberItem, 0, len(group.All))
+		for _, tag := range group.All {
+			p := proxies[tag]
+			members = append(members, groupMemberItem{Tag: tag, Delay: p.LastDelay()})
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"tag":     ob.Tag,
+			"type":    ob.Type,
+			"now":     group.Now,
+			"members": members,
+		})
+	}
+}
+
+// selectGroupRequest is the PUT /api/outbounds/:id/selected body.
+type selectGroupRequest struct {
+	Outbound string `json:"outbound"`
+}
+
+// SelectOutboundGroupHandler handles PUT /api/outbounds/:id/selected. It switches a
+// selector at runtime through the Clash API without regenerating the config; after a
+// core restart the group starts from its configured default again.
+func SelectOutboundGroupHandler(sm *scs.SessionManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ob, ok := loadOutboundGroup(w, r)
+		if !ok {
+			return
+		}
+		if ob.Type != "selector" {
+			http.Error(w, "only selector groups can be switched", http.StatusBadRequest)
+			return
+		}
+		var req selectGroupRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		req.Outbound = strings.TrimSpace(req.Outbound)
+		if !containsString(db.OutboundGroupMembers(ob), req.Outbound) {
+			http.Error(w, "outbound is not a member of this group", http.StatusBadRequest)
+			return
+		}
+		if err := core.NewClashClientFromEnv().SelectProxy(r.Context(), ob.Tag, req.Outbound); err != nil {
+			writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"now": req.Outbound})
+	}
+}
+
+// TestOutboundGroupHandler handles POST /api/outbounds/:id/delay. Every member is
+// probed with the group's url (or the sing-box default); unreachable members are omitted.
+func TestOutboundGroupHandler(sm *scs.SessionManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ob, ok := loadOutboundGroup(w, r)
+		if !ok {
+			return
+		}
+		testURL := core.DefaultURLTestURL
+		var cfg struct {
+			URL string `json:"url"`
+		}
+		if len(ob.ConfigJSON) > 0 && json.Unmarshal(ob.ConfigJSON, &cfg) == nil && cfg.URL != "" {
+			testURL = cfg.URL
+		}
+		delays, err := core.NewClashClientFromEnv().GroupDelay(r.Context(), ob.Tag, testURL, groupDelayTimeout)
+		if err != nil {
+			writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"url": testURL, "delays": delays})
+	}
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/s-ui/s-ui/internal/db"
+	"gorm.io/datatypes"
+)
+
+func TestOutboundGroupHandlers(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	group := &db.Outbound{Tag: "exit", Type: "selector", ConfigJSON: datatypes.JSON(`{"outbounds":["warp","direct"]}`)}
+	for _, ob := range []*db.Outbound{{Tag: "warp", Type: "direct"}, group} {
+		if err := db.CreateOutbound(ob); err != nil {
+			t.Fatalf("CreateOutbound: %v", err)
+		}
+	}
+
+	now := "warp"
+	var gotAuth string
+	clash := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/proxies":
+			fmt.Fprintf(w, `{"proxies":{"exit":{"type":"Selector","now":%q,"all":["warp","direct"],"history":[]},`+
+				`"warp":{"type":"Direct","history":[{"time":"t","delay":120}]},"direct":{"type":"Direct","history":[]}}}`, now)
+		case r.Method == http.MethodPut && r.URL.Path == "/proxies/exit":
+			var body struct{ Name string }
+			json.NewDecoder(r.Body).Decode(&body)
+			now = body.Name
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodGet && r.URL.Path == "/group/exit/delay":
+			if r.URL.Query().Get("url") == "" || r.URL.Query().Get("timeout") != "5000" {
+				http.Error(w, `{"message":"bad query"}`, http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"warp":95,"direct":12}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer clash.Close()
+	t.Setenv("CLASH_API_LISTEN", clash.URL)
+	t.Setenv("CLASH_API_SECRET", "s3cret")
+
+	serve := func(h http.HandlerFunc, method, path, body string) *httptest.ResponseRecorder {
+		r := chi.NewRouter()
+		r.Method(method, "/api/outbounds/{id}/*", h)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+	path := fmt.Sprintf("/api/outbounds/%d/", group.ID)
+
+	rec := serve(SelectOutboundGroupHandler(nil), http.MethodPut, path+"selected", `{"outbound":"direct"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("select: %d %s", rec.Code, rec.Body)
+	}
+	if now != "direct" || gotAuth != "Bearer s3cret" {
+		t.Fatalf("clash state now=%q auth=%q", now, gotAuth)
+	}
+	rec = serve(SelectOutboundGroupHandler(nil), http.MethodPut, path+"selected", `{"outbound":"block"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("select non-member: %d", rec.Code)
+	}
+
+	rec = serve(GetOutboundGroupHandler(nil), http.MethodGet, path+"group", "")
+	var info struct {
+		Now     string            `json:"now"`
+		Members []groupMemberItem `json:"members"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("group: %d %s", rec.Code, rec.Body)
+	}
+	if info.Now != "direct" || len(info.Members) != 2 || info.Members[0] != (groupMemberItem{Tag: "warp", Delay: 120}) {
+		t.Fatalf("group = %+v", info)
+	}
+
+	rec = serve(TestOutboundGroupHandler(nil), http.MethodPost, path+"delay", "")
+	var delays struct {
+		Delays map[string]int `json:"delays"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &delays); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("delay: %d %s", rec.Code, rec.Body)
+	}
+	if delays.Delays["warp"] != 95 {
+		t.Fatalf("delays = %v", delays.Delays)
+	}
+
+	clash.Close()
+	rec = serve(GetOutboundGroupHandler(nil), http.MethodGet, path+"group", "")
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("group with clash api down: %d", rec.Code)
+	}
+}
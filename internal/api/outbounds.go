@@ -0,0 +1,538 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/s-ui/s-ui/internal/config"
+	"github.com/s-ui/s-ui/internal/core"
+	"github.com/s-ui/s-ui/internal/db"
+	"gorm.io/datatypes"
+)
+
+// outboundItem is the API response shape for list/get.
+type outboundItem struct {
+	ID         uint   `json:"id"`
+	Tag        string `json:"tag"`
+	Type       string `json:"type"`
+	Server     string `json:"server"`
+	ServerPort uint   `json:"server_port"`
+	Detour     string `json:"detour"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// outboundFromDB converts db.Outbound to API outboundItem.
+// WireGuard outbounds report the first peer as server.
+func outboundFromDB(ob *db.Outbound) outboundItem {
+	item := outboundItem{
+		ID:        ob.ID,
+		Tag:       ob.Tag,
+		Type:      ob.Type,
+		Detour:    db.OutboundDetour(ob),
+		CreatedAt: ob.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	var cfg map[string]any
+	if len(ob.ConfigJSON) == 0 || json.Unmarshal(ob.ConfigJSON, &cfg) != nil {
+		return item
+	}
+	if peers, ok := cfg["peers"].([]any); ok && len(peers) > 0 {
+		peer, _ := peers[0].(map[string]any)
+		item.Server, _ = peer["address"].(string)
+		item.ServerPort, _ = toUintValue(peer["port"])
+		return item
+	}
+	item.Server, _ = cfg["server"].(string)
+	item.ServerPort, _ = toUintValue(cfg["server_port"])
+	return item
+}
+
+// outboundDetail adds config_json for edit form.
+type outboundDetail struct {
+	outboundItem
+	ConfigJSON datatypes.JSON `json:"config_json,omitempty"`
+}
+
+// outboundRequest is the POST/PUT body for create and update.
+type outboundRequest struct {
+	Tag        string         `json:"tag"`
+	Type       string         `json:"type"`
+	ConfigJSON datatypes.JSON `json:"config_json"`
+}
+
+// supportedOutboundTypes lists outbound types ConfigGenerator can emit.
+var supportedOutboundTypes = map[string]bool{
+	"direct":      true,
+	"socks":       true,
+	"http":        true,
+	"shadowsocks": true,
+	"vless":       true,
+	"wireguard":   true,
+	"selector":    true,
+	"urltest":     true,
+}
+
+// toUintValue converts a JSON number to uint.
+func toUintValue(v any) (uint, bool) {
+	f, ok := v.(float64)
+	if !ok || f < 0 || f != float64(uint(f)) {
+		return 0, false
+	}
+	return uint(f), true
+}
+
+// validateServer checks server and server_port of proxy outbounds.
+func validateServer(typ string, cfg map[string]any) error {
+	if server, _ := cfg["server"].(string); server == "" {
+		return fmt.Errorf("%s outbound requires server", typ)
+	}
+	if port, ok := toUintValue(cfg["server_port"]); !ok || port == 0 || port > 65535 {
+		return fmt.Errorf("%s outbound requires server_port", typ)
+	}
+	return nil
+}
+
+// validateWireGuardKey checks that key is a base64 encoded 32-byte curve25519 key.
+func validateWireGuardKey(name string, raw any) error {
+	key, _ := raw.(string)
+	b, err := base64.StdEncoding.DecodeString(key)
+	if key == "" || err != nil || len(b) != 32 {
+		return fmt.Errorf("wireguard %s must be a base64 32-byte key", name)
+	}
+	return nil
+}
+
+// validateOutbound checks type support and type-specific config_json requirements.
+func validateOutbound(typ string, configJSON []byte) error {
+	if !supportedOutboundTypes[typ] {
+		return fmt.Errorf("unsupported outbound type: %s", typ)
+	}
+	cfg := map[string]any{}
+	if len(configJSON) > 0 {
+		if err := json.Unmarshal(configJSON, &cfg); err != nil || cfg == nil {
+			return errors.New("invalid config_json")
+		}
+	}
+	if detour, ok := cfg["detour"]; ok {
+		if _, ok := detour.(string); !ok {
+			return errors.New("detour must be a string")
+		}
+		if core.IsOutboundGroupType(typ) {
+			return fmt.Errorf("%s outbound does not support detour", typ)
+		}
+	}
+	switch typ {
+	case "direct":
+		if v, ok := cfg["bind_interface"]; ok {
+			if _, ok := v.(string); !ok {
+				return errors.New("bind_interface must be a string")
+			}
+		}
+	case "socks":
+		if err := validateServer(typ, cfg); err != nil {
+			return err
+		}
+		if v, ok := cfg["version"].(string); ok && v != "" {
+			switch v {
+			case "4", "4a", "5":
+			default:
+				return fmt.Errorf("unsupported socks version: %s", v)
+			}
+		}
+	case "http":
+		if err := validateServer(typ, cfg); err != nil {
+			return err
+		}
+	case "shadowsocks":
+		if err := validateServer(typ, cfg); err != nil {
+			return err
+		}
+		if method, _ := cfg["method"].(string); method == "" {
+			return errors.New("shadowsocks outbound requires method")
+		}
+		if password, _ := cfg["password"].(string); password == "" {
+			return errors.New("shadowsocks outbound requires password")
+		}
+	case "vless":
+		if err := validateServer(typ, cfg); err != nil {
+			return err
+		}
+		id, _ := cfg["uuid"].(string)
+		if _, err := uuid.Parse(id); err != nil {
+			return errors.New("vless outbound requires a valid uuid")
+		}
+		if flow, ok := cfg["flow"].(string); ok && flow != "" && flow != core.FlowVision {
+			return fmt.Errorf("unsupported flow: %s", flow)
+		}
+	case "wireguard":
+		if err := validateWireGuardKey("private_key", cfg["private_key"]); err != nil {
+			return err
+		}
+		if addrs, _ := cfg["address"].([]any); len(addrs) == 0 {
+			return errors.New("wireguard outbound requires address")
+		}
+		peers, _ := cfg["peers"].([]any)
+		if len(peers) == 0 {
+			return errors.New("wireguard outbound requires at least one peer")
+		}
+		for _, p := range peers {
+			peer, _ := p.(map[string]any)
+			if addr, _ := peer["address"].(string); addr == "" {
+				return errors.New("wireguard peer requires address")
+			}
+			if port, ok := toUintValue(peer["port"]); !ok || port == 0 || port > 65535 {
+				return errors.New("wireguard peer requires port")
+			}
+			if err := validateWireGuardKey("peer public_key", peer["public_key"]); err != nil {
+				return err
+			}
+		}
+	case "selector", "urltest":
+		members, err := groupMembers(typ, cfg)
+		if err != nil {
+			return err
+		}
+		if typ == "selector" {
+			if def, ok := cfg["default"]; ok {
+				s, _ := def.(string)
+				if !containsString(members, s) {
+					return errors.New("selector default must be one of its outbounds")
+				}
+			}
+			break
+		}
+		if v, ok := cfg["url"]; ok {
+			s, _ := v.(string)
+			if u, err := url.Parse(s); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return errors.New("urltest url must be an http(s) URL")
+			}
+		}
+		for _, key := range []string{"interval", "idle_timeout"} {
+			if v, ok := cfg[key]; ok {
+				s, _ := v.(string)
+				if d, err := time.ParseDuration(s); err != nil || d <= 0 {
+					return fmt.Errorf("invalid %s: %v", key, v)
+				}
+			}
+		}
+		if v, ok := cfg["tolerance"]; ok {
+			if _, ok := toUintValue(v); !ok {
+				return errors.New("tolerance must be a non-negative integer")
+			}
+		}
+	}
+	return nil
+}
+
+// groupMembers returns the outbounds list of a group config, which must be a
+// non-empty list of distinct tags.
+func groupMembers(typ string, cfg map[string]any) ([]string, error) {
+	raw, _ := cfg["outbounds"].([]any)
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("%s outbound requires outbounds", typ)
+	}
+	members := make([]string, 0, len(raw))
+	for _, v := range raw {
+		s, _ := v.(string)
+		if s == "" {
+			return nil, errors.New("outbounds must contain non-empty tags")
+		}
+		if containsString(members, s) {
+			return nil, fmt.Errorf("duplicate outbound in group: %s", s)
+		}
+		members = append(members, s)
+	}
+	return members, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// validateOutboundGroup checks that group members exist and that nesting groups does not form a cycle.
+func validateOutboundGroup(tag string, members []string) error {
+	for _, m := range members {
+		if m == tag {
+			return errors.New("outbound group cannot contain itself")
+		}
+		if err := validateOutboundRef(m); err != nil {
+			return err
+		}
+	}
+	outbounds, err := db.ListOutbounds()
+	if err != nil {
+		return err
+	}
+	graph := make(map[string][]string, len(outbounds)+1)
+	for i := range outbounds {
+		graph[outbounds[i].Tag] = db.OutboundGroupMembers(&outbounds[i])
+	}
+	graph[tag] = members
+	seen := map[string]bool{}
+	var visit func(string) bool
+	visit = func(t string) bool {
+		for _, m := range graph[t] {
+			if m == tag {
+				return true
+			}
+			if !seen[m] {
+				seen[m] = true
+				if visit(m) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if visit(tag) {
+		return errors.New("outbound group forms a cycle")
+	}
+	return nil
+}
+
+// validateOutboundDetour checks that detour names another outbound, or a built-in one,
+// and does not form a cycle.
+func validateOutboundDetour(tag, detour string) error {
+	if detour == "" {
+		return nil
+	}
+	if detour == tag {
+		return errors.New("outbound cannot detour to itself")
+	}
+	outbounds, err := db.ListOutbounds()
+	if err != nil {
+		return err
+	}
+	chain := make(map[string]string, len(outbounds)+2)
+	chain[core.OutboundDirect] = ""
+	chain[core.OutboundBlock] = ""
+	for i := range outbounds {
+		chain[outbounds[i].Tag] = db.OutboundDetour(&outbounds[i])
+	}
+	if _, ok := chain[detour]; !ok {
+		return fmt.Errorf("detour outbound not found: %s", detour)
+	}
+	for next, hops := detour, 0; next != "" && hops <= len(chain); hops++ {
+		if next == tag {
+			return fmt.Errorf("detour %s forms a cycle", detour)
+		}
+		next = chain[next]
+	}
+	return nil
+}
+
+// parseIDParam reads the {id} path parameter.
+func parseIDParam(r *http.Request) (uint, bool) {
+	id64, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint(id64), true
+}
+
+// ListOutboundsHandler returns GET /api/outbounds handler.
+func ListOutboundsHandler(sm *scs.SessionManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		outbounds, err := db.ListOutbounds()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		items := make([]outboundItem, len(outbounds))
+		for i := range outbounds {
+			items[i] = outboundFromDB(&outbounds[i])
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": items})
+	}
+}
+
+// GetOutboundHandler returns GET /api/outbounds/:id handler.
+func GetOutboundHandler(sm *scs.SessionManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := parseIDParam(r)
+		if !ok {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		ob, err := db.GetOutboundByID(id)
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, outboundDetail{
+			outboundItem: outboundFromDB(ob),
+			ConfigJSON:   ob.ConfigJSON,
+		})
+	}
+}
+
+// decodeOutboundRequest decodes and validates the create/update body, writing 400 on failure.
+func decodeOutboundRequest(w http.ResponseWriter, r *http.Request) (*outboundRequest, bool) {
+	var req outboundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return nil, false
+	}
+	req.Tag = strings.TrimSpace(req.Tag)
+	if req.Tag == "" || req.Type == "" {
+		http.Error(w, "tag and type required", http.StatusBadRequest)
+		return nil, false
+	}
+	if core.IsBuiltinOutboundTag(req.Tag) {
+		http.Error(w, "tag is reserved for built-in outbounds", http.StatusBadRequest)
+		return nil, false
+	}
+	if err := validateOutbound(req.Type, req.ConfigJSON); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return &req, true
+}
+
+// CreateOutboundHandler handles POST /api/outbounds.
+func CreateOutboundHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := decodeOutboundRequest(w, r)
+		if !ok {
+			return
+		}
+		exists, err := db.OutboundExistsByTag(req.Tag)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if exists {
+			http.Error(w, "tag already exists", http.StatusBadRequest)
+			return
+		}
+		ob := &db.Outbound{Tag: req.Tag, Type: req.Type, ConfigJSON: req.ConfigJSON}
+		if err := validateOutboundDetour(ob.Tag, db.OutboundDetour(ob)); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if core.IsOutboundGroupType(ob.Type) {
+			if err := validateOutboundGroup(ob.Tag, db.OutboundGroupMembers(ob)); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if err := db.CreateOutbound(ob); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !applyGeneratedConfig(w, panelCfg, "outbound create", func() { db.DeleteOutbound(ob.ID) }) {
+			return
+		}
+		writeJSON(w, http.StatusCreated, outboundFromDB(ob))
+	}
+}
+
+// UpdateOutboundHandler handles PUT /api/outbounds/:id.
+func UpdateOutboundHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := parseIDParam(r)
+		if !ok {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		old, err := db.GetOutboundByID(id)
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		req, ok := decodeOutboundRequest(w, r)
+		if !ok {
+			return
+		}
+		if req.Tag != old.Tag {
+			exists, err := db.OutboundExistsByTag(req.Tag)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if exists {
+				http.Error(w, "tag already exists", http.StatusBadRequest)
+				return
+			}
+		}
+		// A referenced outbound keeps its tag and type: detours, groups and rules were
+		// validated against what it is now.
+		if req.Tag != old.Tag || req.Type != old.Type {
+			refs, err := outboundUsers(old.Tag)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if len(refs) > 0 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "outbound is in use by: " + strings.Join(refs, ", ")})
+				return
+			}
+		}
+		updated := &db.Outbound{ID: id, Tag: req.Tag, Type: req.Type, ConfigJSON: req.ConfigJSON, CreatedAt: old.CreatedAt}
+		if err := validateOutboundDetour(updated.Tag, db.OutboundDetour(updated)); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if core.IsOutboundGroupType(updated.Type) {
+			if err := validateOutboundGroup(updated.Tag, db.OutboundGroupMembers(updated)); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if err := db.UpdateOutbound(updated); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !applyGeneratedConfig(w, panelCfg, "outbound update", func() { db.UpdateOutbound(old) }) {
+			return
+		}
+		writeJSON(w, http.StatusOK, outboundFromDB(updated))
+	}
+}
+
+// DeleteOutboundHandler handles DELETE /api/outbounds/:id.
+func DeleteOutboundHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := parseIDParam(r)
+		if !ok {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		ob, err := db.GetOutboundByID(id)
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		refs, err := outboundUsers(ob.Tag)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(refs) > 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "outbound is in use by: " + strings.Join(refs, ", ")})
+			return
+		}
+		if err := db.DeleteOutbound(id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !applyGeneratedConfig(w, panelCfg, "outbound delete", func() { db.CreateOutbound(ob) }) {
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
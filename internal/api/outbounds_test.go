@@ -0,0 +1,177 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/s-ui/s-ui/internal/db"
+	"gorm.io/datatypes"
+)
+
+const testWireGuardKey = "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+
+func TestValidateOutbound(t *testing.T) {
+	cases := []struct {
+		name    string
+		typ     string
+		config  string
+		wantErr bool
+	}{
+		{name: "direct_empty", typ: "direct", config: "", wantErr: false},
+		{name: "direct_bind_interface", typ: "direct", config: `{"bind_interface":"eth1"}`, wantErr: false},
+		{name: "unknown_type", typ: "tor", config: "", wantErr: true},
+		{name: "invalid_config_json", typ: "socks", config: "{", wantErr: true},
+		{name: "socks_valid", typ: "socks", config: `{"server":"127.0.0.1","server_port":1080,"version":"5"}`, wantErr: false},
+		{name: "socks_missing_port", typ: "socks", config: `{"server":"127.0.0.1"}`, wantErr: true},
+		{name: "socks_bad_version", typ: "socks", config: `{"server":"127.0.0.1","server_port":1080,"version":"6"}`, wantErr: true},
+		{name: "http_missing_server", typ: "http", config: `{"server_port":8080}`, wantErr: true},
+		{name: "shadowsocks_missing_password", typ: "shadowsocks", config: `{"server":"a.example.com","server_port":8388,"method":"aes-128-gcm"}`, wantErr: true},
+		{name: "vless_valid", typ: "vless", config: `{"server":"a.example.com","server_port":443,"uuid":"bf000d23-0752-40b4-affe-68f7707a9661"}`, wantErr: false},
+		{name: "vless_bad_uuid", typ: "vless", config: `{"server":"a.example.com","server_port":443,"uuid":"x"}`, wantErr: true},
+		{name: "detour_not_string", typ: "direct", config: `{"detour":1}`, wantErr: true},
+		{
+			name:    "wireguard_valid",
+			typ:     "wireguard",
+			config:  `{"private_key":"` + testWireGuardKey + `","address":["172.16.0.2/32"],"peers":[{"address":"engage.cloudflareclient.com","port":2408,"public_key":"` + testWireGuardKey + `"}]}`,
+			wantErr: false,
+		},
+		{name: "wireguard_bad_key", typ: "wireguard", config: `{"private_key":"short","address":["172.16.0.2/32"]}`, wantErr: true},
+		{name: "wireguard_no_peers", typ: "wireguard", config: `{"private_key":"` + testWireGuardKey + `","address":["172.16.0.2/32"]}`, wantErr: true},
+		{name: "selector_valid", typ: "selector", config: `{"outbounds":["warp","direct"],"default":"warp"}`, wantErr: false},
+		{name: "selector_no_members", typ: "selector", config: `{"outbounds":[]}`, wantErr: true},
+		{name: "selector_duplicate_member", typ: "selector", config: `{"outbounds":["warp","warp"]}`, wantErr: true},
+		{name: "selector_bad_default", typ: "selector", config: `{"outbounds":["warp"],"default":"direct"}`, wantErr: true},
+		{name: "selector_detour", typ: "selector", config: `{"outbounds":["warp"],"detour":"direct"}`, wantErr: true},
+		{name: "urltest_valid", typ: "urltest", config: `{"outbounds":["warp","direct"],"url":"https://cp.cloudflare.com","interval":"3m","tolerance":50}`, wantErr: false},
+		{name: "urltest_bad_interval", typ: "urltest", config: `{"outbounds":["warp"],"interval":"often"}`, wantErr: true},
+		{name: "urltest_bad_url", typ: "urltest", config: `{"outbounds":["warp"],"url":"ftp://x"}`, wantErr: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateOutbound(tc.typ, []byte(tc.config))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("validateOutbound() err = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateOutboundDetour(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	for _, ob := range []*db.Outbound{
+		{Tag: "hop-a", Type: "socks", ConfigJSON: datatypes.JSON(`{"server":"a","server_port":1080}`)},
+		{Tag: "hop-b", Type: "socks", ConfigJSON: datatypes.JSON(`{"server":"b","server_port":1080,"detour":"hop-a"}`)},
+	} {
+		if err := db.CreateOutbound(ob); err != nil {
+			t.Fatalf("CreateOutbound: %v", err)
+		}
+	}
+
+	if err := validateOutboundDetour("hop-c", "hop-b"); err != nil {
+		t.Fatalf("chain through hop-b: %v", err)
+	}
+	if err := validateOutboundDetour("hop-c", "direct"); err != nil {
+		t.Fatalf("detour to direct: %v", err)
+	}
+	if err := validateOutboundDetour("hop-c", "block"); err != nil {
+		t.Fatalf("detour to block: %v", err)
+	}
+	if err := validateOutboundDetour("hop-c", "missing"); err == nil {
+		t.Fatal("expected error for unknown detour")
+	}
+	if err := validateOutboundDetour("hop-a", "hop-a"); err == nil {
+		t.Fatal("expected error for self detour")
+	}
+	if err := validateOutboundDetour("hop-a", "hop-b"); err == nil {
+		t.Fatal("expected error for detour cycle hop-a -> hop-b -> hop-a")
+	}
+}
+
+func TestValidateOutboundGroup(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	for _, ob := range []*db.Outbound{
+		{Tag: "warp", Type: "direct"},
+		{Tag: "auto", Type: "urltest", ConfigJSON: datatypes.JSON(`{"outbounds":["warp","direct"]}`)},
+		{Tag: "pick", Type: "selector", ConfigJSON: datatypes.JSON(`{"outbounds":["auto","warp"]}`)},
+	} {
+		if err := db.CreateOutbound(ob); err != nil {
+			t.Fatalf("CreateOutbound: %v", err)
+		}
+	}
+
+	if err := validateOutboundGroup("new", []string{"pick", "direct"}); err != nil {
+		t.Fatalf("nested group: %v", err)
+	}
+	if err := validateOutboundGroup("new", []string{"missing"}); err == nil {
+		t.Fatal("expected error for unknown member")
+	}
+	if err := validateOutboundGroup("new", []string{"new"}); err == nil {
+		t.Fatal("expected error for self member")
+	}
+	if err := validateOutboundGroup("auto", []string{"pick"}); err == nil {
+		t.Fatal("expected error for group cycle auto -> pick -> auto")
+	}
+
+	users, err := outboundUsers("auto")
+	if err != nil {
+		t.Fatalf("outboundUsers: %v", err)
+	}
+	if len(users) != 1 || users[0] != "outbound group pick" {
+		t.Fatalf("outboundUsers(auto) = %v", users)
+	}
+}
+
+func TestUpdateOutboundTypeChangeChecksReferences(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	binary := filepath.Join(t.TempDir(), "sing-box")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\n[ \"$1\" = check ] && exit 0\nexit 1\n"), 0755); err != nil {
+		t.Fatalf("write fake binary: %v", err)
+	}
+	cfg := testCoreConfig(t, binary)
+	hop := &db.Outbound{Tag: "hop", Type: "socks", ConfigJSON: datatypes.JSON(`{"server":"a","server_port":1080}`)}
+	for _, ob := range []*db.Outbound{
+		hop,
+		{Tag: "pick", Type: "selector", ConfigJSON: datatypes.JSON(`{"outbounds":["hop","direct"]}`)},
+	} {
+		if err := db.CreateOutbound(ob); err != nil {
+			t.Fatalf("CreateOutbound: %v", err)
+		}
+	}
+	r := chi.NewRouter()
+	r.Put("/api/outbounds/{id}", UpdateOutboundHandler(nil, cfg))
+
+	cases := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "type_change", body: `{"tag":"hop","type":"urltest","config_json":{"outbounds":["direct"]}}`, wantStatus: http.StatusBadRequest},
+		{name: "same_type", body: `{"tag":"hop","type":"socks","config_json":{"server":"b","server_port":1080}}`, wantStatus: http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, fmt.Sprintf("/api/outbounds/%d", hop.ID), strings.NewReader(tc.body)))
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d, body=%s", rec.Code, tc.wantStatus, rec.Body.String())
+			}
+			if tc.wantStatus == http.StatusBadRequest && !strings.Contains(rec.Body.String(), "outbound group pick") {
+				t.Fatalf("body = %s, want the referencing group", rec.Body.String())
+			}
+		})
+	}
+}
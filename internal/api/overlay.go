@@ -0,0 +1,64 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/s-ui/s-ui/internal/config"
+	"github.com/s-ui/s-ui/internal/core"
+	"github.com/s-ui/s-ui/internal/db"
+)
+
+// GetOverlayHandler handles GET /api/core/overlay. An unset overlay is returned as {}.
+func GetOverlayHandler(sm *scs.SessionManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		raw, err := db.GetSetting(db.SettingConfigOverlay)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		overlay := json.RawMessage("{}")
+		if raw != "" {
+			overlay = json.RawMessage(raw)
+		}
+		writeJSON(w, http.StatusOK, map[string]json.RawMessage{"overlay": overlay})
+	}
+}
+
+// UpdateOverlayHandler handles PUT /api/core/overlay. The body is the overlay object,
+// merged into every generated config; {} clears it. The config is applied right away
+// and the previous overlay is restored when it is rejected.
+func UpdateOverlayHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, importMaxSize))
+		if err != nil {
+			http.Error(w, "overlay too large", http.StatusBadRequest)
+			return
+		}
+		overlay, err := core.ParseOverlay(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		value := ""
+		if len(overlay) > 0 {
+			b, _ := json.Marshal(overlay)
+			value = string(b)
+		}
+		old, err := db.GetSetting(db.SettingConfigOverlay)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := db.SetSetting(db.SettingConfigOverlay, value); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !applyGeneratedConfig(w, panelCfg, "overlay update", func() { db.SetSetting(db.SettingConfigOverlay, old) }) {
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"overlay": overlay})
+	}
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/s-ui/s-ui/internal/db"
+)
+
+func TestUpdateOverlayHandler(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	binary := filepath.Join(t.TempDir(), "sing-box")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\n[ \"$1\" = check ] && exit 0\nexit 1\n"), 0755); err != nil {
+		t.Fatalf("write fake binary: %v", err)
+	}
+	cfg := testCoreConfig(t, binary)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/core/overlay", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		UpdateOverlayHandler(nil, cfg).ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := put(`{"ntp": {"enabled": true, "server": "time.apple.com"}}`); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	written, err := os.ReadFile(cfg.SingboxConfigPath)
+	if err != nil || !strings.Contains(string(written), `"time.apple.com"`) {
+		t.Fatalf("config file = %s (%v), want overlay merged", written, err)
+	}
+
+	rec := put(`{"outbounds": [{"type": "socks", "tag": "direct"}]}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `tag \"direct\" conflicts with a managed tag`) {
+		t.Fatalf("conflict status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	if v, _ := db.GetSetting(db.SettingConfigOverlay); !strings.Contains(v, "time.apple.com") {
+		t.Fatalf("overlay after rejected update = %q, want previous overlay", v)
+	}
+
+	if rec := put(`[1]`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("non-object status = %d", rec.Code)
+	}
+	if rec := put(`{}`); rec.Code != http.StatusOK {
+		t.Fatalf("clear status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	if v, _ := db.GetSetting(db.SettingConfigOverlay); v != "" {
+		t.Fatalf("overlay after clear = %q", v)
+	}
+}
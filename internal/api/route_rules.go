@@ -0,0 +1,380 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/s-ui/s-ui/internal/config"
+	"github.com/s-ui/s-ui/internal/core"
+	"github.com/s-ui/s-ui/internal/db"
+	"gorm.io/datatypes"
+)
+
+// routeRuleItem is the API response shape for route rules.
+type routeRuleItem struct {
+	ID        uint           `json:"id"`
+	Priority  int            `json:"priority"`
+	Remark    string         `json:"remark"`
+	Match     datatypes.JSON `json:"match"`
+	Outbound  string         `json:"outbound"`
+	Enabled   bool           `json:"enabled"`
+	CreatedAt string         `json:"created_at"`
+}
+
+func routeRuleFromDB(rule *db.RouteRule) routeRuleItem {
+	return routeRuleItem{
+		ID:        rule.ID,
+		Priority:  rule.Priority,
+		Remark:    rule.Remark,
+		Match:     rule.Match,
+		Outbound:  rule.Outbound,
+		Enabled:   !rule.Disabled,
+		CreatedAt: rule.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// routeRuleRequest is the POST/PUT body for create and update. enabled defaults to true.
+type routeRuleRequest struct {
+	Remark   string         `json:"remark"`
+	Match    datatypes.JSON `json:"match"`
+	Outbound string         `json:"outbound"`
+	Enabled  *bool          `json:"enabled"`
+}
+
+// validateOutboundRef checks that tag names a built-in or db outbound.
+func validateOutboundRef(tag string) error {
+	if core.IsBuiltinOutboundTag(tag) {
+		return nil
+	}
+	exists, err := db.OutboundExistsByTag(tag)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("outbound not found: %s", tag)
+	}
+	return nil
+}
+
+// outboundUsers describes everything that references outbound tag: chained
+// outbounds, outbound groups, rule-set download detours, dns servers and rules, routing profiles,
+// user egress, route rules and the route final setting.
+func outboundUsers(tag string) ([]string, error) {
+	var users []string
+	detours, err := db.OutboundsReferencingDetour(tag)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range detours {
+		users = append(users, "outbound "+t)
+	}
+	groups, err := db.OutboundsReferencingMember(tag)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range groups {
+		users = append(users, "outbound group "+t)
+	}
+	ruleSets, err := db.RuleSetsByDownloadDetour(tag)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range ruleSets {
+		users = append(users, "rule-set "+t)
+	}
+	dnsServers, err := db.DNSServersByDetour(tag)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range dnsServers {
+		users = append(users, "dns server "+t)
+	}
+	dnsRuleIDs, err := db.DNSRulesReferencingMatch("outbound", tag)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range dnsRuleIDs {
+		users = append(users, fmt.Sprintf("dns rule #%d", id))
+	}
+	profiles, err := db.RoutingProfilesReferencingOutbound(tag)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range profiles {
+		users = append(users, "routing profile "+name)
+	}
+	userNames, err := db.UserNamesByOutbound(tag)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range userNames {
+		users = append(users, "user "+name)
+	}
+	ruleIDs, err := db.RouteRulesReferencingOutbound(tag)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ruleIDs {
+		users = append(users, fmt.Sprintf("route rule #%d", id))
+	}
+	final, err := db.GetSetting(db.SettingRouteFinal)
+	if err != nil {
+		return nil, err
+	}
+	if final == tag {
+		users = append(users, "route final")
+	}
+	return users, nil
+}
+
+// validateRouteRule checks match fields, referenced inbound and rule-set tags and the target outbound.
+func validateRouteRule(req *routeRuleRequest) error {
+	if req.Outbound == "" {
+		return errors.New("outbound required")
+	}
+	var match map[string]any
+	if len(req.Match) > 0 {
+		if err := json.Unmarshal(req.Match, &match); err != nil {
+			return errors.New("invalid match")
+		}
+	}
+	if err := core.ValidateRouteMatch(match); err != nil {
+		return err
+	}
+	if tags, ok := match["inbound"].([]any); ok {
+		for _, t := range tags {
+			tag, _ := t.(string)
+			exists, err := db.InboundExistsByTag(tag)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return fmt.Errorf("inbound not found: %s", tag)
+			}
+		}
+	}
+	if tags, ok := match["rule_set"].([]any); ok {
+		for _, t := range tags {
+			tag, _ := t.(string)
+			exists, err := db.RuleSetExistsByTag(tag)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return fmt.Errorf("rule-set not found: %s", tag)
+			}
+		}
+	}
+	return validateOutboundRef(req.Outbound)
+}
+
+// decodeRouteRuleRequest decodes and validates the create/update body, writing 400 on failure.
+func decodeRouteRuleRequest(w http.ResponseWriter, r *http.Request) (*routeRuleRequest, bool) {
+	var req routeRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return nil, false
+	}
+	req.Outbound = strings.TrimSpace(req.Outbound)
+	if err := validateRouteRule(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return &req, true
+}
+
+// ListRouteRulesHandler returns GET /api/route/rules handler.
+func ListRouteRulesHandler(sm *scs.SessionManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rules, err := db.ListRouteRules()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		final, err := db.GetSetting(db.SettingRouteFinal)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		items := make([]routeRuleItem, len(rules))
+		for i := range rules {
+			items[i] = routeRuleFromDB(&rules[i])
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": items, "final": final})
+	}
+}
+
+// CreateRouteRuleHandler handles POST /api/route/rules. New rules are appended last.
+func CreateRouteRuleHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := decodeRouteRuleRequest(w, r)
+		if !ok {
+			return
+		}
+		rule := &db.RouteRule{
+			Remark:   req.Remark,
+			Match:    req.Match,
+			Outbound: req.Outbound,
+			Disabled: req.Enabled != nil && !*req.Enabled,
+		}
+		if err := db.CreateRouteRule(rule); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !applyGeneratedConfig(w, panelCfg, "route rule create", func() { db.DeleteRouteRule(rule.ID) }) {
+			return
+		}
+		writeJSON(w, http.StatusCreated, routeRuleFromDB(rule))
+	}
+}
+
+// UpdateRouteRuleHandler handles PUT /api/route/rules/:id. Priority is kept.
+func UpdateRouteRuleHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := parseIDParam(r)
+		if !ok {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		old, err := db.GetRouteRuleByID(id)
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		req, ok := decodeRouteRuleRequest(w, r)
+		if !ok {
+			return
+		}
+		updated := *old
+		updated.Remark = req.Remark
+		updated.Match = req.Match
+		updated.Outbound = req.Outbound
+		if req.Enabled != nil {
+			updated.Disabled = !*req.Enabled
+		}
+		if err := db.UpdateRouteRule(&updated); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !applyGeneratedConfig(w, panelCfg, "route rule update", func() { db.UpdateRouteRule(old) }) {
+			return
+		}
+		writeJSON(w, http.StatusOK, routeRuleFromDB(&updated))
+	}
+}
+
+// DeleteRouteRuleHandler handles DELETE /api/route/rules/:id.
+func DeleteRouteRuleHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := parseIDParam(r)
+		if !ok {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		rule, err := db.GetRouteRuleByID(id)
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if err := db.DeleteRouteRule(id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !applyGeneratedConfig(w, panelCfg, "route rule delete", func() { db.UpdateRouteRule(rule) }) {
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// routeRuleOrderRequest is the PUT /api/route/rules/order body.
+type routeRuleOrderRequest struct {
+	IDs []uint `json:"ids"`
+}
+
+// ReorderRouteRulesHandler handles PUT /api/route/rules/order.
+// ids must list every rule exactly once, in the desired evaluation order.
+func ReorderRouteRulesHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req routeRuleOrderRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		old, err := db.ListRouteRules()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		known := make(map[uint]bool, len(old))
+		for _, rule := range old {
+			known[rule.ID] = true
+		}
+		seen := make(map[uint]bool, len(req.IDs))
+		for _, id := range req.IDs {
+			if !known[id] || seen[id] {
+				http.Error(w, "ids must list every rule exactly once", http.StatusBadRequest)
+				return
+			}
+			seen[id] = true
+		}
+		if len(seen) != len(known) {
+			http.Error(w, "ids must list every rule exactly once", http.StatusBadRequest)
+			return
+		}
+		if err := db.ReorderRouteRules(req.IDs); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rollback := func() {
+			for i := range old {
+				db.UpdateRouteRule(&old[i])
+			}
+		}
+		if !applyGeneratedConfig(w, panelCfg, "route rule reorder", rollback) {
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"ok": "true"})
+	}
+}
+
+// routeFinalRequest is the PUT /api/route/final body. An empty outbound clears
+// route.final so sing-box falls back to the first outbound (direct).
+type routeFinalRequest struct {
+	Outbound string `json:"outbound"`
+}
+
+// UpdateRouteFinalHandler handles PUT /api/route/final.
+func UpdateRouteFinalHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req routeFinalRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		req.Outbound = strings.TrimSpace(req.Outbound)
+		if req.Outbound != "" {
+			if err := validateOutboundRef(req.Outbound); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		old, err := db.GetSetting(db.SettingRouteFinal)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := db.SetSetting(db.SettingRouteFinal, req.Outbound); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !applyGeneratedConfig(w, panelCfg, "route final update", func() { db.SetSetting(db.SettingRouteFinal, old) }) {
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"final": req.Outbound})
+	}
+}
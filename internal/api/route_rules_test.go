@@ -0,0 +1,78 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/s-ui/s-ui/internal/db"
+	"gorm.io/datatypes"
+)
+
+func TestValidateRouteRule(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	if err := db.CreateInbound(&db.Inbound{Tag: "vless-in", Protocol: "vless", ListenPort: 443}); err != nil {
+		t.Fatalf("CreateInbound: %v", err)
+	}
+	if err := db.CreateOutbound(&db.Outbound{Tag: "warp", Type: "direct"}); err != nil {
+		t.Fatalf("CreateOutbound: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		req     routeRuleRequest
+		wantErr string
+	}{
+		{name: "valid", req: routeRuleRequest{Match: datatypes.JSON(`{"inbound":["vless-in"],"domain":["openai.com"]}`), Outbound: "warp"}},
+		{name: "block", req: routeRuleRequest{Match: datatypes.JSON(`{"protocol":["bittorrent"]}`), Outbound: "block"}},
+		{name: "missing_outbound", req: routeRuleRequest{Match: datatypes.JSON(`{"domain":["a.com"]}`)}, wantErr: "outbound required"},
+		{name: "unknown_outbound", req: routeRuleRequest{Match: datatypes.JSON(`{"domain":["a.com"]}`), Outbound: "nope"}, wantErr: "outbound not found"},
+		{name: "unknown_inbound", req: routeRuleRequest{Match: datatypes.JSON(`{"inbound":["nope"]}`), Outbound: "direct"}, wantErr: "inbound not found"},
+		{name: "no_match", req: routeRuleRequest{Outbound: "direct"}, wantErr: "at least one match field"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateRouteRule(&tc.req)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateRouteRule() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("validateRouteRule() = %v, want error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestOutboundUsers(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	if err := db.CreateOutbound(&db.Outbound{Tag: "warp", Type: "direct"}); err != nil {
+		t.Fatalf("CreateOutbound: %v", err)
+	}
+	if err := db.CreateOutbound(&db.Outbound{Tag: "chained", Type: "socks", ConfigJSON: datatypes.JSON(`{"detour":"warp"}`)}); err != nil {
+		t.Fatalf("CreateOutbound: %v", err)
+	}
+	rule := &db.RouteRule{Match: datatypes.JSON(`{"domain":["a.com"]}`), Outbound: "warp"}
+	if err := db.CreateRouteRule(rule); err != nil {
+		t.Fatalf("CreateRouteRule: %v", err)
+	}
+	if err := db.SetSetting(db.SettingRouteFinal, "warp"); err != nil {
+		t.Fatalf("SetSetting: %v", err)
+	}
+
+	users, err := outboundUsers("warp")
+	if err != nil {
+		t.Fatalf("outboundUsers: %v", err)
+	}
+	got := strings.Join(users, ", ")
+	want := "outbound chained, route rule #1, route final"
+	if got != want {
+		t.Fatalf("outboundUsers = %q, want %q", got, want)
+	}
+}
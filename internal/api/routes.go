@@ -34,8 +34,19 @@ func Routes(staticFS fs.FS, sm *scs.SessionManager, cfg *config.Config) chi.Rout
 			r.Post("/stop", StopHandler(sm, cfg))
 			r.Post("/restart", RestartHandler(sm, cfg))
 			r.Get("/logs", LogsHandler(sm, cfg))
+			r.Get("/logs/entries", LogEntriesHandler(sm, cfg))
+			r.Get("/logs/stream", LogStreamHandler(sm, cfg))
+			r.Get("/log-settings", GetLogSettingsHandler(sm))
+			r.Put("/log-settings", UpdateLogSettingsHandler(sm, cfg))
 			r.Post("/config", ConfigHandler(sm, cfg))
+			r.Post("/config/preview", PreviewConfigHandler(sm, cfg))
 			r.Get("/config-file", ConfigFileHandler(sm, cfg))
+			r.Get("/config/revisions", ListConfigRevisionsHandler(sm))
+			r.Get("/config/revisions/diff", DiffConfigRevisionsHandler(sm))
+			r.Get("/config/revisions/{id}", GetConfigRevisionHandler(sm))
+			r.Post("/config/revisions/{id}/restore", RestoreConfigRevisionHandler(sm, cfg))
+			r.Get("/overlay", GetOverlayHandler(sm))
+			r.Put("/overlay", UpdateOverlayHandler(sm, cfg))
 			r.Post("/update", UpdateHandler(sm, cfg))
 			r.Get("/update/stream", UpdateStreamHandler(sm, cfg))
 			r.Post("/rollback", RollbackHandler(sm, cfg))
@@ -52,6 +63,50 @@ func Routes(staticFS fs.FS, sm *scs.SessionManager, cfg *config.Config) chi.Rout
 			r.Put("/{id}", UpdateInboundHandler(sm, cfg))
 			r.Delete("/{id}", DeleteInboundHandler(sm, cfg))
 		})
+		r.Route("/outbounds", func(r chi.Router) {
+			r.Use(RequireAuth(sm))
+			r.Get("/", ListOutboundsHandler(sm))
+			r.Get("/{id}", GetOutboundHandler(sm))
+			r.Post("/", CreateOutboundHandler(sm, cfg))
+			r.Put("/{id}", UpdateOutboundHandler(sm, cfg))
+			r.Delete("/{id}", DeleteOutboundHandler(sm, cfg))
+			r.Get("/{id}/group", GetOutboundGroupHandler(sm))
+			r.Put("/{id}/selected", SelectOutboundGroupHandler(sm))
+			r.Post("/{id}/delay", TestOutboundGroupHandler(sm))
+		})
+		r.Route("/route", func(r chi.Router) {
+			r.Use(RequireAuth(sm))
+			r.Get("/rules", ListRouteRulesHandler(sm))
+			r.Post("/rules", CreateRouteRuleHandler(sm, cfg))
+			r.Put("/rules/order", ReorderRouteRulesHandler(sm, cfg))
+			r.Put("/rules/{id}", UpdateRouteRuleHandler(sm, cfg))
+			r.Delete("/rules/{id}", DeleteRouteRuleHandler(sm, cfg))
+			r.Put("/final", UpdateRouteFinalHandler(sm, cfg))
+			r.Get("/profiles", ListRoutingProfilesHandler(sm))
+			r.Post("/profiles", CreateRoutingProfileHandler(sm))
+			r.Put("/profiles/{id}", UpdateRoutingProfileHandler(sm, cfg))
+			r.Delete("/profiles/{id}", DeleteRoutingProfileHandler(sm))
+			r.Get("/rule-sets", ListRuleSetsHandler(sm))
+			r.Post("/rule-sets", CreateRemoteRuleSetHandler(sm, cfg))
+			r.Post("/rule-sets/upload", UploadRuleSetHandler(sm, cfg))
+			r.Put("/rule-sets/{id}", UpdateRemoteRuleSetHandler(sm, cfg))
+			r.Delete("/rule-sets/{id}", DeleteRuleSetHandler(sm, cfg))
+			r.Post("/rule-sets/{id}/refresh", RefreshRuleSetHandler(sm, cfg))
+		})
+		r.Route("/dns", func(r chi.Router) {
+			r.Use(RequireAuth(sm))
+			r.Get("/servers", ListDNSServersHandler(sm))
+			r.Post("/servers", CreateDNSServerHandler(sm, cfg))
+			r.Put("/servers/{id}", UpdateDNSServerHandler(sm, cfg))
+			r.Delete("/servers/{id}", DeleteDNSServerHandler(sm, cfg))
+			r.Get("/rules", ListDNSRulesHandler(sm))
+			r.Post("/rules", CreateDNSRuleHandler(sm, cfg))
+			r.Put("/rules/order", ReorderDNSRulesHandler(sm, cfg))
+			r.Put("/rules/{id}", UpdateDNSRuleHandler(sm, cfg))
+			r.Delete("/rules/{id}", DeleteDNSRuleHandler(sm, cfg))
+			r.Get("/settings", GetDNSSettingsHandler(sm))
+			r.Put("/settings", UpdateDNSSettingsHandler(sm, cfg))
+		})
 		r.Route("/certs", func(r chi.Router) {
 			r.Use(RequireAuth(sm))
 			r.Get("/", ListCertificatesHandler(sm))
@@ -70,6 +125,11 @@ func Routes(staticFS fs.FS, sm *scs.SessionManager, cfg *config.Config) chi.Rout
 			r.Put("/{id}", UpdateUserHandler(sm, cfg))
 			r.Delete("/{id}", DeleteUserHandler(sm, cfg))
 		})
+		r.Route("/import", func(r chi.Router) {
+			r.Use(RequireAuth(sm))
+			r.Post("/singbox", ImportSingBoxHandler(sm, cfg))
+			r.Post("/xui", ImportXUIHandler(sm, cfg))
+		})
 	})
 
 	r.NotFound(spaHandler(staticFS))
@@ -0,0 +1,238 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/s-ui/s-ui/internal/config"
+	"github.com/s-ui/s-ui/internal/db"
+	"gorm.io/datatypes"
+)
+
+// routingProfileItem is the API response shape for routing profiles.
+type routingProfileItem struct {
+	ID        uint                    `json:"id"`
+	Name      string                  `json:"name"`
+	Remark    string                  `json:"remark"`
+	Rules     []db.RoutingProfileRule `json:"rules"`
+	Outbound  string                  `json:"outbound"`
+	Users     []string                `json:"users"`
+	CreatedAt string                  `json:"created_at"`
+}
+
+func routingProfileFromDB(p *db.RoutingProfile) routingProfileItem {
+	item := routingProfileItem{
+		ID:        p.ID,
+		Name:      p.Name,
+		Remark:    p.Remark,
+		Rules:     p.ParseRules(),
+		Outbound:  p.Outbound,
+		Users:     []string{},
+		CreatedAt: p.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if item.Rules == nil {
+		item.Rules = []db.RoutingProfileRule{}
+	}
+	if names, err := db.UserNamesByRoutingProfile(p.ID); err == nil && names != nil {
+		item.Users = names
+	}
+	return item
+}
+
+// routingProfileRequest is the POST/PUT body for create and update.
+type routingProfileRequest struct {
+	Name     string                  `json:"name"`
+	Remark   string                  `json:"remark"`
+	Rules    []db.RoutingProfileRule `json:"rules"`
+	Outbound string                  `json:"outbound"`
+}
+
+// validateRoutingProfile checks the name, each rule's match and outbound, and the
+// default outbound. auth_user is not allowed in rules since it is filled from members.
+func validateRoutingProfile(req *routingProfileRequest) error {
+	if req.Name == "" {
+		return errors.New("name required")
+	}
+	if len(req.Rules) == 0 && req.Outbound == "" {
+		return errors.New("routing profile requires rules or an outbound")
+	}
+	for i, rule := range req.Rules {
+		if _, ok := rule.Match["auth_user"]; ok {
+			return fmt.Errorf("rule %d: auth_user is set from profile members", i+1)
+		}
+		if rule.Outbound == "" {
+			return fmt.Errorf("rule %d: outbound required", i+1)
+		}
+		if err := validateRouteRule(&routeRuleRequest{Match: mustJSON(rule.Match), Outbound: rule.Outbound}); err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+	if req.Outbound != "" {
+		return validateOutboundRef(req.Outbound)
+	}
+	return nil
+}
+
+// mustJSON re-encodes a decoded JSON value.
+func mustJSON(v any) datatypes.JSON {
+	b, _ := json.Marshal(v)
+	return datatypes.JSON(b)
+}
+
+// decodeRoutingProfileRequest decodes and validates the create/update body, writing 400 on failure.
+func decodeRoutingProfileRequest(w http.ResponseWriter, r *http.Request) (*routingProfileRequest, bool) {
+	var req routingProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return nil, false
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	req.Outbound = strings.TrimSpace(req.Outbound)
+	for i := range req.Rules {
+		req.Rules[i].Outbound = strings.TrimSpace(req.Rules[i].Outbound)
+	}
+	if err := validateRoutingProfile(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return &req, true
+}
+
+// validateUserPolicy checks a user's egress outbound and routing profile references.
+func validateUserPolicy(outbound string, profileID *uint) error {
+	if outbound != "" {
+		if err := validateOutboundRef(outbound); err != nil {
+			return err
+		}
+	}
+	if profileID != nil {
+		if _, err := db.GetRoutingProfileByID(*profileID); err != nil {
+			return fmt.Errorf("routing profile not found: %d", *profileID)
+		}
+	}
+	return nil
+}
+
+// ListRoutingProfilesHandler returns GET /api/route/profiles handler.
+func ListRoutingProfilesHandler(sm *scs.SessionManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		profiles, err := db.ListRoutingProfiles()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		items := make([]routingProfileItem, len(profiles))
+		for i := range profiles {
+			items[i] = routingProfileFromDB(&profiles[i])
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": items})
+	}
+}
+
+// CreateRoutingProfileHandler handles POST /api/route/profiles.
+func CreateRoutingProfileHandler(sm *scs.SessionManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := decodeRoutingProfileRequest(w, r)
+		if !ok {
+			return
+		}
+		exists, err := db.RoutingProfileExistsByName(req.Name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if exists {
+			http.Error(w, "name already exists", http.StatusBadRequest)
+			return
+		}
+		p := &db.RoutingProfile{Name: req.Name, Remark: req.Remark, Rules: mustJSON(req.Rules), Outbound: req.Outbound}
+		if err := db.CreateRoutingProfile(p); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// A new profile has no members, so the generated config is unchanged.
+		writeJSON(w, http.StatusCreated, routingProfileFromDB(p))
+	}
+}
+
+// UpdateRoutingProfileHandler handles PUT /api/route/profiles/:id.
+func UpdateRoutingProfileHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := parseIDParam(r)
+		if !ok {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		old, err := db.GetRoutingProfileByID(id)
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		req, ok := decodeRoutingProfileRequest(w, r)
+		if !ok {
+			return
+		}
+		if req.Name != old.Name {
+			exists, err := db.RoutingProfileExistsByName(req.Name)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if exists {
+				http.Error(w, "name already exists", http.StatusBadRequest)
+				return
+			}
+		}
+		updated := *old
+		updated.Name = req.Name
+		updated.Remark = req.Remark
+		updated.Rules = mustJSON(req.Rules)
+		updated.Outbound = req.Outbound
+		if err := db.UpdateRoutingProfile(&updated); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !applyGeneratedConfig(w, panelCfg, "routing profile update", func() { db.UpdateRoutingProfile(old) }) {
+			return
+		}
+		writeJSON(w, http.StatusOK, routingProfileFromDB(&updated))
+	}
+}
+
+// DeleteRoutingProfileHandler handles DELETE /api/route/profiles/:id. Only profiles
+// without members can be deleted, so the generated config is unchanged.
+func DeleteRoutingProfileHandler(sm *scs.SessionManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := parseIDParam(r)
+		if !ok {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		if _, err := db.GetRoutingProfileByID(id); err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		names, err := db.UserNamesByRoutingProfile(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(names) > 0 {
+			refs := make([]string, len(names))
+			for i, n := range names {
+				refs[i] = "user " + n
+			}
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "routing profile is in use by: " + strings.Join(refs, ", ")})
+			return
+		}
+		if err := db.DeleteRoutingProfile(id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
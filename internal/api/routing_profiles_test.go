@@ -0,0 +1,88 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/s-ui/s-ui/internal/db"
+)
+
+func TestValidateRoutingProfile(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	if err := db.CreateOutbound(&db.Outbound{Tag: "residential", Type: "direct"}); err != nil {
+		t.Fatalf("CreateOutbound: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		req     routingProfileRequest
+		wantErr string
+	}{
+		{name: "outbound_only", req: routingProfileRequest{Name: "premium", Outbound: "residential"}},
+		{name: "rules", req: routingProfileRequest{Name: "p", Rules: []db.RoutingProfileRule{
+			{Match: map[string]any{"domain_suffix": []any{"netflix.com"}}, Outbound: "residential"},
+			{Match: map[string]any{"protocol": []any{"bittorrent"}}, Outbound: "block"},
+		}}},
+		{name: "missing_name", req: routingProfileRequest{Outbound: "residential"}, wantErr: "name required"},
+		{name: "empty", req: routingProfileRequest{Name: "p"}, wantErr: "requires rules or an outbound"},
+		{name: "auth_user", req: routingProfileRequest{Name: "p", Rules: []db.RoutingProfileRule{
+			{Match: map[string]any{"auth_user": []any{"alice"}}, Outbound: "residential"},
+		}}, wantErr: "rule 1: auth_user is set from profile members"},
+		{name: "rule_outbound", req: routingProfileRequest{Name: "p", Rules: []db.RoutingProfileRule{
+			{Match: map[string]any{"domain": []any{"a.com"}}, Outbound: "nope"},
+		}}, wantErr: "rule 1: outbound not found"},
+		{name: "default_outbound", req: routingProfileRequest{Name: "p", Outbound: "nope"}, wantErr: "outbound not found"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateRoutingProfile(&tc.req)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateRoutingProfile() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("validateRoutingProfile() = %v, want error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestUserPolicyReferences(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	if err := db.CreateOutbound(&db.Outbound{Tag: "residential", Type: "direct"}); err != nil {
+		t.Fatalf("CreateOutbound: %v", err)
+	}
+	profile := &db.RoutingProfile{Name: "premium", Outbound: "residential"}
+	if err := db.CreateRoutingProfile(profile); err != nil {
+		t.Fatalf("CreateRoutingProfile: %v", err)
+	}
+	if err := db.CreateUser(&db.User{Name: "alice", Outbound: "residential"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	if err := validateUserPolicy("nope", nil); err == nil || !strings.Contains(err.Error(), "outbound not found") {
+		t.Fatalf("validateUserPolicy(unknown outbound) = %v", err)
+	}
+	missing := uint(99)
+	if err := validateUserPolicy("", &missing); err == nil || !strings.Contains(err.Error(), "routing profile not found") {
+		t.Fatalf("validateUserPolicy(unknown profile) = %v", err)
+	}
+	if err := validateUserPolicy("residential", &profile.ID); err != nil {
+		t.Fatalf("validateUserPolicy() = %v", err)
+	}
+
+	users, err := outboundUsers("residential")
+	if err != nil {
+		t.Fatalf("outboundUsers: %v", err)
+	}
+	if got := strings.Join(users, ", "); got != "routing profile premium, user alice" {
+		t.Fatalf("outboundUsers = %q", got)
+	}
+}
@@ -0,0 +1,478 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/s-ui/s-ui/internal/config"
+	"github.com/s-ui/s-ui/internal/core"
+	"github.com/s-ui/s-ui/internal/db"
+)
+
+// ruleSetTagPattern restricts tags since local rule-sets are stored as <tag>.<ext> under DataDir.
+var ruleSetTagPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ruleSetItem is the API response shape for rule-sets.
+type ruleSetItem struct {
+	ID             uint    `json:"id"`
+	Tag            string  `json:"tag"`
+	Type           string  `json:"type"`
+	Format         string  `json:"format"`
+	Path           string  `json:"path,omitempty"`
+	URL            string  `json:"url,omitempty"`
+	UpdateInterval string  `json:"update_interval,omitempty"`
+	DownloadDetour string  `json:"download_detour,omitempty"`
+	SHA256         string  `json:"sha256"`
+	LastUpdatedAt  *string `json:"last_updated_at"`
+	LastError      string  `json:"last_error"`
+	CreatedAt      string  `json:"created_at"`
+}
+
+func ruleSetFromDB(rs *db.RuleSet) ruleSetItem {
+	item := ruleSetItem{
+		ID:             rs.ID,
+		Tag:            rs.Tag,
+		Type:           rs.Type,
+		Format:         rs.Format,
+		Path:           rs.Path,
+		URL:            rs.URL,
+		UpdateInterval: rs.UpdateInterval,
+		DownloadDetour: rs.DownloadDetour,
+		SHA256:         rs.SHA256,
+		LastError:      rs.LastError,
+		CreatedAt:      rs.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if rs.LastUpdatedAt != nil {
+		s := rs.LastUpdatedAt.Format("2006-01-02T15:04:05Z07:00")
+		item.LastUpdatedAt = &s
+	}
+	return item
+}
+
+// remoteRuleSetRequest is the POST/PUT body for remote rule-sets.
+// format is inferred from the URL extension when empty.
+type remoteRuleSetRequest struct {
+	Tag            string `json:"tag"`
+	Format         string `json:"format"`
+	URL            string `json:"url"`
+	UpdateInterval string `json:"update_interval"`
+	DownloadDetour string `json:"download_detour"`
+}
+
+// validateRuleSetTag checks tag format.
+func validateRuleSetTag(tag string) error {
+	if tag == "" {
+		return errors.New("tag required")
+	}
+	if !ruleSetTagPattern.MatchString(tag) {
+		return errors.New("tag may only contain letters, digits, '.', '_' and '-'")
+	}
+	return nil
+}
+
+// validateRemoteRuleSet normalizes and checks a remote rule-set request.
+func validateRemoteRuleSet(req *remoteRuleSetRequest) error {
+	if err := validateRuleSetTag(req.Tag); err != nil {
+		return err
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an http(s) URL")
+	}
+	if req.Format == "" {
+		req.Format = core.RuleSetFormatFromName(u.Path)
+	}
+	if req.Format != core.RuleSetFormatBinary && req.Format != core.RuleSetFormatSource {
+		return errors.New("format must be binary or source")
+	}
+	if req.UpdateInterval != "" {
+		if err := core.ValidateUpdateInterval(req.UpdateInterval); err != nil {
+			return err
+		}
+	}
+	if req.DownloadDetour != "" {
+		if err := validateOutboundRef(req.DownloadDetour); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ruleSetUsers returns descriptions of route rules, dns rules and routing profiles referencing rule-set tag.
+func ruleSetUsers(tag string) ([]string, error) {
+	ids, err := db.RouteRulesReferencingRuleSet(tag)
+	if err != nil {
+		return nil, err
+	}
+	dnsIDs, err := db.DNSRulesReferencingMatch("rule_set", tag)
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := db.RoutingProfilesReferencingRuleSet(tag)
+	if err != nil {
+		return nil, err
+	}
+	users := make([]string, 0, len(ids)+len(dnsIDs)+len(profiles))
+	for _, id := range ids {
+		users = append(users, fmt.Sprintf("route rule #%d", id))
+	}
+	for _, id := range dnsIDs {
+		users = append(users, fmt.Sprintf("dns rule #%d", id))
+	}
+	for _, name := range profiles {
+		users = append(users, "routing profile "+name)
+	}
+	return users, nil
+}
+
+// ruleSetRefreshes tracks background rule-set refreshes so tests can wait for them.
+var ruleSetRefreshes sync.WaitGroup
+
+// refreshRuleSet refreshes rs and re-applies the config when its content changed.
+func refreshRuleSet(panelCfg *config.Config, rs *db.RuleSet) error {
+	changed, err := core.RefreshRuleSet(rs, panelCfg.DataDir)
+	if err != nil || !changed {
+		return err
+	}
+	_, err = regenerateConfig(panelCfg, "rule-set refresh")
+	return err
+}
+
+// refreshRuleSetInBackground downloads remote rule-set rs once its create or update
+// has been applied; failures are kept in last_error.
+func refreshRuleSetInBackground(panelCfg *config.Config, rs db.RuleSet) {
+	ruleSetRefreshes.Add(1)
+	go func() {
+		defer ruleSetRefreshes.Done()
+		if err := refreshRuleSet(panelCfg, &rs); err != nil {
+			log.Printf("[rule-set] refresh %s: %v", rs.Tag, err)
+		}
+	}()
+}
+
+// RunRuleSetRefresher refreshes remote rule-sets whose update_interval has passed,
+// checking every interval until ctx is done.
+func RunRuleSetRefresher(ctx context.Context, panelCfg *config.Config, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		sets, err := db.ListRuleSets()
+		if err != nil {
+			log.Printf("[rule-set] list: %v", err)
+		}
+		for i := range sets {
+			if !core.RuleSetRefreshDue(&sets[i], time.Now()) {
+				continue
+			}
+			if err := refreshRuleSet(panelCfg, &sets[i]); err != nil {
+				log.Printf("[rule-set] refresh %s: %v", sets[i].Tag, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ListRuleSetsHandler returns GET /api/route/rule-sets handler.
+func ListRuleSetsHandler(sm *scs.SessionManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sets, err := db.ListRuleSets()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		items := make([]ruleSetItem, len(sets))
+		for i := range sets {
+			items[i] = ruleSetFromDB(&sets[i])
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": items})
+	}
+}
+
+// CreateRemoteRuleSetHandler handles POST /api/route/rule-sets.
+// The panel downloads the URL in the background once the config is applied; until
+// that succeeds sing-box downloads it itself, through download_detour when set.
+func CreateRemoteRuleSetHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req remoteRuleSetRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		req.Tag = strings.TrimSpace(req.Tag)
+		if err := validateRemoteRuleSet(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		exists, err := db.RuleSetExistsByTag(req.Tag)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if exists {
+			http.Error(w, "tag already exists", http.StatusBadRequest)
+			return
+		}
+		rs := &db.RuleSet{
+			Tag:            req.Tag,
+			Type:           "remote",
+			Format:         req.Format,
+			URL:            req.URL,
+			UpdateInterval: req.UpdateInterval,
+			DownloadDetour: req.DownloadDetour,
+		}
+		if err := db.CreateRuleSet(rs); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !applyGeneratedConfig(w, panelCfg, "rule-set create", func() { db.DeleteRuleSet(rs.ID) }) {
+			return
+		}
+		refreshRuleSetInBackground(panelCfg, *rs)
+		writeJSON(w, http.StatusCreated, ruleSetFromDB(rs))
+	}
+}
+
+// UpdateRemoteRuleSetHandler handles PUT /api/route/rule-sets/:id for remote rule-sets.
+// The tag cannot change since route rules reference it. A new URL or format drops the
+// panel's download and fetches it again in the background.
+func UpdateRemoteRuleSetHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := parseIDParam(r)
+		if !ok {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		old, err := db.GetRuleSetByID(id)
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if old.Type != "remote" {
+			http.Error(w, "local rule-sets are replaced via upload", http.StatusBadRequest)
+			return
+		}
+		var req remoteRuleSetRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		req.Tag = old.Tag
+		if err := validateRemoteRuleSet(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		updated := *old
+		updated.Format = req.Format
+		updated.URL = req.URL
+		updated.UpdateInterval = req.UpdateInterval
+		updated.DownloadDetour = req.DownloadDetour
+		sourceChanged := updated.URL != old.URL || updated.Format != old.Format
+		if sourceChanged {
+			updated.Path = ""
+		}
+		if err := db.UpdateRuleSet(&updated); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !applyGeneratedConfig(w, panelCfg, "rule-set update", func() { db.UpdateRuleSet(old) }) {
+			return
+		}
+		if sourceChanged {
+			if old.Path != "" {
+				os.Remove(old.Path)
+			}
+			refreshRuleSetInBackground(panelCfg, updated)
+		}
+		writeJSON(w, http.StatusOK, ruleSetFromDB(&updated))
+	}
+}
+
+// UploadRuleSetHandler handles POST /api/route/rule-sets/upload (multipart: tag, format, file).
+// The file is stored as DataDir/rule-sets/<tag>.srs|.json; uploading an existing local tag replaces it.
+func UploadRuleSetHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, core.RuleSetMaxSize+1<<20)
+		if err := r.ParseMultipartForm(8 << 20); err != nil {
+			http.Error(w, "invalid multipart form", http.StatusBadRequest)
+			return
+		}
+		tag := strings.TrimSpace(r.FormValue("tag"))
+		if err := validateRuleSetTag(tag); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "file required", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		data, err := io.ReadAll(io.LimitReader(file, core.RuleSetMaxSize+1))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(data) > core.RuleSetMaxSize {
+			http.Error(w, "rule-set exceeds 64 MiB", http.StatusBadRequest)
+			return
+		}
+		format := r.FormValue("format")
+		if format == "" {
+			format = core.RuleSetFormatFromName(header.Filename)
+		}
+		if err := core.ValidateRuleSetContent(format, data); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		old, err := db.GetRuleSetByTag(tag)
+		if err == nil && old.Type != "local" {
+			http.Error(w, "tag already exists", http.StatusBadRequest)
+			return
+		}
+		dir := core.RuleSetDir(panelCfg.DataDir)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			http.Error(w, "failed to create rule-set dir", http.StatusInternalServerError)
+			return
+		}
+		path := filepath.Join(dir, tag+core.RuleSetFileExt(format))
+		var previous []byte
+		if old != nil {
+			previous, _ = os.ReadFile(old.Path)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rs := &db.RuleSet{Tag: tag, Type: "local"}
+		if old != nil {
+			copied := *old
+			rs = &copied
+		}
+		rs.Format = format
+		rs.Path = path
+		if old == nil {
+			err = db.CreateRuleSet(rs)
+		} else {
+			err = db.UpdateRuleSet(rs)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rollback := func() {
+			if old == nil {
+				db.DeleteRuleSet(rs.ID)
+				os.Remove(path)
+				return
+			}
+			db.UpdateRuleSet(old)
+			if path != old.Path {
+				os.Remove(path)
+			}
+			if previous != nil {
+				os.WriteFile(old.Path, previous, 0644)
+			}
+		}
+		if _, err := core.RefreshRuleSet(rs, panelCfg.DataDir); err != nil {
+			rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !applyGeneratedConfig(w, panelCfg, "rule-set upload", rollback) {
+			return
+		}
+		if old != nil && old.Path != path {
+			os.Remove(old.Path)
+		}
+		status := http.StatusCreated
+		if old != nil {
+			status = http.StatusOK
+		}
+		writeJSON(w, status, ruleSetFromDB(rs))
+	}
+}
+
+// DeleteRuleSetHandler handles DELETE /api/route/rule-sets/:id.
+func DeleteRuleSetHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := parseIDParam(r)
+		if !ok {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		rs, err := db.GetRuleSetByID(id)
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		refs, err := ruleSetUsers(rs.Tag)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(refs) > 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "rule-set is in use by: " + strings.Join(refs, ", ")})
+			return
+		}
+		if err := db.DeleteRuleSet(id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !applyGeneratedConfig(w, panelCfg, "rule-set delete", func() { db.CreateRuleSet(rs) }) {
+			return
+		}
+		if rs.Path != "" {
+			os.Remove(rs.Path)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// RefreshRuleSetHandler handles POST /api/route/rule-sets/:id/refresh.
+// Remote rule-sets are re-downloaded into DataDir and local ones re-hashed; when the
+// content changes the config is re-applied so sing-box loads it.
+func RefreshRuleSetHandler(sm *scs.SessionManager, panelCfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := parseIDParam(r)
+		if !ok {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		rs, err := db.GetRuleSetByID(id)
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		changed, err := core.RefreshRuleSet(rs, panelCfg.DataDir)
+		if err != nil {
+			writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+			return
+		}
+		if changed && !applyGeneratedConfig(w, panelCfg, "rule-set refresh", nil) {
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"changed":  changed,
+			"rule_set": ruleSetFromDB(rs),
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/s-ui/s-ui/internal/core"
+	"github.com/s-ui/s-ui/internal/db"
+)
+
+func TestValidateRemoteRuleSet(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	req := remoteRuleSetRequest{Tag: "geoip-cn", URL: "https://example.com/rule-set/geoip-cn.srs", UpdateInterval: "1d"}
+	if err := validateRemoteRuleSet(&req); err != nil {
+		t.Fatalf("validateRemoteRuleSet: %v", err)
+	}
+	if req.Format != core.RuleSetFormatBinary {
+		t.Fatalf("format = %q, want inferred binary", req.Format)
+	}
+
+	bad := []remoteRuleSetRequest{
+		{Tag: "../x", URL: "https://example.com/a.srs"},
+		{Tag: "a", URL: "ftp://example.com/a.srs"},
+		{Tag: "a", URL: "https://example.com/a"},
+		{Tag: "a", URL: "https://example.com/a.srs", UpdateInterval: "soon"},
+		{Tag: "a", URL: "https://example.com/a.srs", DownloadDetour: "missing"},
+	}
+	for _, b := range bad {
+		b := b
+		if err := validateRemoteRuleSet(&b); err == nil {
+			t.Errorf("validateRemoteRuleSet(%+v) = nil, want error", b)
+		}
+	}
+}
+
+func TestUploadRuleSetHandler(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	binary := filepath.Join(t.TempDir(), "sing-box")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\n[ \"$1\" = check ] && exit 0\nexit 1\n"), 0755); err != nil {
+		t.Fatalf("write fake binary: %v", err)
+	}
+	cfg := testCoreConfig(t, binary)
+
+	content := []byte(`{"version":2,"rules":[{"domain_suffix":["ads.example"]}]}`)
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("tag", "block-ads")
+	fw, _ := mw.CreateFormFile("file", "ads.json")
+	fw.Write(content)
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/route/rule-sets/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	UploadRuleSetHandler(nil, cfg).ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+
+	var item ruleSetItem
+	decodeJSON(t, rec, &item)
+	wantPath := filepath.Join(core.RuleSetDir(cfg.DataDir), "block-ads.json")
+	if item.Type != "local" || item.Format != core.RuleSetFormatSource || item.Path != wantPath {
+		t.Fatalf("item = %+v, want local source rule-set at %s", item, wantPath)
+	}
+	if item.SHA256 != core.HashRuleSet(content) || item.LastUpdatedAt == nil {
+		t.Fatalf("item = %+v, want hash and last update", item)
+	}
+	if got, err := os.ReadFile(wantPath); err != nil || !bytes.Equal(got, content) {
+		t.Fatalf("stored file = %q, %v", got, err)
+	}
+}
+
+func TestCreateRemoteRuleSetDownloadsInBackground(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	binary := filepath.Join(t.TempDir(), "sing-box")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\n[ \"$1\" = check ] && exit 0\nexit 1\n"), 0755); err != nil {
+		t.Fatalf("write fake binary: %v", err)
+	}
+	cfg := testCoreConfig(t, binary)
+	content := `{"version":2,"rules":[{"domain_suffix":["ads.example"]}]}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(content))
+	}))
+	defer srv.Close()
+
+	body := `{"tag":"ads","url":"` + srv.URL + `/ads.json","download_detour":"direct"}`
+	rec := httptest.NewRecorder()
+	CreateRemoteRuleSetHandler(nil, cfg).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/route/rule-sets", strings.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	ruleSetRefreshes.Wait()
+
+	rs, err := db.GetRuleSetByTag("ads")
+	if err != nil {
+		t.Fatalf("GetRuleSetByTag: %v", err)
+	}
+	wantPath := filepath.Join(core.RuleSetDir(cfg.DataDir), "ads.json")
+	if rs.Path != wantPath || rs.SHA256 != core.HashRuleSet([]byte(content)) || rs.LastError != "" {
+		t.Fatalf("rule-set = %+v, want download stored at %s", rs, wantPath)
+	}
+	written, _ := os.ReadFile(cfg.SingboxConfigPath)
+	if !strings.Contains(string(written), `"path": "`+wantPath+`"`) || strings.Contains(string(written), srv.URL) {
+		t.Fatalf("config does not load the downloaded rule-set: %s", written)
+	}
+}
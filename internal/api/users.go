@@ -38,6 +38,8 @@ type userItem struct {
 	CreatedAt          string                 `json:"created_at"`
 	InboundIDs         []uint                 `json:"inbound_ids"`
 	InboundTags        []string               `json:"inbound_tags"`
+	Outbound           string                 `json:"outbound"`           // egress outbound tag; empty = none
+	RoutingProfileID   *uint                  `json:"routing_profile_id"` // null = none
 	SubscriptionURL    string                 `json:"subscription_url"`
 	SubscriptionNodes  []subscriptionNodeItem  `json:"subscription_nodes,omitempty"` // only in GetUser detail
 }
@@ -67,6 +69,8 @@ func userFromDB(u *db.User, includeNodes bool, fallbackHost string) userItem {
 		InboundIDs:    make([]uint, 0, len(u.Inbounds)),
 		InboundTags:   make([]string, 0, len(u.Inbounds)),
 		SubscriptionURL: buildSubscriptionURL(u.SubscriptionToken),
+		Outbound:        u.Outbound,
+		RoutingProfileID: u.RoutingProfileID,
 	}
 	if u.ExpireAt != nil {
 		s := u.ExpireAt.Format(time.RFC3339)
@@ -131,6 +135,8 @@ type userCreateRequest struct {
 	InboundIDs   []uint   `json:"inbound_ids"`
 	TrafficLimit *int64   `json:"traffic_limit"`
 	ExpireAt     *string  `json:"expire_at"` // ISO date string
+	Outbound         string `json:"outbound"`
+	RoutingProfileID *uint  `json:"routing_profile_id"`
 }
 
 // userUpdateRequest is the PUT body for update.
@@ -140,6 +146,8 @@ type userUpdateRequest struct {
 	InboundIDs   []uint   `json:"inbound_ids"`
 	TrafficLimit *int64   `json:"traffic_limit"`
 	ExpireAt     *string  `json:"expire_at"`
+	Outbound         *string `json:"outbound"`           // nil = keep, "" = clear
+	RoutingProfileID *uint   `json:"routing_profile_id"` // nil = keep, 0 = clear
 }
 
 func parseExpireAt(s *string) (*time.Time, error) {
@@ -174,11 +182,21 @@ func CreateUserHandler(sm *scs.SessionManager, panelCfg *config.Config) http.Han
 		if req.TrafficLimit != nil {
 			trafficLimit = *req.TrafficLimit
 		}
+		if req.RoutingProfileID != nil && *req.RoutingProfileID == 0 {
+			req.RoutingProfileID = nil
+		}
+		req.Outbound = strings.TrimSpace(req.Outbound)
+		if err := validateUserPolicy(req.Outbound, req.RoutingProfileID); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		u := &db.User{
 			Name:         req.Name,
 			Remark:       req.Remark,
 			TrafficLimit: trafficLimit,
 			ExpireAt:     expireAt,
+			Outbound:         req.Outbound,
+			RoutingProfileID: req.RoutingProfileID,
 		}
 		if len(req.InboundIDs) > 0 {
 			inbounds, err := db.GetInboundsByIDs(req.InboundIDs)
@@ -207,16 +225,14 @@ func CreateUserHandler(sm *scs.SessionManager, panelCfg *config.Config) http.Han
 			return
 		}
 		pm := core.NewProcessManagerFromConfig(panelCfg)
-		if err := core.ApplyConfig(path, cfg, pm); err != nil {
+		if err := core.ApplyConfig(path, cfg, pm, "user create"); err != nil {
 			db.DeleteUser(u.ID)
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 			return
 		}
-		if err := pm.Restart(path); err != nil {
-			// Config applied; restart failure is best-effort
-		}
+		w.Header().Set(coreApplyHeader, reloadCore(pm, path))
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(userFromDB(u, false, ""))
@@ -258,15 +274,31 @@ func UpdateUserHandler(sm *scs.SessionManager, panelCfg *config.Config) http.Han
 			Remark:            req.Remark,
 			UUID:              old.UUID,
 			Password:          old.Password,
+			ShadowsocksKey:    old.ShadowsocksKey,
 			SubscriptionToken: old.SubscriptionToken,
 			TrafficLimit:      old.TrafficLimit,
 			TrafficUsed:       old.TrafficUsed,
 			ExpireAt:          expireAt,
 			Enabled:           old.Enabled,
+			Outbound:          old.Outbound,
+			RoutingProfileID:  old.RoutingProfileID,
 		}
 		if req.TrafficLimit != nil {
 			u.TrafficLimit = *req.TrafficLimit
 		}
+		if req.Outbound != nil {
+			u.Outbound = strings.TrimSpace(*req.Outbound)
+		}
+		if req.RoutingProfileID != nil {
+			u.RoutingProfileID = req.RoutingProfileID
+			if *req.RoutingProfileID == 0 {
+				u.RoutingProfileID = nil
+			}
+		}
+		if err := validateUserPolicy(u.Outbound, u.RoutingProfileID); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		if err := db.ReplaceUserInbounds(id, req.InboundIDs); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -285,7 +317,7 @@ func UpdateUserHandler(sm *scs.SessionManager, panelCfg *config.Config) http.Han
 			return
 		}
 		pm := core.NewProcessManagerFromConfig(panelCfg)
-		if err := core.ApplyConfig(path, cfg, pm); err != nil {
+		if err := core.ApplyConfig(path, cfg, pm, "user update"); err != nil {
 			db.UpdateUser(old)
 			db.ReplaceUserInbounds(id, inboundIDsFromUsers(old.Inbounds))
 			w.Header().Set("Content-Type", "application/json")
@@ -293,9 +325,7 @@ func UpdateUserHandler(sm *scs.SessionManager, panelCfg *config.Config) http.Han
 			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 			return
 		}
-		if err := pm.Restart(path); err != nil {
-			// Config applied; restart failure is best-effort
-		}
+		w.Header().Set(coreApplyHeader, reloadCore(pm, path))
 		u, _ = db.GetUserByID(id)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(userFromDB(u, false, ""))
@@ -342,7 +372,7 @@ func DeleteUserHandler(sm *scs.SessionManager, panelCfg *config.Config) http.Han
 			return
 		}
 		pm := core.NewProcessManagerFromConfig(panelCfg)
-		if err := core.ApplyConfig(path, cfg, pm); err != nil {
+		if err := core.ApplyConfig(path, cfg, pm, "user delete"); err != nil {
 			u.ID = 0
 			db.CreateUser(u)
 			w.Header().Set("Content-Type", "application/json")
@@ -350,9 +380,7 @@ func DeleteUserHandler(sm *scs.SessionManager, panelCfg *config.Config) http.Han
 			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 			return
 		}
-		if err := pm.Restart(path); err != nil {
-			// Config applied; restart failure is best-effort
-		}
+		w.Header().Set(coreApplyHeader, reloadCore(pm, path))
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
@@ -504,7 +532,7 @@ func BatchUsersHandler(sm *scs.SessionManager, panelCfg *config.Config) http.Han
 			return
 		}
 		pm := core.NewProcessManagerFromConfig(panelCfg)
-		if err := core.ApplyConfig(path, cfg, pm); err != nil {
+		if err := core.ApplyConfig(path, cfg, pm, "user batch"); err != nil {
 			for _, rb := range rollbacks {
 				rb()
 			}
@@ -513,9 +541,7 @@ func BatchUsersHandler(sm *scs.SessionManager, panelCfg *config.Config) http.Han
 			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 			return
 		}
-		if err := pm.Restart(path); err != nil {
-			// Config applied; restart failure is best-effort
-		}
+		w.Header().Set(coreApplyHeader, reloadCore(pm, path))
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(map[string]string{"ok": "true"})
 	}
@@ -114,6 +114,11 @@ func EnsureDir(path string) error {
 	return os.MkdirAll(path, 0755)
 }
 
+// LogPath returns the captured sing-box log path for the given data dir.
+func LogPath(dataDir string) string {
+	return filepath.Join(dataDir, "sing-box.log")
+}
+
 // DBPath returns the s-ui database path for the given data dir.
 func DBPath(dataDir string) string {
 	return filepath.Join(dataDir, "s-ui.db")
@@ -0,0 +1,147 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// DefaultURLTestURL is the probe URL used when a group sets none (sing-box default).
+const DefaultURLTestURL = "https://www.gstatic.com/generate_204"
+
+// clashAPIListen returns the Clash API controller address from CLASH_API_LISTEN.
+func clashAPIListen() string {
+	if s := os.Getenv("CLASH_API_LISTEN"); s != "" {
+		return s
+	}
+	return "127.0.0.1:9090"
+}
+
+// clashAPIBlock returns experimental.clash_api; the secret comes from CLASH_API_SECRET.
+func clashAPIBlock() map[string]any {
+	block := map[string]any{"external_controller": clashAPIListen()}
+	if secret := os.Getenv("CLASH_API_SECRET"); secret != "" {
+		block["secret"] = secret
+	}
+	return block
+}
+
+// ClashProxy is a proxy or group as reported by the Clash API.
+type ClashProxy struct {
+	Name    string       `json:"name"`
+	Type    string       `json:"type"`
+	Now     string       `json:"now,omitempty"` // selected member of a group
+	All     []string     `json:"all,omitempty"` // group members
+	History []ClashDelay `json:"history"`
+}
+
+// ClashDelay is one URL test result; Delay 0 means the test failed.
+type ClashDelay struct {
+	Time  string `json:"time"`
+	Delay int    `json:"delay"`
+}
+
+// LastDelay returns the most recent delay in ms, or 0 when untested or failed.
+func (p *ClashProxy) LastDelay() int {
+	if len(p.History) == 0 {
+		return 0
+	}
+	return p.History[len(p.History)-1].Delay
+}
+
+// ClashClient talks to the sing-box Clash API to inspect and switch outbound groups at runtime.
+type ClashClient struct {
+	baseURL string
+	secret  string
+	http    *http.Client
+}
+
+// NewClashClient creates a client for the controller at addr (host:port or base URL).
+func NewClashClient(addr, secret string) *ClashClient {
+	base := addr
+	if u, err := url.Parse(addr); err != nil || u.Scheme == "" || u.Host == "" {
+		base = "http://" + addr
+	}
+	return &ClashClient{baseURL: base, secret: secret, http: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// NewClashClientFromEnv creates a client for the controller the generator configures.
+func NewClashClientFromEnv() *ClashClient {
+	return NewClashClient(clashAPIListen(), os.Getenv("CLASH_API_SECRET"))
+}
+
+func (c *ClashClient) do(ctx context.Context, method, path string, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.secret != "" {
+		req.Header.Set("Authorization", "Bearer "+c.secret)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("clash api: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var msg struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&msg)
+		if msg.Message != "" {
+			return fmt.Errorf("clash api: HTTP %d: %s", resp.StatusCode, msg.Message)
+		}
+		return fmt.Errorf("clash api: HTTP %d", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Proxies returns all proxies and groups keyed by tag.
+func (c *ClashClient) Proxies(ctx context.Context) (map[string]ClashProxy, error) {
+	var resp struct {
+		Proxies map[string]ClashProxy `json:"proxies"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/proxies", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Proxies, nil
+}
+
+// SelectProxy switches selector group to member.
+func (c *ClashClient) SelectProxy(ctx context.Context, group, member string) error {
+	return c.do(ctx, http.MethodPut, "/proxies/"+url.PathEscape(group), map[string]string{"name": member}, nil)
+}
+
+// GroupDelay tests every member of group against testURL and returns delays in ms
+// keyed by member tag. Members that fail are omitted by sing-box.
+func (c *ClashClient) GroupDelay(ctx context.Context, group, testURL string, timeout time.Duration) (map[string]int, error) {
+	q := url.Values{}
+	q.Set("url", testURL)
+	q.Set("timeout", strconv.FormatInt(timeout.Milliseconds(), 10))
+	delays := map[string]int{}
+	if err := c.do(ctx, http.MethodGet, "/group/"+url.PathEscape(group)+"/delay?"+q.Encode(), nil, &delays); err != nil {
+		return nil, err
+	}
+	return delays, nil
+}
@@ -2,14 +2,21 @@ package core
 
 import (
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+
+	"github.com/s-ui/s-ui/internal/db"
 )
 
+// ConfigRevisionLimit is how many applied configs are kept in config_revisions.
+const ConfigRevisionLimit = 100
+
 // ApplyConfig writes configJSON to a temp file, runs sing-box check, and atomically
 // renames to configPath on success. On check failure, original config is preserved
-// and error includes check output for frontend Modal display.
-func ApplyConfig(configPath string, configJSON []byte, pm *ProcessManager) error {
+// and error includes check output for frontend Modal display. A successful apply is
+// recorded as a config revision with the replaced file and source (what triggered it).
+func ApplyConfig(configPath string, configJSON []byte, pm *ProcessManager, source string) error {
 	dir := filepath.Dir(configPath)
 	tmpPath := filepath.Join(dir, filepath.Base(configPath)+".tmp")
 
@@ -23,8 +30,22 @@ func ApplyConfig(configPath string, configJSON []byte, pm *ProcessManager) error
 		return err
 	}
 
+	previous, _ := os.ReadFile(configPath)
 	if err := os.Rename(tmpPath, configPath); err != nil {
 		return fmt.Errorf("atomic rename: %w", err)
 	}
+	recordConfigRevision(source, previous, configJSON)
 	return nil
 }
+
+// recordConfigRevision stores an applied config; failures are logged since the
+// config itself is already in place.
+func recordConfigRevision(source string, previous, config []byte) {
+	if db.DB == nil {
+		return
+	}
+	rev := &db.ConfigRevision{Source: source, PreviousConfig: string(previous), Config: string(config)}
+	if err := db.CreateConfigRevision(rev, ConfigRevisionLimit); err != nil {
+		log.Printf("[config] record revision: %v", err)
+	}
+}
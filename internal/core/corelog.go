@@ -0,0 +1,193 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/s-ui/s-ui/internal/db"
+)
+
+// logLevels maps sing-box log.level values to their severity.
+var logLevels = map[string]int{
+	"trace": 0, "debug": 1, "info": 2, "warn": 3, "error": 4, "fatal": 5, "panic": 6,
+}
+
+// ValidateLogLevel checks a log.level value; "" keeps the panel default (info).
+func ValidateLogLevel(level string) error {
+	if _, ok := logLevels[level]; level != "" && !ok {
+		return fmt.Errorf("unsupported log level: %s", level)
+	}
+	return nil
+}
+
+// logToSingBox builds the log block from settings. output is never set: sing-box logs
+// to stderr, which the panel captures into the rotated log file.
+func logToSingBox(conn db.Conn) (map[string]any, error) {
+	level, err := conn.GetSetting(db.SettingLogLevel)
+	if err != nil {
+		return nil, err
+	}
+	if level == "" {
+		level = "info"
+	}
+	timestamp, err := conn.GetSetting(db.SettingLogTimestamp)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"level": level, "timestamp": timestamp != "false"}, nil
+}
+
+// LogRotation is the rotation and retention policy of the captured sing-box log.
+type LogRotation struct {
+	MaxSize    int64         // rotate once the file reaches this many bytes
+	MaxBackups int           // rotated files kept; older ones are deleted
+	MaxAge     time.Duration // rotated files older than this are deleted
+}
+
+// logRotateEvery rotates a non-empty log at least this often regardless of size.
+const logRotateEvery = 24 * time.Hour
+
+// LogRotationFromEnv reads SINGBOX_LOG_MAX_SIZE_MB (default 10), SINGBOX_LOG_MAX_BACKUPS
+// (default 5) and SINGBOX_LOG_MAX_AGE_DAYS (default 7).
+func LogRotationFromEnv() LogRotation {
+	return LogRotation{
+		MaxSize:    int64(envPositiveInt("SINGBOX_LOG_MAX_SIZE_MB", 10)) << 20,
+		MaxBackups: envPositiveInt("SINGBOX_LOG_MAX_BACKUPS", 5),
+		MaxAge:     time.Duration(envPositiveInt("SINGBOX_LOG_MAX_AGE_DAYS", 7)) * 24 * time.Hour,
+	}
+}
+
+func envPositiveInt(key string, def int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil && n > 0 {
+		return n
+	}
+	return def
+}
+
+// openCoreLog opens the sing-box log for appending. sing-box gets the descriptor
+// itself rather than a pipe, so it keeps logging (and does not die of SIGPIPE)
+// when the panel restarts.
+func openCoreLog(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+}
+
+// coreLogBackupLayout is the UTC timestamp suffix of rotated logs. It sorts
+// chronologically; a -<n> counter is appended should two rotations share it.
+const coreLogBackupLayout = "20060102-150405.000000000"
+
+// coreLogBackupSuffix matches rotated log suffixes, including the second-resolution
+// ones written by earlier versions, so pruning leaves other files alone.
+var coreLogBackupSuffix = regexp.MustCompile(`^\d{8}-\d{6}(\.\d{9})?(-\d+)?$`)
+
+// RotateCoreLog copies the log to path.<timestamp> and truncates it in place, since
+// sing-box holds the file open; its O_APPEND writes continue at the new end. Lines
+// written between the copy and the truncate are lost. Backups are then pruned.
+func RotateCoreLog(path string, policy LogRotation) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	backup, dst, err := createCoreLogBackup(path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(backup)
+		return err
+	}
+	if err := os.Truncate(path, 0); err != nil {
+		return err
+	}
+	return pruneCoreLogs(path, policy)
+}
+
+// createCoreLogBackup creates a new, uniquely named backup file for the log at path.
+func createCoreLogBackup(path string) (string, *os.File, error) {
+	base := path + "." + time.Now().UTC().Format(coreLogBackupLayout)
+	for i := 0; ; i++ {
+		name := base
+		if i > 0 {
+			name += "-" + strconv.Itoa(i)
+		}
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			return name, f, nil
+		}
+		if !os.IsExist(err) || i >= 100 {
+			return "", nil, err
+		}
+	}
+}
+
+// pruneCoreLogs deletes rotated logs beyond MaxBackups or older than MaxAge.
+func pruneCoreLogs(path string, policy LogRotation) error {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return err
+	}
+	backups := matches[:0]
+	for _, name := range matches {
+		if coreLogBackupSuffix.MatchString(strings.TrimPrefix(name, path+".")) {
+			backups = append(backups, name)
+		}
+	}
+	// Timestamp suffixes sort chronologically; newest first.
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	for i, name := range backups {
+		info, err := os.Stat(name)
+		if err != nil {
+			continue
+		}
+		if i >= policy.MaxBackups || time.Since(info.ModTime()) > policy.MaxAge {
+			if err := os.Remove(name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RunLogRotator checks the log at path every interval until ctx is done, rotating it
+// once it reaches MaxSize or has been growing for logRotateEvery.
+func RunLogRotator(ctx context.Context, path string, policy LogRotation, interval time.Duration) {
+	since := time.Now()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.Size() == 0 {
+			since = time.Now()
+			continue
+		}
+		if info.Size() < policy.MaxSize && time.Since(since) < logRotateEvery {
+			continue
+		}
+		if err := RotateCoreLog(path, policy); err != nil {
+			log.Printf("[log] rotate %s: %v", path, err)
+			continue
+		}
+		since = time.Now()
+	}
+}
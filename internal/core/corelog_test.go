@@ -0,0 +1,115 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/s-ui/s-ui/internal/config"
+	"github.com/s-ui/s-ui/internal/db"
+)
+
+func TestRotateCoreLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sing-box.log")
+	// child stands in for sing-box's inherited descriptor.
+	child, err := openCoreLog(path)
+	if err != nil {
+		t.Fatalf("openCoreLog: %v", err)
+	}
+	defer child.Close()
+	old := path + ".20000101-000000"
+	if err := os.WriteFile(old, []byte("old\n"), 0644); err != nil {
+		t.Fatalf("write backup: %v", err)
+	}
+	if err := os.Chtimes(old, time.Now().Add(-48*time.Hour), time.Now().Add(-48*time.Hour)); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	// Not a backup, so pruning must leave it alone however old it is.
+	unrelated := path + ".bak"
+	if err := os.WriteFile(unrelated, []byte("keep\n"), 0644); err != nil {
+		t.Fatalf("write unrelated file: %v", err)
+	}
+	if err := os.Chtimes(unrelated, time.Now().Add(-48*time.Hour), time.Now().Add(-48*time.Hour)); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	child.WriteString("before rotate\n")
+
+	policy := LogRotation{MaxSize: 1, MaxBackups: 5, MaxAge: 24 * time.Hour}
+	if err := RotateCoreLog(path, policy); err != nil {
+		t.Fatalf("RotateCoreLog: %v", err)
+	}
+	child.WriteString("after rotate\n")
+
+	if data, _ := os.ReadFile(path); string(data) != "after rotate\n" {
+		t.Fatalf("log after rotate = %q", data)
+	}
+	// A second rotation within the same second gets its own backup.
+	if err := RotateCoreLog(path, policy); err != nil {
+		t.Fatalf("second RotateCoreLog: %v", err)
+	}
+	backups, _ := filepath.Glob(path + ".2*")
+	if len(backups) != 2 || backups[0] == old || backups[1] == old {
+		t.Fatalf("backups = %v, want the two new ones", backups)
+	}
+	if data, _ := os.ReadFile(backups[0]); string(data) != "before rotate\n" {
+		t.Fatalf("first backup = %q", data)
+	}
+	if data, _ := os.ReadFile(backups[1]); string(data) != "after rotate\n" {
+		t.Fatalf("second backup = %q", data)
+	}
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Fatalf("unrelated file pruned: %v", err)
+	}
+}
+
+func TestProcessManagerCapturesOutput(t *testing.T) {
+	dataDir := t.TempDir()
+	binary := filepath.Join(dataDir, "sing-box")
+	script := "#!/bin/sh\n[ \"$1\" = run ] || exit 0\necho \"out $4\"\necho err >&2\nwhile :; do sleep 0.05; done\n"
+	if err := os.WriteFile(binary, []byte(script), 0755); err != nil {
+		t.Fatalf("write fake binary: %v", err)
+	}
+	cfg := &config.Config{DataDir: dataDir, SingboxConfigPath: filepath.Join(dataDir, "sing-box.json"), SingboxBinaryPath: binary}
+	pm := NewProcessManagerFromConfig(cfg)
+	if err := pm.Start(cfg.SingboxConfigPath); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { pm.Stop() })
+	data, _ := os.ReadFile(config.LogPath(dataDir))
+	if string(data) != "out --disable-color\nerr\n" {
+		t.Fatalf("captured log = %q", data)
+	}
+}
+
+func TestGenerateLogBlock(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	gen := &ConfigGenerator{}
+	for _, tc := range []struct {
+		level, timestamp string
+		wantLevel        string
+		wantTimestamp    bool
+	}{
+		{wantLevel: "info", wantTimestamp: true},
+		{level: "debug", timestamp: "false", wantLevel: "debug", wantTimestamp: false},
+	} {
+		db.SetSetting(db.SettingLogLevel, tc.level)
+		db.SetSetting(db.SettingLogTimestamp, tc.timestamp)
+		out, err := gen.Generate()
+		if err != nil {
+			t.Fatalf("Generate: %v", err)
+		}
+		var cfg struct {
+			Log map[string]any `json:"log"`
+		}
+		if err := json.Unmarshal(out, &cfg); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if cfg.Log["level"] != tc.wantLevel || cfg.Log["timestamp"] != tc.wantTimestamp || cfg.Log["output"] != nil {
+			t.Fatalf("log block = %v", cfg.Log)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{name: "equal", a: "a\nb\n", b: "a\nb\n", want: ""},
+		{name: "change", a: "1\n2\n3\n4\n5\n6\n7\n8\n", b: "1\n2\n3\n4\nfive\n6\n7\n8\n",
+			want: "--- a\n+++ b\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n"},
+		{name: "from_empty", a: "", b: "x\ny\n", want: "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+x\n+y\n"},
+		{name: "two_hunks", a: "a\n1\n2\n3\n4\n5\n6\n7\n8\nb\n", b: "A\n1\n2\n3\n4\n5\n6\n7\n8\nB\n",
+			want: "--- a\n+++ b\n@@ -1,4 +1,4 @@\n-a\n+A\n 1\n 2\n 3\n@@ -7,4 +7,4 @@\n 6\n 7\n 8\n-b\n+B\n"},
+		{name: "insert_delete", a: "a\nb\nc\n", b: "a\nc\nd\n",
+			want: "--- a\n+++ b\n@@ -1,3 +1,3 @@\n a\n-b\n c\n+d\n"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got := UnifiedDiff("a", "b", []byte(tc.a), []byte(tc.b))
+			if got != tc.want {
+				t.Fatalf("UnifiedDiff() =\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiffAppliesBack(t *testing.T) {
+	a := strings.Repeat("keep\nold\n", 50)
+	b := strings.Repeat("keep\nnew\nextra\n", 40)
+	ops := diffLines(splitLines(a), splitLines(b))
+	var from, to []string
+	for _, op := range ops {
+		if op.kind != '+' {
+			from = append(from, op.line)
+		}
+		if op.kind != '-' {
+			to = append(to, op.line)
+		}
+	}
+	if strings.Join(from, "\n")+"\n" != a || strings.Join(to, "\n")+"\n" != b {
+		t.Fatal("edit script does not reproduce both inputs")
+	}
+}
@@ -0,0 +1,236 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/s-ui/s-ui/internal/db"
+)
+
+// DNS server types ConfigGenerator can emit.
+var dnsServerTypes = map[string]bool{
+	"udp":    true,
+	"tls":    true,
+	"https":  true,
+	"quic":   true,
+	"fakeip": true,
+}
+
+// DefaultFakeIPInet4Range is used when a fakeip server sets neither range.
+const DefaultFakeIPInet4Range = "198.18.0.0/15"
+
+// dnsStrategies lists values accepted by dns.strategy.
+var dnsStrategies = map[string]bool{
+	"prefer_ipv4": true,
+	"prefer_ipv6": true,
+	"ipv4_only":   true,
+	"ipv6_only":   true,
+}
+
+// dnsMatchFields are the dns rule fields the panel manages. outbound matches the
+// outbound a connection was routed to, so per-egress lookups (e.g. WARP) can use
+// a server that detours through that egress.
+var dnsMatchFields = map[string]bool{
+	"domain":         true,
+	"domain_suffix":  true,
+	"domain_keyword": true,
+	"domain_regex":   true,
+	"rule_set":       true,
+	"outbound":       true,
+	"query_type":     true,
+}
+
+// ValidateDNSServer checks type-specific fields of a dns server. References to
+// outbounds and other dns servers are checked by the caller.
+func ValidateDNSServer(s *db.DNSServer) error {
+	if !dnsServerTypes[s.Type] {
+		return fmt.Errorf("unsupported dns server type: %s", s.Type)
+	}
+	if s.Type == "fakeip" {
+		if s.Inet4Range != "" {
+			if p, err := netip.ParsePrefix(s.Inet4Range); err != nil || !p.Addr().Is4() {
+				return fmt.Errorf("invalid inet4_range: %s", s.Inet4Range)
+			}
+		}
+		if s.Inet6Range != "" {
+			if p, err := netip.ParsePrefix(s.Inet6Range); err != nil || !p.Addr().Is6() {
+				return fmt.Errorf("invalid inet6_range: %s", s.Inet6Range)
+			}
+		}
+		return nil
+	}
+	if s.Server == "" {
+		return fmt.Errorf("%s dns server requires server", s.Type)
+	}
+	if _, err := netip.ParseAddr(s.Server); err != nil {
+		if strings.ContainsAny(s.Server, "/: ") {
+			return fmt.Errorf("invalid server: %s", s.Server)
+		}
+		if s.DomainResolver == "" {
+			return errors.New("domain_resolver required when server is a domain name")
+		}
+	}
+	if s.ServerPort > 65535 {
+		return fmt.Errorf("invalid server_port: %d", s.ServerPort)
+	}
+	if s.Path != "" {
+		if s.Type != "https" {
+			return errors.New("path is only supported by https dns servers")
+		}
+		if !strings.HasPrefix(s.Path, "/") {
+			return errors.New("path must start with /")
+		}
+	}
+	return nil
+}
+
+// ValidateDNSMatch checks the match fields of a dns rule. Every field must be a
+// non-empty list; query_type takes record type names or numbers.
+func ValidateDNSMatch(match map[string]any) error {
+	if len(match) == 0 {
+		return errors.New("dns rule requires at least one match field")
+	}
+	for key, raw := range match {
+		if !dnsMatchFields[key] {
+			return fmt.Errorf("unsupported match field: %s", key)
+		}
+		list, ok := raw.([]any)
+		if !ok || len(list) == 0 {
+			return fmt.Errorf("match %s must be a non-empty list", key)
+		}
+		for _, v := range list {
+			if key == "query_type" {
+				if n, ok := toUint(v); ok && n > 0 && n <= 65535 {
+					continue
+				}
+			}
+			s, ok := v.(string)
+			if !ok || s == "" {
+				return fmt.Errorf("match %s must contain non-empty strings", key)
+			}
+			if err := validateRouteMatchValue(key, s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateDNSStrategy checks a dns.strategy value; "" keeps the sing-box default.
+func ValidateDNSStrategy(s string) error {
+	if s != "" && !dnsStrategies[s] {
+		return fmt.Errorf("unsupported dns strategy: %s", s)
+	}
+	return nil
+}
+
+// ValidateClientSubnet checks an EDNS client subnet given as prefix or address.
+func ValidateClientSubnet(s string) error {
+	if s == "" {
+		return nil
+	}
+	if _, err := netip.ParsePrefix(s); err != nil {
+		if _, err := netip.ParseAddr(s); err != nil {
+			return fmt.Errorf("invalid client_subnet: %s", s)
+		}
+	}
+	return nil
+}
+
+// dnsServerToSingBox converts db.DNSServer to a sing-box dns server. Zero port
+// and empty path fall back to the protocol defaults.
+func dnsServerToSingBox(s *db.DNSServer) map[string]any {
+	out := map[string]any{
+		"type": s.Type,
+		"tag":  s.Tag,
+	}
+	if s.Type == "fakeip" {
+		if s.Inet4Range == "" && s.Inet6Range == "" {
+			out["inet4_range"] = DefaultFakeIPInet4Range
+		}
+		if s.Inet4Range != "" {
+			out["inet4_range"] = s.Inet4Range
+		}
+		if s.Inet6Range != "" {
+			out["inet6_range"] = s.Inet6Range
+		}
+		return out
+	}
+	out["server"] = s.Server
+	if s.ServerPort != 0 {
+		out["server_port"] = s.ServerPort
+	}
+	if s.Path != "" {
+		out["path"] = s.Path
+	}
+	if s.Detour != "" {
+		out["detour"] = s.Detour
+	}
+	if s.DomainResolver != "" {
+		out["domain_resolver"] = s.DomainResolver
+	}
+	return out
+}
+
+// dnsRuleToSingBox converts db.DNSRule to a sing-box dns rule with the route action.
+func dnsRuleToSingBox(rule *db.DNSRule) map[string]any {
+	out := map[string]any{}
+	if len(rule.Match) > 0 {
+		_ = json.Unmarshal(rule.Match, &out)
+		if out == nil {
+			out = map[string]any{}
+		}
+	}
+	out["action"] = "route"
+	out["server"] = rule.Server
+	return out
+}
+
+// dnsToSingBox builds the dns block from db.DNSServer and enabled db.DNSRule rows
+// plus the dns settings. It returns nil when no server is configured so sing-box
+// keeps its default resolver.
+func dnsToSingBox(conn db.Conn) (map[string]any, error) {
+	servers, err := conn.ListDNSServers()
+	if err != nil {
+		return nil, err
+	}
+	if len(servers) == 0 {
+		return nil, nil
+	}
+	rawServers := make([]any, 0, len(servers))
+	for i := range servers {
+		rawServers = append(rawServers, dnsServerToSingBox(&servers[i]))
+	}
+	rows, err := conn.ListDNSRules()
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]any, 0, len(rows))
+	for i := range rows {
+		if rows[i].Disabled {
+			continue
+		}
+		rules = append(rules, dnsRuleToSingBox(&rows[i]))
+	}
+	dns := map[string]any{"servers": rawServers}
+	if len(rules) > 0 {
+		dns["rules"] = rules
+	}
+	for key, setting := range map[string]string{
+		"final":         db.SettingDNSFinal,
+		"strategy":      db.SettingDNSStrategy,
+		"client_subnet": db.SettingDNSClientSubnet,
+	} {
+		v, err := conn.GetSetting(setting)
+		if err != nil {
+			return nil, err
+		}
+		if v != "" {
+			dns[key] = v
+		}
+	}
+	return dns, nil
+}
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/s-ui/s-ui/internal/db"
+	"gorm.io/datatypes"
+)
+
+func TestValidateDNSServer(t *testing.T) {
+	cases := []struct {
+		name    string
+		server  db.DNSServer
+		wantErr bool
+	}{
+		{name: "udp", server: db.DNSServer{Type: "udp", Server: "1.1.1.1"}, wantErr: false},
+		{name: "https_path", server: db.DNSServer{Type: "https", Server: "1.1.1.1", Path: "/dns-query"}, wantErr: false},
+		{name: "unsupported_type", server: db.DNSServer{Type: "dhcp", Server: "1.1.1.1"}, wantErr: true},
+		{name: "missing_server", server: db.DNSServer{Type: "tls"}, wantErr: true},
+		{name: "domain_without_resolver", server: db.DNSServer{Type: "tls", Server: "dns.google"}, wantErr: true},
+		{name: "domain_with_resolver", server: db.DNSServer{Type: "quic", Server: "dns.adguard.com", DomainResolver: "local"}, wantErr: false},
+		{name: "bad_server", server: db.DNSServer{Type: "udp", Server: "tls://1.1.1.1"}, wantErr: true},
+		{name: "path_on_udp", server: db.DNSServer{Type: "udp", Server: "1.1.1.1", Path: "/dns-query"}, wantErr: true},
+		{name: "fakeip", server: db.DNSServer{Type: "fakeip", Inet4Range: "198.18.0.0/15", Inet6Range: "fc00::/18"}, wantErr: false},
+		{name: "fakeip_swapped_range", server: db.DNSServer{Type: "fakeip", Inet4Range: "fc00::/18"}, wantErr: true},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateDNSServer(&tc.server)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ValidateDNSServer() err = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDNSMatch(t *testing.T) {
+	cases := []struct {
+		name    string
+		match   string
+		wantErr bool
+	}{
+		{name: "empty", match: `{}`, wantErr: true},
+		{name: "outbound", match: `{"outbound":["warp"]}`, wantErr: false},
+		{name: "query_type", match: `{"query_type":["A","AAAA",65]}`, wantErr: false},
+		{name: "bad_query_type", match: `{"query_type":[0]}`, wantErr: true},
+		{name: "route_only_field", match: `{"port":[53]}`, wantErr: true},
+		{name: "bad_regex", match: `{"domain_regex":["("]}`, wantErr: true},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var match map[string]any
+			if err := json.Unmarshal([]byte(tc.match), &match); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			err := ValidateDNSMatch(match)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ValidateDNSMatch() err = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerateDNS(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	gen := &ConfigGenerator{}
+	out, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	var empty map[string]any
+	if err := json.Unmarshal(out, &empty); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if _, ok := empty["dns"]; ok {
+		t.Fatalf("dns emitted without servers: %v", empty["dns"])
+	}
+
+	if err := db.CreateOutbound(&db.Outbound{Tag: "warp", Type: "direct"}); err != nil {
+		t.Fatalf("CreateOutbound: %v", err)
+	}
+	for _, s := range []*db.DNSServer{
+		{Tag: "cf", Type: "https", Server: "1.1.1.1"},
+		{Tag: "warp-dns", Type: "tls", Server: "1.1.1.1", ServerPort: 853, Detour: "warp"},
+		{Tag: "fake", Type: "fakeip"},
+	} {
+		if err := db.CreateDNSServer(s); err != nil {
+			t.Fatalf("CreateDNSServer: %v", err)
+		}
+	}
+	for _, rule := range []*db.DNSRule{
+		{Match: datatypes.JSON(`{"outbound":["warp"]}`), Server: "warp-dns"},
+		{Match: datatypes.JSON(`{"query_type":["A"]}`), Server: "fake", Disabled: true},
+	} {
+		if err := db.CreateDNSRule(rule); err != nil {
+			t.Fatalf("CreateDNSRule: %v", err)
+		}
+	}
+	for key, value := range map[string]string{
+		db.SettingDNSFinal:        "cf",
+		db.SettingDNSStrategy:     "prefer_ipv4",
+		db.SettingDNSClientSubnet: "203.0.113.0/24",
+	} {
+		if err := db.SetSetting(key, value); err != nil {
+			t.Fatalf("SetSetting: %v", err)
+		}
+	}
+
+	out, err = gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	var cfg struct {
+		DNS struct {
+			Servers      []map[string]any `json:"servers"`
+			Rules        []map[string]any `json:"rules"`
+			Final        string           `json:"final"`
+			Strategy     string           `json:"strategy"`
+			ClientSubnet string           `json:"client_subnet"`
+		} `json:"dns"`
+	}
+	if err := json.Unmarshal(out, &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(cfg.DNS.Servers) != 3 {
+		t.Fatalf("servers = %v, want 3", cfg.DNS.Servers)
+	}
+	if cfg.DNS.Servers[1]["detour"] != "warp" || cfg.DNS.Servers[1]["server_port"] != float64(853) {
+		t.Fatalf("warp-dns = %v", cfg.DNS.Servers[1])
+	}
+	if cfg.DNS.Servers[2]["inet4_range"] != DefaultFakeIPInet4Range {
+		t.Fatalf("fakeip = %v, want default inet4_range", cfg.DNS.Servers[2])
+	}
+	if len(cfg.DNS.Rules) != 1 {
+		t.Fatalf("rules = %v, want disabled rule skipped", cfg.DNS.Rules)
+	}
+	if cfg.DNS.Rules[0]["action"] != "route" || cfg.DNS.Rules[0]["server"] != "warp-dns" {
+		t.Fatalf("rule = %v", cfg.DNS.Rules[0])
+	}
+	if cfg.DNS.Final != "cf" || cfg.DNS.Strategy != "prefer_ipv4" || cfg.DNS.ClientSubnet != "203.0.113.0/24" {
+		t.Fatalf("dns settings = %+v", cfg.DNS)
+	}
+}
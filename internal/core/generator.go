@@ -7,33 +7,71 @@ import (
 	"github.com/s-ui/s-ui/internal/db"
 )
 
-// ConfigGenerator produces full sing-box JSON config from DB inbounds.
-type ConfigGenerator struct{}
+// ConfigGenerator produces full sing-box JSON config from DB inbounds, outbounds, route rules and dns settings.
+type ConfigGenerator struct {
+	// DB is the handle rows are read from; the zero value reads db.DB.
+	DB db.Conn
+}
 
-// Generate reads all inbounds from DB and builds full sing-box config JSON.
+// Generate reads all inbounds, outbounds, route rules and dns settings from DB and builds full sing-box config JSON.
 func (g *ConfigGenerator) Generate() ([]byte, error) {
-	inbounds, err := db.ListInbounds("")
+	inbounds, err := g.DB.ListInbounds("")
 	if err != nil {
 		return nil, err
 	}
 
 	raw := make([]map[string]any, 0, len(inbounds))
 	for i := range inbounds {
-		raw = append(raw, g.inboundToSingBox(&inbounds[i]))
+		raw = append(raw, g.inboundsToSingBox(&inbounds[i])...)
+	}
+
+	outbounds, endpoints, groups, err := outboundsToSingBox(g.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	route, err := routeToSingBox(g.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	dns, err := dnsToSingBox(g.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	logBlock, err := logToSingBox(g.DB)
+	if err != nil {
+		return nil, err
 	}
 
 	cfg := map[string]any{
-		"log": map[string]any{"level": "info"},
+		"log": logBlock,
 		"inbounds": raw,
-		"outbounds": []map[string]any{
-			{"type": "direct", "tag": "direct"},
-			{"type": "block", "tag": "block"},
-		},
-		"route": map[string]any{"rules": []any{}},
+		"outbounds": outbounds,
+		"route": route,
+	}
+	if len(endpoints) > 0 {
+		cfg["endpoints"] = endpoints
+	}
+	if dns != nil {
+		cfg["dns"] = dns
 	}
 
+	experimental := map[string]any{}
 	if g.v2rayAPIEnabled() {
-		cfg["experimental"] = g.v2rayAPIBlock(inbounds)
+		experimental["v2ray_api"] = g.v2rayAPIBlock(inbounds, append(outbounds, endpoints...))
+	}
+	if groups || os.Getenv("CLASH_API_ENABLED") == "true" {
+		experimental["clash_api"] = clashAPIBlock()
+	}
+	if len(experimental) > 0 {
+		cfg["experimental"] = experimental
+	}
+
+	cfg, err = applyOverlay(g.DB, cfg)
+	if err != nil {
+		return nil, err
 	}
 
 	return json.MarshalIndent(cfg, "", "  ")
@@ -50,12 +88,20 @@ func (g *ConfigGenerator) v2rayAPIListen() string {
 	return "127.0.0.1:8080"
 }
 
-func (g *ConfigGenerator) v2rayAPIBlock(inbounds []db.Inbound) map[string]any {
+// v2rayAPIBlock enables stats for every inbound, every user of one and every emitted
+// outbound and endpoint.
+func (g *ConfigGenerator) v2rayAPIBlock(inbounds []db.Inbound, outbounds []map[string]any) map[string]any {
+	outboundTags := make([]string, 0, len(outbounds))
+	for _, ob := range outbounds {
+		if tag, _ := ob["tag"].(string); tag != "" {
+			outboundTags = append(outboundTags, tag)
+		}
+	}
 	tags := make([]string, 0, len(inbounds))
 	userSet := make(map[string]struct{})
 	for _, ib := range inbounds {
 		tags = append(tags, ib.Tag)
-		users, _ := db.GetUsersForInbound(ib.ID)
+		users, _ := g.DB.GetUsersForInbound(ib.ID)
 		for _, u := range users {
 			userSet[u.Name] = struct{}{}
 		}
@@ -65,39 +111,79 @@ func (g *ConfigGenerator) v2rayAPIBlock(inbounds []db.Inbound) map[string]any {
 		users = append(users, name)
 	}
 	return map[string]any{
-		"v2ray_api": map[string]any{
-			"listen": g.v2rayAPIListen(),
-			"stats": map[string]any{
-				"enabled":   true,
-				"inbounds":  tags,
-				"users":     users,
-				"outbounds": []string{"direct"},
-			},
+		"listen": g.v2rayAPIListen(),
+		"stats": map[string]any{
+			"enabled":   true,
+			"inbounds":  tags,
+			"users":     users,
+			"outbounds": outboundTags,
 		},
 	}
 }
 
+// inboundsToSingBox converts db.Inbound to one or more sing-box inbounds.
+// Wrapper protocols such as shadowtls also emit the internal inbound they detour to.
+func (g *ConfigGenerator) inboundsToSingBox(ib *db.Inbound) []map[string]any {
+	if ib.Protocol == "shadowtls" {
+		return g.shadowTLSToSingBox(ib)
+	}
+	return []map[string]any{g.inboundToSingBox(ib)}
+}
+
 // inboundToSingBox converts db.Inbound to sing-box inbound JSON object.
 func (g *ConfigGenerator) inboundToSingBox(ib *db.Inbound) map[string]any {
 	switch ib.Protocol {
 	case "hysteria2":
 		return g.hysteria2ToSingBox(ib)
+	case "trojan":
+		return g.trojanToSingBox(ib)
+	case "shadowsocks":
+		return g.shadowsocksToSingBox(ib)
+	case "vmess":
+		return g.vmessToSingBox(ib)
+	case "tuic":
+		return g.tuicToSingBox(ib)
+	case "anytls":
+		return g.anyTLSToSingBox(ib)
+	case "naive":
+		return g.naiveToSingBox(ib)
 	default:
 		return g.vlessToSingBox(ib)
 	}
 }
 
+// FlowVision is the only VLESS flow sing-box supports.
+const FlowVision = "xtls-rprx-vision"
+
+// vlessFlow returns the VLESS flow for an inbound from config_json.flow.
+// When the key is absent, inbounds with TLS over plain TCP keep the legacy
+// xtls-rprx-vision default; an explicit "" disables flow.
+func vlessFlow(ib *db.Inbound) string {
+	cfg := inboundConfigMap(ib)
+	if flow, ok := cfg["flow"].(string); ok {
+		return flow
+	}
+	if isTLSEnabled(ib) && inboundTransport(ib).Type == "tcp" {
+		return FlowVision
+	}
+	return ""
+}
+
 // vlessToSingBox produces VLESS inbound map for sing-box.
 // Users are derived from User+UserInbound (valid only); config_json users ignored.
 func (g *ConfigGenerator) vlessToSingBox(ib *db.Inbound) map[string]any {
-	users, _ := db.GetUsersForInbound(ib.ID)
+	flow := vlessFlow(ib)
+	users, _ := g.DB.GetUsersForInbound(ib.ID)
 	userArr := make([]any, 0, len(users))
 	for _, u := range users {
-		userArr = append(userArr, map[string]any{
+		user := map[string]any{
 			"name": u.Name,
 			"uuid": u.UUID,
-			"flow": "xtls-rprx-vision",
-		})
+		}
+		if flow != "" {
+			user["flow"] = flow
+		}
+		userArr = append(userArr, user)
 	}
 
 	out := map[string]any{
@@ -113,7 +199,8 @@ func (g *ConfigGenerator) vlessToSingBox(ib *db.Inbound) map[string]any {
 		if err := json.Unmarshal(ib.ConfigJSON, &cfg); err == nil {
 			if tls, ok := cfg["tls"]; ok && tls != nil {
 				if t, ok := tls.(map[string]any); ok && len(t) > 0 {
-					resolveCertInTLS(t)
+					resolveCertInTLS(g.DB, t)
+					realityToSingBox(t)
 					out["tls"] = t
 				}
 			}
@@ -131,7 +218,7 @@ func (g *ConfigGenerator) vlessToSingBox(ib *db.Inbound) map[string]any {
 // hysteria2ToSingBox produces Hysteria2 inbound map for sing-box.
 // Users are derived from User+UserInbound (valid only); config_json users ignored.
 func (g *ConfigGenerator) hysteria2ToSingBox(ib *db.Inbound) map[string]any {
-	users, _ := db.GetUsersForInbound(ib.ID)
+	users, _ := g.DB.GetUsersForInbound(ib.ID)
 	userArr := make([]any, 0, len(users))
 	for _, u := range users {
 		userArr = append(userArr, map[string]any{
@@ -169,12 +256,16 @@ func (g *ConfigGenerator) hysteria2ToSingBox(ib *db.Inbound) map[string]any {
 			}
 			if obfs, ok := cfg["obfs"]; ok && obfs != nil {
 				if o, ok := obfs.(map[string]any); ok && len(o) > 0 {
+					// Same default as inboundHysteria2; sing-box rejects an empty type.
+					if t, _ := o["type"].(string); t == "" {
+						o["type"] = "salamander"
+					}
 					out["obfs"] = o
 				}
 			}
 			if tls, ok := cfg["tls"]; ok && tls != nil {
 				if t, ok := tls.(map[string]any); ok && len(t) > 0 {
-					resolveCertInTLS(t)
+					resolveCertInTLS(g.DB, t)
 					out["tls"] = t
 				}
 			}
@@ -184,6 +275,239 @@ func (g *ConfigGenerator) hysteria2ToSingBox(ib *db.Inbound) map[string]any {
 	return out
 }
 
+// trojanToSingBox produces Trojan inbound map for sing-box.
+// Users are derived from User+UserInbound (valid only) and authenticate with User.Password.
+func (g *ConfigGenerator) trojanToSingBox(ib *db.Inbound) map[string]any {
+	users, _ := g.DB.GetUsersForInbound(ib.ID)
+	userArr := make([]any, 0, len(users))
+	for _, u := range users {
+		userArr = append(userArr, map[string]any{
+			"name":     u.Name,
+			"password": u.Password,
+		})
+	}
+
+	out := map[string]any{
+		"type":        "trojan",
+		"tag":         ib.Tag,
+		"listen":      ib.Listen,
+		"listen_port": ib.ListenPort,
+		"users":       userArr,
+	}
+
+	if cfg := inboundConfigMap(ib); cfg != nil {
+		if t, ok := cfg["tls"].(map[string]any); ok && len(t) > 0 {
+			resolveCertInTLS(g.DB, t)
+			out["tls"] = t
+		}
+		if tr, ok := cfg["transport"].(map[string]any); ok && len(tr) > 0 {
+			out["transport"] = tr
+		}
+	}
+
+	return out
+}
+
+// anyTLSToSingBox produces AnyTLS inbound map for sing-box.
+// Users authenticate with User.Password; tls and padding_scheme are copied from config_json.
+func (g *ConfigGenerator) anyTLSToSingBox(ib *db.Inbound) map[string]any {
+	users, _ := g.DB.GetUsersForInbound(ib.ID)
+	userArr := make([]any, 0, len(users))
+	for _, u := range users {
+		userArr = append(userArr, map[string]any{
+			"name":     u.Name,
+			"password": u.Password,
+		})
+	}
+
+	out := map[string]any{
+		"type":        "anytls",
+		"tag":         ib.Tag,
+		"listen":      ib.Listen,
+		"listen_port": ib.ListenPort,
+		"users":       userArr,
+	}
+
+	if cfg := inboundConfigMap(ib); cfg != nil {
+		if t, ok := cfg["tls"].(map[string]any); ok && len(t) > 0 {
+			resolveCertInTLS(g.DB, t)
+			out["tls"] = t
+		}
+		if ps, ok := cfg["padding_scheme"].([]any); ok && len(ps) > 0 {
+			out["padding_scheme"] = ps
+		}
+	}
+
+	return out
+}
+
+// naiveToSingBox produces NaiveProxy inbound map for sing-box.
+// Users authenticate with User.Name as username and User.Password; tls is copied from config_json.
+func (g *ConfigGenerator) naiveToSingBox(ib *db.Inbound) map[string]any {
+	users, _ := g.DB.GetUsersForInbound(ib.ID)
+	userArr := make([]any, 0, len(users))
+	for _, u := range users {
+		userArr = append(userArr, map[string]any{
+			"username": u.Name,
+			"password": u.Password,
+		})
+	}
+
+	out := map[string]any{
+		"type":        "naive",
+		"tag":         ib.Tag,
+		"listen":      ib.Listen,
+		"listen_port": ib.ListenPort,
+		"users":       userArr,
+	}
+
+	if cfg := inboundConfigMap(ib); cfg != nil {
+		if t, ok := cfg["tls"].(map[string]any); ok && len(t) > 0 {
+			resolveCertInTLS(g.DB, t)
+			out["tls"] = t
+		}
+		if network, ok := cfg["network"].(string); ok && network != "" {
+			out["network"] = network
+		}
+	}
+
+	return out
+}
+
+// vmessToSingBox produces VMess inbound map for sing-box.
+// Users reuse User.UUID with alterId 0 (AEAD only); tls and transport are copied from config_json.
+func (g *ConfigGenerator) vmessToSingBox(ib *db.Inbound) map[string]any {
+	users, _ := g.DB.GetUsersForInbound(ib.ID)
+	userArr := make([]any, 0, len(users))
+	for _, u := range users {
+		userArr = append(userArr, map[string]any{
+			"name":    u.Name,
+			"uuid":    u.UUID,
+			"alterId": 0,
+		})
+	}
+
+	out := map[string]any{
+		"type":        "vmess",
+		"tag":         ib.Tag,
+		"listen":      ib.Listen,
+		"listen_port": ib.ListenPort,
+		"users":       userArr,
+	}
+
+	if cfg := inboundConfigMap(ib); cfg != nil {
+		if t, ok := cfg["tls"].(map[string]any); ok && len(t) > 0 {
+			resolveCertInTLS(g.DB, t)
+			out["tls"] = t
+		}
+		if tr, ok := cfg["transport"].(map[string]any); ok && len(tr) > 0 {
+			out["transport"] = tr
+		}
+	}
+
+	return out
+}
+
+// tuicToSingBox produces TUIC v5 inbound map for sing-box.
+// Users carry User.UUID + User.Password; congestion_control and alpn come from config_json.
+func (g *ConfigGenerator) tuicToSingBox(ib *db.Inbound) map[string]any {
+	users, _ := g.DB.GetUsersForInbound(ib.ID)
+	userArr := make([]any, 0, len(users))
+	for _, u := range users {
+		userArr = append(userArr, map[string]any{
+			"name":     u.Name,
+			"uuid":     u.UUID,
+			"password": u.Password,
+		})
+	}
+
+	cfg := inboundConfigMap(ib)
+	cc, alpn := tuicSettings(cfg)
+	tls := map[string]any{
+		"enabled":          true,
+		"server_name":      "",
+		"certificate_path": "",
+		"key_path":         "",
+	}
+	if t, ok := cfg["tls"].(map[string]any); ok && len(t) > 0 {
+		resolveCertInTLS(g.DB, t)
+		tls = t
+	}
+	tls["enabled"] = true // QUIC requires TLS
+	tls["alpn"] = alpn
+
+	return map[string]any{
+		"type":               "tuic",
+		"tag":                ib.Tag,
+		"listen":             ib.Listen,
+		"listen_port":        ib.ListenPort,
+		"users":              userArr,
+		"congestion_control": cc,
+		"tls":                tls,
+	}
+}
+
+// tuicSettings returns congestion control and ALPN for a TUIC inbound.
+// alpn is read from config_json.alpn, then tls.alpn; defaults are "cubic" and ["h3"].
+func tuicSettings(cfg map[string]any) (cc string, alpn []string) {
+	cc, _ = cfg["congestion_control"].(string)
+	if cc == "" {
+		cc = "cubic"
+	}
+	raw, ok := cfg["alpn"].([]any)
+	if !ok {
+		if t, ok := cfg["tls"].(map[string]any); ok {
+			raw, _ = t["alpn"].([]any)
+		}
+	}
+	for _, v := range raw {
+		if s, ok := v.(string); ok && s != "" {
+			alpn = append(alpn, s)
+		}
+	}
+	if len(alpn) == 0 {
+		alpn = []string{"h3"}
+	}
+	return cc, alpn
+}
+
+// shadowsocksToSingBox produces multi-user Shadowsocks 2022 inbound map for sing-box.
+// Server PSK comes from config_json.password; user keys come from User.ShadowsocksKey.
+func (g *ConfigGenerator) shadowsocksToSingBox(ib *db.Inbound) map[string]any {
+	method, psk := shadowsocksSettings(inboundConfigMap(ib))
+	users, _ := g.DB.GetUsersForInbound(ib.ID)
+	userArr := make([]any, 0, len(users))
+	for _, u := range users {
+		userArr = append(userArr, map[string]any{
+			"name":     u.Name,
+			"password": shadowsocksUserKey(&u, method),
+		})
+	}
+
+	return map[string]any{
+		"type":        "shadowsocks",
+		"tag":         ib.Tag,
+		"listen":      ib.Listen,
+		"listen_port": ib.ListenPort,
+		"method":      method,
+		"password":    psk,
+		"users":       userArr,
+	}
+}
+
+// inboundConfigMap decodes inbound ConfigJSON into a generic map.
+// Returns nil when ConfigJSON is empty or invalid.
+func inboundConfigMap(ib *db.Inbound) map[string]any {
+	if len(ib.ConfigJSON) == 0 {
+		return nil
+	}
+	var cfg map[string]any
+	if err := json.Unmarshal(ib.ConfigJSON, &cfg); err != nil {
+		return nil
+	}
+	return cfg
+}
+
 func toFloat(v any) (float64, bool) {
 	switch x := v.(type) {
 	case float64:
@@ -228,7 +552,7 @@ func toUint(v any) (uint, bool) {
 
 // resolveCertInTLS resolves certificate_id in tls map to certificate_path and key_path.
 // Modifies tls in-place. Deletes certificate_id before emitting (sing-box does not know it).
-func resolveCertInTLS(tls map[string]any) {
+func resolveCertInTLS(conn db.Conn, tls map[string]any) {
 	certID, ok := tls["certificate_id"]
 	if !ok || certID == nil {
 		return
@@ -238,7 +562,7 @@ func resolveCertInTLS(tls map[string]any) {
 		delete(tls, "certificate_id")
 		return
 	}
-	cert, err := db.GetCertificateByID(id)
+	cert, err := conn.GetCertificateByID(id)
 	if err != nil {
 		delete(tls, "certificate_id")
 		return
@@ -0,0 +1,466 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/s-ui/s-ui/internal/db"
+	"gorm.io/datatypes"
+)
+
+// generateInbound runs Generate and returns the inbound with the given tag.
+func generateInbound(t *testing.T, tag string) map[string]any {
+	t.Helper()
+	gen := &ConfigGenerator{}
+	out, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	var cfg struct {
+		Inbounds []map[string]any `json:"inbounds"`
+	}
+	if err := json.Unmarshal(out, &cfg); err != nil {
+		t.Fatalf("unmarshal config: %v", err)
+	}
+	for _, ib := range cfg.Inbounds {
+		if ib["tag"] == tag {
+			return ib
+		}
+	}
+	t.Fatalf("inbound %q not found in generated config", tag)
+	return nil
+}
+
+func TestGenerateTrojanInbound(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	u := &db.User{Name: "trojan-user", Enabled: true}
+	if err := db.CreateUser(u); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	ib := &db.Inbound{
+		Tag:        "trojan-in",
+		Protocol:   "trojan",
+		ListenPort: 443,
+		ConfigJSON: datatypes.JSON(`{"tls":{"enabled":true,"server_name":"t.example.com"}}`),
+	}
+	if err := db.CreateInbound(ib); err != nil {
+		t.Fatalf("CreateInbound: %v", err)
+	}
+	if err := db.ReplaceUserInbounds(u.ID, []uint{ib.ID}); err != nil {
+		t.Fatalf("ReplaceUserInbounds: %v", err)
+	}
+
+	got := generateInbound(t, "trojan-in")
+	if got["type"] != "trojan" {
+		t.Fatalf("type = %v, want trojan", got["type"])
+	}
+	users, _ := got["users"].([]any)
+	if len(users) != 1 {
+		t.Fatalf("users = %v, want 1 entry", got["users"])
+	}
+	entry, _ := users[0].(map[string]any)
+	if entry["name"] != u.Name || entry["password"] != u.Password {
+		t.Fatalf("user entry = %v, want name/password of %s", entry, u.Name)
+	}
+	if _, ok := got["tls"].(map[string]any); !ok {
+		t.Fatalf("tls missing: %v", got)
+	}
+}
+
+func TestGenerateShadowsocksInbound(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	u := &db.User{Name: "ss-user", Password: "user-secret"}
+	if err := db.CreateUser(u); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	psk, err := GenerateShadowsocksPSK("2022-blake3-aes-256-gcm")
+	if err != nil {
+		t.Fatalf("GenerateShadowsocksPSK: %v", err)
+	}
+	ib := &db.Inbound{
+		Tag:        "ss-in",
+		Protocol:   "shadowsocks",
+		ListenPort: 8388,
+		ConfigJSON: datatypes.JSON(`{"method":"2022-blake3-aes-256-gcm","password":"` + psk + `"}`),
+	}
+	if err := db.CreateInbound(ib); err != nil {
+		t.Fatalf("CreateInbound: %v", err)
+	}
+	if err := db.ReplaceUserInbounds(u.ID, []uint{ib.ID}); err != nil {
+		t.Fatalf("ReplaceUserInbounds: %v", err)
+	}
+
+	got := generateInbound(t, "ss-in")
+	if got["method"] != "2022-blake3-aes-256-gcm" || got["password"] != psk {
+		t.Fatalf("method/password = %v/%v, want configured values", got["method"], got["password"])
+	}
+	users, _ := got["users"].([]any)
+	if len(users) != 1 {
+		t.Fatalf("users = %v, want 1 entry", got["users"])
+	}
+	entry, _ := users[0].(map[string]any)
+	userKey, _ := entry["password"].(string)
+	if err := ValidateShadowsocksPSK("2022-blake3-aes-256-gcm", userKey); err != nil {
+		t.Fatalf("derived user key invalid: %v", err)
+	}
+}
+
+func TestGenerateVMessInbound(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	u := &db.User{Name: "vmess-user"}
+	if err := db.CreateUser(u); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	ib := &db.Inbound{
+		Tag:        "vmess-in",
+		Protocol:   "vmess",
+		ListenPort: 10086,
+		ConfigJSON: datatypes.JSON(`{"transport":{"type":"ws","path":"/vm","headers":{"Host":"v.example.com"}}}`),
+	}
+	if err := db.CreateInbound(ib); err != nil {
+		t.Fatalf("CreateInbound: %v", err)
+	}
+	if err := db.ReplaceUserInbounds(u.ID, []uint{ib.ID}); err != nil {
+		t.Fatalf("ReplaceUserInbounds: %v", err)
+	}
+
+	got := generateInbound(t, "vmess-in")
+	if got["type"] != "vmess" {
+		t.Fatalf("type = %v, want vmess", got["type"])
+	}
+	users, _ := got["users"].([]any)
+	if len(users) != 1 {
+		t.Fatalf("users = %v, want 1 entry", got["users"])
+	}
+	entry, _ := users[0].(map[string]any)
+	if entry["name"] != u.Name || entry["uuid"] != u.UUID || entry["alterId"] != float64(0) {
+		t.Fatalf("user entry = %v, want name/uuid of %s with alterId 0", entry, u.Name)
+	}
+	tr, _ := got["transport"].(map[string]any)
+	headers, _ := tr["headers"].(map[string]any)
+	if tr["type"] != "ws" || tr["path"] != "/vm" || headers["Host"] != "v.example.com" {
+		t.Fatalf("transport = %v, want ws passthrough", got["transport"])
+	}
+	if _, ok := got["tls"]; ok {
+		t.Fatalf("tls = %v, want none without config", got["tls"])
+	}
+}
+
+func TestGenerateTUICInbound(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	cert := &db.Certificate{Name: "tuic", FullchainPath: "/etc/tuic/fullchain.pem", PrivkeyPath: "/etc/tuic/privkey.pem"}
+	if err := db.CreateCertificate(cert); err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	u := &db.User{Name: "tuic-user"}
+	if err := db.CreateUser(u); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	ib := &db.Inbound{
+		Tag:        "tuic-in",
+		Protocol:   "tuic",
+		ListenPort: 8443,
+		ConfigJSON: datatypes.JSON(fmt.Sprintf(`{"congestion_control":"bbr","alpn":["h3","spdy/3.1"],"tls":{"enabled":true,"certificate_id":%d}}`, cert.ID)),
+	}
+	if err := db.CreateInbound(ib); err != nil {
+		t.Fatalf("CreateInbound: %v", err)
+	}
+	if err := db.ReplaceUserInbounds(u.ID, []uint{ib.ID}); err != nil {
+		t.Fatalf("ReplaceUserInbounds: %v", err)
+	}
+
+	got := generateInbound(t, "tuic-in")
+	if got["congestion_control"] != "bbr" {
+		t.Fatalf("congestion_control = %v, want bbr", got["congestion_control"])
+	}
+	tls, _ := got["tls"].(map[string]any)
+	if tls["certificate_path"] != cert.FullchainPath || tls["key_path"] != cert.PrivkeyPath {
+		t.Fatalf("tls cert not resolved: %v", tls)
+	}
+	if _, ok := tls["certificate_id"]; ok {
+		t.Fatalf("certificate_id should be removed: %v", tls)
+	}
+	if alpn, _ := tls["alpn"].([]any); len(alpn) != 2 || alpn[0] != "h3" {
+		t.Fatalf("tls.alpn = %v, want [h3 spdy/3.1]", tls["alpn"])
+	}
+	users, _ := got["users"].([]any)
+	entry, _ := users[0].(map[string]any)
+	if entry["uuid"] != u.UUID || entry["password"] != u.Password {
+		t.Fatalf("user entry = %v, want uuid/password of %s", entry, u.Name)
+	}
+}
+
+func TestGenerateHysteria2ObfsDefaultsType(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	ib := &db.Inbound{
+		Tag:        "hy2-in",
+		Protocol:   "hysteria2",
+		ListenPort: 443,
+		ConfigJSON: datatypes.JSON(`{"obfs":{"password":"obfs-pw"}}`),
+	}
+	if err := db.CreateInbound(ib); err != nil {
+		t.Fatalf("CreateInbound: %v", err)
+	}
+
+	got := generateInbound(t, "hy2-in")
+	obfs, _ := got["obfs"].(map[string]any)
+	if obfs["type"] != "salamander" || obfs["password"] != "obfs-pw" {
+		t.Fatalf("obfs = %v, want salamander with configured password", got["obfs"])
+	}
+}
+
+func TestVLESSFlowPerInbound(t *testing.T) {
+	cases := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{name: "legacy_tls_tcp_defaults_to_vision", config: `{"tls":{"enabled":true}}`, want: FlowVision},
+		{name: "legacy_ws_has_no_flow", config: `{"tls":{"enabled":true},"transport":{"type":"ws"}}`, want: ""},
+		{name: "legacy_plain_has_no_flow", config: `{}`, want: ""},
+		{name: "explicit_empty_disables_flow", config: `{"flow":"","tls":{"enabled":true}}`, want: ""},
+		{name: "explicit_vision", config: `{"flow":"xtls-rprx-vision","tls":{"enabled":true}}`, want: FlowVision},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if err := db.Init(":memory:"); err != nil {
+				t.Fatalf("Init: %v", err)
+			}
+			u := &db.User{Name: "flow-user"}
+			if err := db.CreateUser(u); err != nil {
+				t.Fatalf("CreateUser: %v", err)
+			}
+			ib := &db.Inbound{
+				Tag:        "vless-flow",
+				Protocol:   "vless",
+				ListenPort: 443,
+				ConfigJSON: datatypes.JSON(tc.config),
+			}
+			if err := db.CreateInbound(ib); err != nil {
+				t.Fatalf("CreateInbound: %v", err)
+			}
+			if err := db.ReplaceUserInbounds(u.ID, []uint{ib.ID}); err != nil {
+				t.Fatalf("ReplaceUserInbounds: %v", err)
+			}
+
+			got := generateInbound(t, "vless-flow")
+			users, _ := got["users"].([]any)
+			entry, _ := users[0].(map[string]any)
+			flow, _ := entry["flow"].(string)
+			if flow != tc.want {
+				t.Fatalf("user flow = %q, want %q", flow, tc.want)
+			}
+
+			withInbounds, err := db.GetUserByID(u.ID)
+			if err != nil {
+				t.Fatalf("GetUserByID: %v", err)
+			}
+			links := GetNodeLinks(withInbounds, "v.example.com")
+			if len(links) != 1 {
+				t.Fatalf("GetNodeLinks len = %d, want 1", len(links))
+			}
+			if has := strings.Contains(links[0].Link, "flow="); has != (tc.want != "") {
+				t.Fatalf("link %s: flow present = %v, want %v", links[0].Link, has, tc.want != "")
+			}
+		})
+	}
+}
+
+func TestGenerateShadowTLSInboundDetour(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	u := &db.User{Name: "stls-user", Password: "stls-secret"}
+	if err := db.CreateUser(u); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	psk, err := GenerateShadowsocksPSK(DefaultShadowsocksMethod)
+	if err != nil {
+		t.Fatalf("GenerateShadowsocksPSK: %v", err)
+	}
+	ib := &db.Inbound{
+		Tag:        "stls-in",
+		Protocol:   "shadowtls",
+		ListenPort: 8443,
+		ConfigJSON: datatypes.JSON(`{"handshake":{"server":"www.example.com"},"strict_mode":true,"password":"` + psk + `"}`),
+	}
+	if err := db.CreateInbound(ib); err != nil {
+		t.Fatalf("CreateInbound: %v", err)
+	}
+	if err := db.ReplaceUserInbounds(u.ID, []uint{ib.ID}); err != nil {
+		t.Fatalf("ReplaceUserInbounds: %v", err)
+	}
+
+	got := generateInbound(t, "stls-in")
+	detour := ShadowTLSDetourTag("stls-in")
+	if got["type"] != "shadowtls" || got["version"] != float64(3) || got["detour"] != detour {
+		t.Fatalf("shadowtls inbound = %v, want v3 detouring to %s", got, detour)
+	}
+	hs, _ := got["handshake"].(map[string]any)
+	if hs["server"] != "www.example.com" || hs["server_port"] != float64(443) {
+		t.Fatalf("handshake = %v, want www.example.com:443", hs)
+	}
+	users, _ := got["users"].([]any)
+	if len(users) != 1 {
+		t.Fatalf("users = %v, want 1 entry", got["users"])
+	}
+	if entry, _ := users[0].(map[string]any); entry["password"] != u.Password {
+		t.Fatalf("shadowtls user = %v, want password of %s", entry, u.Name)
+	}
+
+	inner := generateInbound(t, detour)
+	if inner["type"] != "shadowsocks" || inner["listen"] != "127.0.0.1" || inner["password"] != psk {
+		t.Fatalf("detour inbound = %v, want loopback shadowsocks with configured PSK", inner)
+	}
+	if _, ok := inner["listen_port"]; ok {
+		t.Fatalf("detour inbound must not listen on a port: %v", inner)
+	}
+}
+
+func TestGenerateOutbounds(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	for _, ob := range []*db.Outbound{
+		{Tag: "eth1", Type: "direct", ConfigJSON: datatypes.JSON(`{"bind_interface":"eth1"}`)},
+		{Tag: "upstream", Type: "socks", ConfigJSON: datatypes.JSON(`{"server":"10.0.0.1","server_port":1080,"detour":"eth1","type":"ignored"}`)},
+		{Tag: "warp", Type: "wireguard", ConfigJSON: datatypes.JSON(`{"private_key":"k","address":["172.16.0.2/32"],"peers":[{"address":"engage.cloudflareclient.com","port":2408,"public_key":"p"}]}`)},
+	} {
+		if err := db.CreateOutbound(ob); err != nil {
+			t.Fatalf("CreateOutbound: %v", err)
+		}
+	}
+
+	gen := &ConfigGenerator{}
+	out, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	var cfg struct {
+		Outbounds []map[string]any `json:"outbounds"`
+		Endpoints []map[string]any `json:"endpoints"`
+	}
+	if err := json.Unmarshal(out, &cfg); err != nil {
+		t.Fatalf("unmarshal config: %v", err)
+	}
+
+	tags := make([]string, 0, len(cfg.Outbounds))
+	for _, ob := range cfg.Outbounds {
+		tags = append(tags, fmt.Sprint(ob["tag"]))
+	}
+	if got := strings.Join(tags, ","); got != "direct,block,eth1,upstream" {
+		t.Fatalf("outbound tags = %s, want direct,block,eth1,upstream", got)
+	}
+	if cfg.Outbounds[2]["bind_interface"] != "eth1" {
+		t.Fatalf("direct outbound = %v, want bind_interface eth1", cfg.Outbounds[2])
+	}
+	if cfg.Outbounds[3]["type"] != "socks" || cfg.Outbounds[3]["detour"] != "eth1" {
+		t.Fatalf("socks outbound = %v, want type socks detouring via eth1", cfg.Outbounds[3])
+	}
+
+	if len(cfg.Endpoints) != 1 || cfg.Endpoints[0]["tag"] != "warp" || cfg.Endpoints[0]["type"] != "wireguard" {
+		t.Fatalf("endpoints = %v, want wireguard endpoint warp", cfg.Endpoints)
+	}
+	peers, _ := cfg.Endpoints[0]["peers"].([]any)
+	peer, _ := peers[0].(map[string]any)
+	if ips, _ := peer["allowed_ips"].([]any); len(ips) != 2 {
+		t.Fatalf("peer allowed_ips = %v, want default full tunnel", peer["allowed_ips"])
+	}
+}
+
+func TestGenerateOutboundGroupsEnableClashAPI(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	t.Setenv("CLASH_API_LISTEN", "127.0.0.1:19090")
+	t.Setenv("CLASH_API_SECRET", "s3cret")
+
+	gen := &ConfigGenerator{}
+	out, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if strings.Contains(string(out), "clash_api") {
+		t.Fatalf("clash_api emitted without groups:\n%s", out)
+	}
+
+	if err := db.CreateOutbound(&db.Outbound{Tag: "auto", Type: "urltest", ConfigJSON: datatypes.JSON(`{"outbounds":["direct"],"interval":"3m","tolerance":50}`)}); err != nil {
+		t.Fatalf("CreateOutbound: %v", err)
+	}
+	out, err = gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	var cfg struct {
+		Outbounds    []map[string]any `json:"outbounds"`
+		Experimental struct {
+			ClashAPI map[string]any `json:"clash_api"`
+		} `json:"experimental"`
+	}
+	if err := json.Unmarshal(out, &cfg); err != nil {
+		t.Fatalf("unmarshal config: %v", err)
+	}
+	group := cfg.Outbounds[2]
+	if group["type"] != "urltest" || group["interval"] != "3m" || group["tolerance"] != float64(50) {
+		t.Fatalf("group = %v", group)
+	}
+	if cfg.Experimental.ClashAPI["external_controller"] != "127.0.0.1:19090" || cfg.Experimental.ClashAPI["secret"] != "s3cret" {
+		t.Fatalf("clash_api = %v", cfg.Experimental.ClashAPI)
+	}
+}
+
+func TestGenerateV2RayStatsCoverOutbounds(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	t.Setenv("V2RAY_API_ENABLED", "true")
+	for _, ob := range []*db.Outbound{
+		{Tag: "proxy", Type: "socks", ConfigJSON: datatypes.JSON(`{"server":"a","server_port":1080}`)},
+		{Tag: "warp", Type: "wireguard", ConfigJSON: datatypes.JSON(`{"private_key":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=","address":["10.0.0.2/32"],"peers":[{"address":"1.2.3.4","port":51820,"public_key":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}]}`)},
+	} {
+		if err := db.CreateOutbound(ob); err != nil {
+			t.Fatalf("CreateOutbound: %v", err)
+		}
+	}
+
+	out, err := (&ConfigGenerator{}).Generate()
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	var cfg struct {
+		Experimental struct {
+			V2RayAPI struct {
+				Stats struct {
+					Outbounds []string `json:"outbounds"`
+				} `json:"stats"`
+			} `json:"v2ray_api"`
+		} `json:"experimental"`
+	}
+	if err := json.Unmarshal(out, &cfg); err != nil {
+		t.Fatalf("unmarshal config: %v", err)
+	}
+	got := strings.Join(cfg.Experimental.V2RayAPI.Stats.Outbounds, ",")
+	if got != "direct,block,proxy,warp" {
+		t.Fatalf("stats outbounds = %s, want direct,block,proxy,warp", got)
+	}
+}
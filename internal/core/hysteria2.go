@@ -0,0 +1,75 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/s-ui/s-ui/internal/db"
+)
+
+// hysteria2Info is the client-relevant view of a Hysteria2 inbound's config_json.
+type hysteria2Info struct {
+	ObfsType     string
+	ObfsPassword string
+	HopPorts     string // port hopping range advertised to clients, e.g. "20000-30000"
+	UpMbps       int    // server upload bandwidth
+	DownMbps     int    // server download bandwidth
+}
+
+// inboundHysteria2 reads obfs, hop_ports and bandwidth hints from config_json.
+func inboundHysteria2(ib *db.Inbound) hysteria2Info {
+	var info hysteria2Info
+	cfg := inboundConfigMap(ib)
+	if obfs, ok := cfg["obfs"].(map[string]any); ok {
+		info.ObfsType, _ = obfs["type"].(string)
+		info.ObfsPassword, _ = obfs["password"].(string)
+		if info.ObfsType == "" && info.ObfsPassword != "" {
+			info.ObfsType = "salamander"
+		}
+	}
+	info.HopPorts, _ = cfg["hop_ports"].(string)
+	if f, ok := toFloat(cfg["up_mbps"]); ok && f > 0 {
+		info.UpMbps = int(f)
+	}
+	if f, ok := toFloat(cfg["down_mbps"]); ok && f > 0 {
+		info.DownMbps = int(f)
+	}
+	return info
+}
+
+// ValidateHopPorts checks a port hopping spec: comma-separated ports or
+// "start-end" ranges within 1-65535.
+func ValidateHopPorts(spec string) error {
+	if strings.TrimSpace(spec) == "" {
+		return errors.New("hop_ports must not be empty")
+	}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		lo, hi, isRange := strings.Cut(part, "-")
+		start, err := parsePort(lo)
+		if err != nil {
+			return fmt.Errorf("invalid hop_ports %q: %w", part, err)
+		}
+		if !isRange {
+			continue
+		}
+		end, err := parsePort(hi)
+		if err != nil {
+			return fmt.Errorf("invalid hop_ports %q: %w", part, err)
+		}
+		if start > end {
+			return fmt.Errorf("invalid hop_ports %q: start greater than end", part)
+		}
+	}
+	return nil
+}
+
+func parsePort(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 1 || n > 65535 {
+		return 0, errors.New("port must be 1-65535")
+	}
+	return n, nil
+}
@@ -0,0 +1,241 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/s-ui/s-ui/internal/db"
+	"gorm.io/datatypes"
+)
+
+// ImportPlan is what an importer extracted from a foreign config, with every
+// unsupported item listed in Skipped.
+type ImportPlan struct {
+	db.ImportData
+	Skipped []string
+}
+
+// Skip records an item that is not imported.
+func (p *ImportPlan) Skip(format string, args ...any) {
+	p.Skipped = append(p.Skipped, fmt.Sprintf(format, args...))
+}
+
+// singBoxImportKeys lists inbound options carried into config_json per protocol;
+// listen, listen_port, tag and users map to columns and user rows instead.
+var singBoxImportKeys = map[string][]string{
+	"vless":       {"tls", "transport", "flow"},
+	"vmess":       {"tls", "transport"},
+	"trojan":      {"tls", "transport"},
+	"hysteria2":   {"tls", "obfs", "up_mbps", "down_mbps"},
+	"tuic":        {"tls", "congestion_control"},
+	"shadowsocks": {"method", "password"},
+	"anytls":      {"tls", "padding_scheme"},
+	"naive":       {"tls", "network"},
+}
+
+// ParseSingBoxImport reads inbounds and their users from a sing-box config. Users
+// are deduplicated across inbounds by name, uuid or password; an entry whose
+// credentials contradict an earlier one with the same identity is skipped.
+func ParseSingBoxImport(data []byte) (*ImportPlan, error) {
+	var cfg map[string]json.RawMessage
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, errors.New("invalid sing-box config")
+	}
+	var inbounds []map[string]any
+	if raw, ok := cfg["inbounds"]; ok {
+		if err := json.Unmarshal(raw, &inbounds); err != nil {
+			return nil, errors.New("inbounds must be an array")
+		}
+	}
+	plan := &ImportPlan{}
+	sections := make([]string, 0, len(cfg))
+	for key := range cfg {
+		if key != "inbounds" && key != "log" {
+			sections = append(sections, key)
+		}
+	}
+	sort.Strings(sections)
+	for _, key := range sections {
+		plan.Skip("%s: section is not imported", key)
+	}
+
+	users := &importUsers{}
+	for i, in := range inbounds {
+		typ, _ := in["type"].(string)
+		tag, _ := in["tag"].(string)
+		if tag == "" {
+			tag = fmt.Sprintf("%s-%d", typ, i+1)
+		}
+		keys, ok := singBoxImportKeys[typ]
+		if !ok {
+			plan.Skip("inbound %s: unsupported type %q", tag, typ)
+			continue
+		}
+		port, _ := toUint(in["listen_port"])
+		if port == 0 {
+			plan.Skip("inbound %s: listen_port required", tag)
+			continue
+		}
+		listen, _ := in["listen"].(string)
+		if listen == "" {
+			listen = "::"
+		}
+		conf := map[string]any{}
+		for _, k := range keys {
+			if v, ok := in[k]; ok && v != nil {
+				conf[k] = v
+			}
+		}
+		item := db.ImportInbound{Inbound: db.Inbound{Tag: tag, Protocol: typ, Listen: listen, ListenPort: port}}
+		if tls, ok := conf["tls"].(map[string]any); ok {
+			item.Cert = importCertificate(tag, tls)
+			if _, inline := tls["certificate"]; inline && item.Cert == nil {
+				plan.Skip("inbound %s: inline tls certificate kept in config_json, not registered", tag)
+			}
+		}
+		rawUsers, _ := in["users"].([]any)
+		for j, ru := range rawUsers {
+			m, _ := ru.(map[string]any)
+			u := singBoxImportUser(typ, m)
+			if typ == "vless" && u.flow != "" {
+				if _, ok := conf["flow"]; !ok {
+					conf["flow"] = u.flow
+				}
+			}
+			if u.Name == "" {
+				u.Name = fmt.Sprintf("%s-%d", tag, j+1)
+			}
+			if err := users.add(u.User, tag); err != nil {
+				plan.Skip("inbound %s: user %s: %v", tag, u.Name, err)
+			}
+		}
+		b, err := json.Marshal(conf)
+		if err != nil {
+			return nil, err
+		}
+		item.Inbound.ConfigJSON = datatypes.JSON(b)
+		plan.Inbounds = append(plan.Inbounds, item)
+	}
+	plan.Users = users.list
+	return plan, nil
+}
+
+// Drop removes the inbound tag from the plan and unlinks it from users; users left
+// without inbounds are dropped too.
+func (p *ImportPlan) Drop(tag string) {
+	inbounds := p.Inbounds[:0]
+	for _, ib := range p.Inbounds {
+		if ib.Inbound.Tag != tag {
+			inbounds = append(inbounds, ib)
+		}
+	}
+	p.Inbounds = inbounds
+	users := p.Users[:0]
+	for _, u := range p.Users {
+		tags := u.InboundTags[:0]
+		for _, t := range u.InboundTags {
+			if t != tag {
+				tags = append(tags, t)
+			}
+		}
+		u.InboundTags = tags
+		if len(tags) > 0 {
+			users = append(users, u)
+		}
+	}
+	p.Users = users
+}
+
+// importCertificate takes certificate_path/key_path out of an inbound tls block as a
+// Certificate to register; it returns nil when either path is missing.
+func importCertificate(tag string, tls map[string]any) *db.Certificate {
+	cert, _ := tls["certificate_path"].(string)
+	key, _ := tls["key_path"].(string)
+	if cert == "" || key == "" {
+		return nil
+	}
+	return &db.Certificate{Name: tag, FullchainPath: cert, PrivkeyPath: key}
+}
+
+// singBoxUser is an inbound user entry with its vless flow.
+type singBoxUser struct {
+	db.User
+	flow string
+}
+
+// singBoxImportUser maps a sing-box inbound user to the panel credentials it uses.
+func singBoxImportUser(typ string, m map[string]any) singBoxUser {
+	var u singBoxUser
+	u.Enabled = true
+	u.Name, _ = m["name"].(string)
+	switch typ {
+	case "vless", "vmess":
+		u.UUID, _ = m["uuid"].(string)
+		u.flow, _ = m["flow"].(string)
+	case "tuic":
+		u.UUID, _ = m["uuid"].(string)
+		u.Password, _ = m["password"].(string)
+	case "naive":
+		u.Name, _ = m["username"].(string)
+		u.Password, _ = m["password"].(string)
+	case "shadowsocks":
+		u.ShadowsocksKey, _ = m["password"].(string)
+	default:
+		u.Password, _ = m["password"].(string)
+	}
+	u.Name = strings.TrimSpace(u.Name)
+	return u
+}
+
+// importUsers deduplicates imported users by name, uuid, password and shadowsocks key.
+type importUsers struct {
+	list []db.ImportUser
+}
+
+// add merges u into a known user with the same name or credential, or appends it,
+// and links it to inbound tag. Credentials that contradict the known user are an error.
+func (s *importUsers) add(u db.User, tag string) error {
+	for i := range s.list {
+		known := &s.list[i].User
+		if known.Name != u.Name && (u.UUID == "" || known.UUID != u.UUID) && (u.Password == "" || known.Password != u.Password) &&
+			(u.ShadowsocksKey == "" || known.ShadowsocksKey != u.ShadowsocksKey) {
+			continue
+		}
+		if u.UUID != "" && known.UUID != "" && u.UUID != known.UUID {
+			return fmt.Errorf("uuid conflicts with user %s", known.Name)
+		}
+		if u.Password != "" && known.Password != "" && u.Password != known.Password {
+			return fmt.Errorf("password conflicts with user %s", known.Name)
+		}
+		if u.ShadowsocksKey != "" && known.ShadowsocksKey != "" && u.ShadowsocksKey != known.ShadowsocksKey {
+			return fmt.Errorf("shadowsocks key conflicts with user %s", known.Name)
+		}
+		if known.UUID == "" {
+			known.UUID = u.UUID
+		}
+		if known.Password == "" {
+			known.Password = u.Password
+		}
+		if known.ShadowsocksKey == "" {
+			known.ShadowsocksKey = u.ShadowsocksKey
+		}
+		if !containsTag(s.list[i].InboundTags, tag) {
+			s.list[i].InboundTags = append(s.list[i].InboundTags, tag)
+		}
+		return nil
+	}
+	s.list = append(s.list, db.ImportUser{User: u, InboundTags: []string{tag}})
+	return nil
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
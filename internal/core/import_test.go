@@ -0,0 +1,41 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSingBoxImportDeduplicatesUsers(t *testing.T) {
+	plan, err := ParseSingBoxImport([]byte(`{"inbounds": [
+	  {"type": "tuic", "tag": "tuic-in", "listen_port": 443,
+	   "users": [{"name": "alice", "uuid": "u-1", "password": "p-1"}]},
+	  {"type": "hysteria2", "tag": "hy2-in", "listen_port": 8443,
+	   "users": [{"name": "a2", "password": "p-1"}, {"password": "p-3"}]},
+	  {"type": "vless", "tag": "vless-in", "listen_port": 9443,
+	   "users": [{"name": "alice", "uuid": "u-2"}]}
+	]}`))
+	if err != nil {
+		t.Fatalf("ParseSingBoxImport: %v", err)
+	}
+	if len(plan.Inbounds) != 3 {
+		t.Fatalf("inbounds = %d, want 3", len(plan.Inbounds))
+	}
+	if len(plan.Users) != 2 {
+		t.Fatalf("users = %+v, want alice and a generated name", plan.Users)
+	}
+	alice := plan.Users[0]
+	if alice.User.Name != "alice" || strings.Join(alice.InboundTags, ",") != "tuic-in,hy2-in" {
+		t.Fatalf("alice = %+v, want merged by password", alice)
+	}
+	if plan.Users[1].User.Name != "hy2-in-2" {
+		t.Fatalf("unnamed user = %q, want hy2-in-2", plan.Users[1].User.Name)
+	}
+	if len(plan.Skipped) != 1 || !strings.Contains(plan.Skipped[0], "inbound vless-in: user alice: uuid conflicts with user alice") {
+		t.Fatalf("skipped = %v", plan.Skipped)
+	}
+
+	plan.Drop("tuic-in")
+	if len(plan.Users) != 2 || strings.Join(plan.Users[0].InboundTags, ",") != "hy2-in" {
+		t.Fatalf("after Drop users = %+v", plan.Users)
+	}
+}
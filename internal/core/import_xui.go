@@ -0,0 +1,337 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/s-ui/s-ui/internal/db"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// xuiInbound is a row of the x-ui / 3x-ui inbounds table.
+type xuiInbound struct {
+	ID             uint
+	Up             int64
+	Down           int64
+	Total          int64
+	Remark         string
+	Enable         bool
+	ExpiryTime     int64 // unix ms; 0 = never
+	Listen         string
+	Port           uint
+	Protocol       string
+	Settings       string
+	StreamSettings string
+	Tag            string
+}
+
+// xuiClientTraffic is a row of the 3x-ui client_traffics table, keyed by client email.
+type xuiClientTraffic struct {
+	Email      string
+	Up         int64
+	Down       int64
+	ExpiryTime int64
+	Total      int64
+	Enable     bool
+}
+
+// xuiClient is a client entry of an inbound's settings JSON.
+type xuiClient struct {
+	ID         string `json:"id"`
+	Password   string `json:"password"`
+	Auth       string `json:"auth"`
+	Email      string `json:"email"`
+	Flow       string `json:"flow"`
+	Enable     *bool  `json:"enable"`
+	ExpiryTime int64  `json:"expiryTime"`
+	TotalGB    int64  `json:"totalGB"` // bytes despite the name
+}
+
+// xuiSettings is the settings JSON of an x-ui inbound.
+type xuiSettings struct {
+	Clients  []xuiClient `json:"clients"`
+	Method   string      `json:"method"`
+	Password string      `json:"password"`
+}
+
+// xuiProtocols maps x-ui inbound protocols to panel protocols.
+var xuiProtocols = map[string]string{
+	"vless":       "vless",
+	"vmess":       "vmess",
+	"trojan":      "trojan",
+	"shadowsocks": "shadowsocks",
+	"hysteria2":   "hysteria2",
+}
+
+// ParseXUIImport reads inbounds, clients and their traffic, expiry and total limits
+// from an x-ui or 3x-ui SQLite database. Clients are deduplicated across inbounds by
+// email, uuid or password; UUIDs and passwords are kept as-is.
+func ParseXUIImport(path string) (*ImportPlan, error) {
+	src, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return nil, err
+	}
+	if sqlDB, err := src.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+	if !src.Migrator().HasTable("inbounds") {
+		return nil, fmt.Errorf("not an x-ui database: inbounds table missing")
+	}
+	var rows []xuiInbound
+	if err := src.Table("inbounds").Order("id ASC").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("read x-ui inbounds: %w", err)
+	}
+	traffic := map[string]xuiClientTraffic{}
+	if src.Migrator().HasTable("client_traffics") {
+		var stats []xuiClientTraffic
+		if err := src.Table("client_traffics").Find(&stats).Error; err != nil {
+			return nil, fmt.Errorf("read x-ui client_traffics: %w", err)
+		}
+		for _, s := range stats {
+			traffic[s.Email] = s
+		}
+	}
+
+	plan := &ImportPlan{}
+	users := &importUsers{}
+	for _, row := range rows {
+		tag := row.Tag
+		if tag == "" {
+			tag = fmt.Sprintf("inbound-%d", row.Port)
+		}
+		protocol, ok := xuiProtocols[row.Protocol]
+		if !ok {
+			plan.Skip("inbound %s: unsupported protocol %q", tag, row.Protocol)
+			continue
+		}
+		if !row.Enable {
+			plan.Skip("inbound %s: disabled in x-ui", tag)
+			continue
+		}
+		var settings xuiSettings
+		if row.Settings != "" {
+			if err := json.Unmarshal([]byte(row.Settings), &settings); err != nil {
+				plan.Skip("inbound %s: invalid settings", tag)
+				continue
+			}
+		}
+		conf, cert, err := xuiStreamConfig(row.StreamSettings)
+		if err != nil {
+			plan.Skip("inbound %s: %v", tag, err)
+			continue
+		}
+		if protocol == "shadowsocks" {
+			conf["method"] = settings.Method
+			conf["password"] = settings.Password
+		}
+		if row.Total > 0 || row.ExpiryTime > 0 {
+			plan.Skip("inbound %s: inbound-level total and expiry are not imported", tag)
+		}
+		listen := row.Listen
+		if listen == "" {
+			listen = "::"
+		}
+		if cert != nil {
+			cert.Name = tag
+		}
+
+		for i, c := range settings.Clients {
+			u := xuiImportUser(protocol, c, traffic[c.Email])
+			if u.Name == "" {
+				u.Name = fmt.Sprintf("%s-%d", tag, i+1)
+			}
+			if protocol == "vless" && c.Flow != "" {
+				if _, ok := conf["flow"]; !ok {
+					conf["flow"] = c.Flow
+				}
+			}
+			if c.ExpiryTime < 0 {
+				plan.Skip("inbound %s: user %s: expiry relative to first use is not supported, imported without expiry", tag, u.Name)
+			}
+			if err := users.add(u, tag); err != nil {
+				plan.Skip("inbound %s: user %s: %v", tag, u.Name, err)
+			}
+		}
+
+		b, err := json.Marshal(conf)
+		if err != nil {
+			return nil, err
+		}
+		plan.Inbounds = append(plan.Inbounds, db.ImportInbound{
+			Inbound: db.Inbound{
+				Tag:             tag,
+				Protocol:        protocol,
+				Listen:          listen,
+				ListenPort:      row.Port,
+				ConfigJSON:      datatypes.JSON(b),
+				TrafficUplink:   row.Up,
+				TrafficDownlink: row.Down,
+			},
+			Cert: cert,
+		})
+	}
+	plan.Users = users.list
+	return plan, nil
+}
+
+// xuiImportUser maps an x-ui client and its traffic row to a panel user. The traffic
+// row, when present, is authoritative for counters, expiry and total.
+func xuiImportUser(protocol string, c xuiClient, stat xuiClientTraffic) db.User {
+	u := db.User{Name: c.Email, Enabled: c.Enable == nil || *c.Enable}
+	switch protocol {
+	case "vless", "vmess":
+		u.UUID = c.ID
+	case "hysteria2":
+		u.Password = c.Password
+		if u.Password == "" {
+			u.Password = c.Auth
+		}
+	case "shadowsocks":
+		u.ShadowsocksKey = c.Password
+	default:
+		u.Password = c.Password
+	}
+	expiry, total := c.ExpiryTime, c.TotalGB
+	if stat.Email != "" {
+		u.TrafficUplink = stat.Up
+		u.TrafficDownlink = stat.Down
+		u.TrafficUsed = stat.Up + stat.Down
+		expiry, total = stat.ExpiryTime, stat.Total
+		u.Enabled = u.Enabled && stat.Enable
+	}
+	if total > 0 {
+		u.TrafficLimit = total
+	}
+	if expiry > 0 {
+		t := time.UnixMilli(expiry).UTC()
+		u.ExpireAt = &t
+	}
+	return u
+}
+
+// xuiStreamConfig converts x-ui streamSettings into config_json tls and transport
+// blocks, returning certificate file paths separately for registration.
+func xuiStreamConfig(raw string) (map[string]any, *db.Certificate, error) {
+	conf := map[string]any{}
+	if raw == "" {
+		return conf, nil, nil
+	}
+	var stream struct {
+		Network         string         `json:"network"`
+		Security        string         `json:"security"`
+		TLSSettings     map[string]any `json:"tlsSettings"`
+		RealitySettings map[string]any `json:"realitySettings"`
+		TCPSettings     map[string]any `json:"tcpSettings"`
+		WSSettings      map[string]any `json:"wsSettings"`
+		GRPCSettings    map[string]any `json:"grpcSettings"`
+		HTTPUpgrade     map[string]any `json:"httpupgradeSettings"`
+		HTTPSettings    map[string]any `json:"httpSettings"`
+	}
+	if err := json.Unmarshal([]byte(raw), &stream); err != nil {
+		return nil, nil, fmt.Errorf("invalid streamSettings")
+	}
+
+	switch stream.Network {
+	case "", "tcp":
+		if header, ok := stream.TCPSettings["header"].(map[string]any); ok {
+			if t, _ := header["type"].(string); t != "" && t != "none" {
+				return nil, nil, fmt.Errorf("unsupported tcp header type %q", t)
+			}
+		}
+	case "ws":
+		tr := map[string]any{"type": "ws"}
+		if p, _ := stream.WSSettings["path"].(string); p != "" {
+			tr["path"] = p
+		}
+		host, _ := stream.WSSettings["host"].(string)
+		if headers, ok := stream.WSSettings["headers"].(map[string]any); ok && host == "" {
+			host = headerHost(headers)
+		}
+		if host != "" {
+			tr["headers"] = map[string]any{"Host": host}
+		}
+		conf["transport"] = tr
+	case "grpc":
+		name, _ := stream.GRPCSettings["serviceName"].(string)
+		conf["transport"] = map[string]any{"type": "grpc", "service_name": name}
+	case "httpupgrade":
+		tr := map[string]any{"type": "httpupgrade"}
+		if p, _ := stream.HTTPUpgrade["path"].(string); p != "" {
+			tr["path"] = p
+		}
+		if h, _ := stream.HTTPUpgrade["host"].(string); h != "" {
+			tr["host"] = h
+		}
+		conf["transport"] = tr
+	case "http", "h2":
+		tr := map[string]any{"type": "http"}
+		if p, _ := stream.HTTPSettings["path"].(string); p != "" {
+			tr["path"] = p
+		}
+		if hosts, ok := stream.HTTPSettings["host"].([]any); ok && len(hosts) > 0 {
+			tr["host"] = hosts
+		}
+		conf["transport"] = tr
+	default:
+		return nil, nil, fmt.Errorf("unsupported transport %q", stream.Network)
+	}
+
+	var cert *db.Certificate
+	switch stream.Security {
+	case "", "none":
+	case "tls":
+		tls := map[string]any{"enabled": true}
+		if sn, _ := stream.TLSSettings["serverName"].(string); sn != "" {
+			tls["server_name"] = sn
+		}
+		if alpn, ok := stream.TLSSettings["alpn"].([]any); ok && len(alpn) > 0 {
+			tls["alpn"] = alpn
+		}
+		certs, _ := stream.TLSSettings["certificates"].([]any)
+		if len(certs) > 0 {
+			c, _ := certs[0].(map[string]any)
+			certFile, _ := c["certificateFile"].(string)
+			keyFile, _ := c["keyFile"].(string)
+			if certFile != "" && keyFile != "" {
+				cert = &db.Certificate{FullchainPath: certFile, PrivkeyPath: keyFile}
+			} else if lines, ok := c["certificate"].([]any); ok && len(lines) > 0 {
+				tls["certificate"] = lines
+				tls["key"] = c["key"]
+			}
+		}
+		conf["tls"] = tls
+	case "reality":
+		rs := stream.RealitySettings
+		dest, _ := rs["dest"].(string)
+		if dest == "" {
+			dest, _ = rs["target"].(string) // 3x-ui renamed dest to target
+		}
+		host, port, err := net.SplitHostPort(dest)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid reality dest")
+		}
+		serverPort, _ := strconv.Atoi(port)
+		reality := map[string]any{
+			"enabled":     true,
+			"handshake":   map[string]any{"server": host, "server_port": serverPort},
+			"private_key": rs["privateKey"],
+		}
+		if ids, ok := rs["shortIds"].([]any); ok {
+			reality["short_id"] = ids
+		}
+		tls := map[string]any{"enabled": true, "reality": reality}
+		if names, ok := rs["serverNames"].([]any); ok && len(names) > 0 {
+			tls["server_name"] = names[0]
+		}
+		conf["tls"] = tls
+	default:
+		return nil, nil, fmt.Errorf("unsupported security %q", stream.Security)
+	}
+	return conf, cert, nil
+}
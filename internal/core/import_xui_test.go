@@ -0,0 +1,84 @@
+package core
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestParseXUIImport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "x-ui.db")
+	src, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE inbounds (id INTEGER PRIMARY KEY, user_id INTEGER, up INTEGER, down INTEGER, total INTEGER, remark TEXT,
+		 enable NUMERIC, expiry_time INTEGER, listen TEXT, port INTEGER, protocol TEXT, settings TEXT, stream_settings TEXT, tag TEXT, sniffing TEXT)`,
+		`CREATE TABLE client_traffics (id INTEGER PRIMARY KEY, inbound_id INTEGER, enable NUMERIC, email TEXT, up INTEGER, down INTEGER, expiry_time INTEGER, total INTEGER, reset INTEGER)`,
+		`INSERT INTO inbounds (up, down, total, enable, expiry_time, listen, port, protocol, settings, stream_settings, tag) VALUES
+		 (100, 200, 0, 1, 0, '', 443, 'vless',
+		  '{"clients":[{"id":"11111111-1111-1111-1111-111111111111","email":"alice","flow":"xtls-rprx-vision","enable":true}],"decryption":"none"}',
+		  '{"network":"tcp","security":"reality","realitySettings":{"dest":"www.example.com:443","serverNames":["www.example.com"],"privateKey":"k","shortIds":["ab"]}}',
+		  'inbound-443'),
+		 (0, 0, 0, 1, 0, '', 8443, 'trojan',
+		  '{"clients":[{"password":"alice-pw","email":"alice"},{"password":"bob-pw","email":"bob","enable":false,"expiryTime":1893456000000,"totalGB":1073741824}]}',
+		  '{"network":"ws","security":"tls","wsSettings":{"path":"/ws","headers":{"Host":"cdn.example.com"}},"tlsSettings":{"serverName":"a.example","certificates":[{"certificateFile":"/c.crt","keyFile":"/c.key"}]}}',
+		  'inbound-8443'),
+		 (0, 0, 0, 1, 0, '', 9000, 'socks', '{}', '', 'inbound-9000'),
+		 (0, 0, 0, 0, 0, '', 9001, 'vmess', '{"clients":[]}', '', 'inbound-9001')`,
+		`INSERT INTO client_traffics (enable, email, up, down, expiry_time, total) VALUES (1, 'alice', 10, 20, 1893456000000, 5000)`,
+	} {
+		if err := src.Exec(stmt).Error; err != nil {
+			t.Fatalf("exec: %v", err)
+		}
+	}
+	if sqlDB, err := src.DB(); err == nil {
+		sqlDB.Close()
+	}
+
+	plan, err := ParseXUIImport(path)
+	if err != nil {
+		t.Fatalf("ParseXUIImport: %v", err)
+	}
+	if len(plan.Inbounds) != 2 {
+		t.Fatalf("inbounds = %+v, want vless and trojan", plan.Inbounds)
+	}
+	vless := plan.Inbounds[0].Inbound
+	if vless.Listen != "::" || vless.TrafficUplink != 100 || vless.TrafficDownlink != 200 {
+		t.Fatalf("vless inbound = %+v", vless)
+	}
+	for _, want := range []string{`"flow":"xtls-rprx-vision"`, `"handshake":{"server":"www.example.com","server_port":443}`, `"short_id":["ab"]`} {
+		if !strings.Contains(string(vless.ConfigJSON), want) {
+			t.Errorf("vless config_json = %s, want %s", vless.ConfigJSON, want)
+		}
+	}
+	trojan := plan.Inbounds[1]
+	if trojan.Cert == nil || trojan.Cert.FullchainPath != "/c.crt" || !strings.Contains(string(trojan.Inbound.ConfigJSON), `"headers":{"Host":"cdn.example.com"}`) {
+		t.Fatalf("trojan inbound = %+v, config_json = %s", trojan, trojan.Inbound.ConfigJSON)
+	}
+
+	if len(plan.Users) != 2 {
+		t.Fatalf("users = %+v, want alice and bob", plan.Users)
+	}
+	alice, bob := plan.Users[0], plan.Users[1]
+	if alice.User.UUID != "11111111-1111-1111-1111-111111111111" || alice.User.Password != "alice-pw" ||
+		strings.Join(alice.InboundTags, ",") != "inbound-443,inbound-8443" {
+		t.Fatalf("alice = %+v", alice)
+	}
+	if alice.User.TrafficUsed != 30 || alice.User.TrafficLimit != 5000 || alice.User.ExpireAt == nil || alice.User.ExpireAt.Year() != 2030 {
+		t.Fatalf("alice traffic/limits = %+v", alice.User)
+	}
+	if bob.User.Enabled || bob.User.TrafficLimit != 1073741824 || bob.User.ExpireAt == nil {
+		t.Fatalf("bob = %+v, want disabled with settings limits", bob.User)
+	}
+	skipped := strings.Join(plan.Skipped, "\n")
+	for _, want := range []string{`inbound inbound-9000: unsupported protocol "socks"`, "inbound inbound-9001: disabled in x-ui"} {
+		if !strings.Contains(skipped, want) {
+			t.Errorf("skipped = %q, want %q", skipped, want)
+		}
+	}
+}
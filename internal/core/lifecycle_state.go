@@ -25,11 +25,12 @@ type LastFailureContext struct {
 
 // LifecycleSnapshot is a serializable state view for API responses.
 type LifecycleSnapshot struct {
-	State     CoreState           `json:"state"`
-	Running   bool                `json:"running"`
-	Installed bool                `json:"installed"`
-	Actions   []string            `json:"actions"`
-	LastError *LastFailureContext `json:"lastError,omitempty"`
+	State      CoreState           `json:"state"`
+	Running    bool                `json:"running"`
+	Installed  bool                `json:"installed"`
+	Actions    []string            `json:"actions"`
+	LastError  *LastFailureContext `json:"lastError,omitempty"`
+	Supervisor SupervisorStatus    `json:"supervisor"`
 }
 
 var (
@@ -46,10 +47,11 @@ func ResolveCoreState(pm *ProcessManager) LifecycleSnapshot {
 
 	state := resolveState(installed, running, failure)
 	snapshot := LifecycleSnapshot{
-		State:     state,
-		Running:   running,
-		Installed: installed,
-		Actions:   ActionMatrix(state),
+		State:      state,
+		Running:    running,
+		Installed:  installed,
+		Actions:    ActionMatrix(state),
+		Supervisor: pm.Supervision(),
 	}
 	if state == CoreStateError {
 		snapshot.LastError = failure
@@ -0,0 +1,186 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// LogEntry is one parsed line of sing-box output. Lines that do not look like a
+// sing-box log line (panics, startup noise) only carry Raw and Message.
+type LogEntry struct {
+	Timestamp  *time.Time `json:"timestamp,omitempty"`
+	Level      string     `json:"level,omitempty"`
+	Connection string     `json:"connection,omitempty"` // sing-box connection id
+	Component  string     `json:"component,omitempty"`  // e.g. router, inbound/vless[vless-in]
+	Tag        string     `json:"tag,omitempty"`        // inbound/outbound tag from Component
+	User       string     `json:"user,omitempty"`       // authenticated user on inbound lines
+	Message    string     `json:"message"`
+	Raw        string     `json:"raw"`
+}
+
+// logTimestampLayout is the prefix sing-box writes when log.timestamp is true.
+const logTimestampLayout = "-0700 2006-01-02 15:04:05"
+
+var (
+	// [timestamp ]LEVEL[[elapsed]] [[id duration] ]message
+	logLinePattern = regexp.MustCompile(`^(?:([+-]\d{4} \d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}) )?(TRACE|DEBUG|INFO|WARN|ERROR|FATAL|PANIC)(?:\[\d+\])? (?:\[(\d+) [^\]]*\] )?(.*)$`)
+	// component[tag]: message
+	logComponentPattern = regexp.MustCompile(`^([\w-]+(?:/[\w-]+)?(?:\[([^\]]*)\])?): (.*)$`)
+	// [user] message, written by inbounds once a user is authenticated.
+	logUserPattern = regexp.MustCompile(`^\[([^\]]+)\] (.*)$`)
+)
+
+// ParseLogLine splits a sing-box log line (written with --disable-color) into its parts.
+func ParseLogLine(line string) LogEntry {
+	entry := LogEntry{Message: line, Raw: line}
+	m := logLinePattern.FindStringSubmatch(line)
+	if m == nil {
+		return entry
+	}
+	if m[1] != "" {
+		if ts, err := time.Parse(logTimestampLayout, m[1]); err == nil {
+			ts = ts.UTC()
+			entry.Timestamp = &ts
+		}
+	}
+	entry.Level = strings.ToLower(m[2])
+	entry.Connection = m[3]
+	entry.Message = m[4]
+	if c := logComponentPattern.FindStringSubmatch(entry.Message); c != nil {
+		entry.Component, entry.Tag, entry.Message = c[1], c[2], c[3]
+		if strings.HasPrefix(entry.Component, "inbound/") {
+			if u := logUserPattern.FindStringSubmatch(entry.Message); u != nil {
+				entry.User, entry.Message = u[1], u[2]
+			}
+		}
+	}
+	return entry
+}
+
+// logFilterMaxConnections bounds the connection ids a LogFilter remembers.
+const logFilterMaxConnections = 4096
+
+// LogFilter selects log entries. Inbound and User match the inbound line that
+// accepted a connection; later lines of the same connection (routing, outbound
+// dials, errors) match too. A LogFilter is stateful and not safe for concurrent use.
+type LogFilter struct {
+	Level   string         // minimum level; lines without a level always pass
+	Inbound string         // inbound tag
+	User    string         // user name
+	Pattern *regexp.Regexp // matched against the raw line
+	conns   map[string]bool
+}
+
+// NewLogFilter validates level and compiles pattern; empty values do not filter.
+func NewLogFilter(level, inbound, user, pattern string) (*LogFilter, error) {
+	if err := ValidateLogLevel(level); err != nil {
+		return nil, err
+	}
+	f := &LogFilter{Level: level, Inbound: inbound, User: user}
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %v", err)
+		}
+		f.Pattern = re
+	}
+	return f, nil
+}
+
+// Match reports whether entry passes the filter. Entries must be passed in log order.
+func (f *LogFilter) Match(entry LogEntry) bool {
+	if (f.Inbound != "" || f.User != "") && !f.matchConnection(entry) {
+		return false
+	}
+	if f.Level != "" && entry.Level != "" && logLevels[entry.Level] < logLevels[f.Level] {
+		return false
+	}
+	return f.Pattern == nil || f.Pattern.MatchString(entry.Raw)
+}
+
+// matchConnection checks the inbound and user filters, remembering the connection
+// of a matching inbound line so the rest of that connection matches as well.
+func (f *LogFilter) matchConnection(entry LogEntry) bool {
+	if entry.Connection != "" && f.conns[entry.Connection] {
+		return true
+	}
+	if f.Inbound != "" && (!strings.HasPrefix(entry.Component, "inbound/") || entry.Tag != f.Inbound) {
+		return false
+	}
+	if f.User != "" && entry.User != f.User {
+		return false
+	}
+	if entry.Connection != "" {
+		if f.conns == nil || len(f.conns) >= logFilterMaxConnections {
+			f.conns = map[string]bool{}
+		}
+		f.conns[entry.Connection] = true
+	}
+	return true
+}
+
+// FollowCoreLog calls fn for every complete line appended to the log at path past
+// offset, checking every interval until ctx is done or fn returns an error. When
+// the file shrinks below offset (RotateCoreLog truncated it) it is read from the start.
+func FollowCoreLog(ctx context.Context, path string, offset int64, interval time.Duration, fn func(line string) error) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var partial []byte
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		data, size, err := readCoreLogFrom(path, offset)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		if size < offset {
+			offset, partial = 0, nil
+			if data, _, err = readCoreLogFrom(path, 0); err != nil {
+				return err
+			}
+		}
+		offset += int64(len(data))
+		partial = append(partial, data...)
+		for {
+			i := bytes.IndexByte(partial, '\n')
+			if i < 0 {
+				break
+			}
+			line := strings.TrimSuffix(string(partial[:i]), "\r")
+			partial = partial[i+1:]
+			if err := fn(line); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readCoreLogFrom returns the bytes of path past offset and the current file size.
+func readCoreLogFrom(path string, offset int64) ([]byte, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	if info.Size() <= offset {
+		return nil, info.Size(), nil
+	}
+	data, err := io.ReadAll(io.NewSectionReader(f, offset, info.Size()-offset))
+	return data, info.Size(), err
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLogLine(t *testing.T) {
+	cases := []struct {
+		name string
+		line string
+		want LogEntry
+	}{
+		{name: "inbound_user",
+			line: "+0800 2024-05-01 12:00:00 INFO [3216549870 0ms] inbound/vless[vless-in]: [alice] inbound connection to example.com:443",
+			want: LogEntry{Level: "info", Connection: "3216549870", Component: "inbound/vless[vless-in]", Tag: "vless-in", User: "alice", Message: "inbound connection to example.com:443"}},
+		{name: "router",
+			line: "+0800 2024-05-01 12:00:01 DEBUG [3216549870 5ms] router: match[0] => direct",
+			want: LogEntry{Level: "debug", Connection: "3216549870", Component: "router", Message: "match[0] => direct"}},
+		{name: "no_timestamp",
+			line: "WARN[0012] outbound/direct[direct]: dial failed",
+			want: LogEntry{Level: "warn", Component: "outbound/direct[direct]", Tag: "direct", Message: "dial failed"}},
+		{name: "unparsed",
+			line: "panic: runtime error",
+			want: LogEntry{Message: "panic: runtime error"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ParseLogLine(tc.line)
+			if tc.name == "inbound_user" {
+				want := time.Date(2024, 5, 1, 4, 0, 0, 0, time.UTC)
+				if got.Timestamp == nil || !got.Timestamp.Equal(want) {
+					t.Fatalf("timestamp = %v, want %v", got.Timestamp, want)
+				}
+			} else if tc.name == "no_timestamp" && got.Timestamp != nil {
+				t.Fatalf("timestamp = %v, want none", got.Timestamp)
+			}
+			got.Timestamp = nil
+			tc.want.Raw = tc.line
+			if got != tc.want {
+				t.Fatalf("ParseLogLine = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLogFilterFollowsConnection(t *testing.T) {
+	lines := []string{
+		"INFO [1 0ms] inbound/vless[vless-in]: [alice] inbound connection to a.com:443",
+		"INFO [2 0ms] inbound/vless[vless-in]: [bob] inbound connection to b.com:443",
+		"INFO [3 0ms] inbound/vmess[vmess-in]: [alice] inbound connection to c.com:443",
+		"ERROR [1 9ms] outbound/direct[direct]: dial tcp a.com:443: timeout",
+		"ERROR [2 9ms] outbound/direct[direct]: dial tcp b.com:443: timeout",
+		"ERROR router: missing rule set",
+	}
+	f, err := NewLogFilter("error", "vless-in", "alice", "")
+	if err != nil {
+		t.Fatalf("NewLogFilter: %v", err)
+	}
+	var got []string
+	for _, line := range lines {
+		if f.Match(ParseLogLine(line)) {
+			got = append(got, line)
+		}
+	}
+	if len(got) != 1 || got[0] != lines[3] {
+		t.Fatalf("matched %q, want only %q", got, lines[3])
+	}
+
+	if _, err := NewLogFilter("loud", "", "", ""); err == nil {
+		t.Fatal("NewLogFilter accepted an unknown level")
+	}
+	if _, err := NewLogFilter("", "", "", "("); err == nil {
+		t.Fatal("NewLogFilter accepted an invalid pattern")
+	}
+}
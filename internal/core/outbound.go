@@ -0,0 +1,82 @@
+package core
+
+import (
+	"encoding/json"
+
+	"github.com/s-ui/s-ui/internal/db"
+)
+
+// Built-in outbound tags the generator always emits ahead of db.Outbound rows.
+const (
+	OutboundDirect = "direct"
+	OutboundBlock  = "block"
+)
+
+// IsBuiltinOutboundTag reports whether tag names a generator-managed outbound.
+func IsBuiltinOutboundTag(tag string) bool {
+	return tag == OutboundDirect || tag == OutboundBlock
+}
+
+// IsOutboundGroupType reports whether typ is a selector or urltest group.
+func IsOutboundGroupType(typ string) bool {
+	return typ == "selector" || typ == "urltest"
+}
+
+// wireguardDefaultAllowedIPs routes all traffic through a peer that omits allowed_ips.
+var wireguardDefaultAllowedIPs = []any{"0.0.0.0/0", "::/0"}
+
+// outboundToSingBox converts db.Outbound to a sing-box outbound map.
+// config_json fields are copied as-is; type and tag always come from the row.
+// WireGuard is emitted as an endpoint (endpoint=true) since sing-box 1.11
+// replaced the wireguard outbound with endpoints; routes reference it by tag either way.
+func outboundToSingBox(ob *db.Outbound) (out map[string]any, endpoint bool) {
+	out = map[string]any{}
+	if len(ob.ConfigJSON) > 0 {
+		_ = json.Unmarshal(ob.ConfigJSON, &out)
+		if out == nil {
+			out = map[string]any{}
+		}
+	}
+	out["type"] = ob.Type
+	out["tag"] = ob.Tag
+
+	if ob.Type != "wireguard" {
+		return out, false
+	}
+	if peers, ok := out["peers"].([]any); ok {
+		for _, p := range peers {
+			if peer, ok := p.(map[string]any); ok {
+				if _, ok := peer["allowed_ips"]; !ok {
+					peer["allowed_ips"] = wireguardDefaultAllowedIPs
+				}
+			}
+		}
+	}
+	return out, true
+}
+
+// outboundsToSingBox returns the built-in direct/block outbounds followed by db.Outbound rows,
+// plus wireguard endpoints. groups reports whether any selector or urltest group exists,
+// which enables the Clash API used to switch and test them.
+func outboundsToSingBox(conn db.Conn) (outbounds, endpoints []map[string]any, groups bool, err error) {
+	rows, err := conn.ListOutbounds()
+	if err != nil {
+		return nil, nil, false, err
+	}
+	outbounds = []map[string]any{
+		{"type": "direct", "tag": OutboundDirect},
+		{"type": "block", "tag": OutboundBlock},
+	}
+	for i := range rows {
+		if IsOutboundGroupType(rows[i].Type) {
+			groups = true
+		}
+		out, endpoint := outboundToSingBox(&rows[i])
+		if endpoint {
+			endpoints = append(endpoints, out)
+		} else {
+			outbounds = append(outbounds, out)
+		}
+	}
+	return outbounds, endpoints, groups, nil
+}
@@ -0,0 +1,185 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/s-ui/s-ui/internal/db"
+)
+
+// OverlayError reports an overlay that cannot be merged into the generated config,
+// such as a block reusing a managed tag.
+type OverlayError struct {
+	Path    string
+	Message string
+}
+
+func (e *OverlayError) Error() string {
+	if e.Path == "" {
+		return "overlay: " + e.Message
+	}
+	return fmt.Sprintf("overlay %s: %s", e.Path, e.Message)
+}
+
+// ParseOverlay decodes a stored or submitted overlay; it must be a JSON object.
+func ParseOverlay(raw []byte) (map[string]any, error) {
+	var overlay map[string]any
+	if err := json.Unmarshal(raw, &overlay); err != nil || overlay == nil {
+		return nil, errors.New("overlay must be a JSON object")
+	}
+	return overlay, nil
+}
+
+// applyOverlay merges the stored overlay setting into the generated cfg. cfg is
+// re-decoded from JSON first so every list is []any like the overlay's.
+func applyOverlay(conn db.Conn, cfg map[string]any) (map[string]any, error) {
+	raw, err := conn.GetSetting(db.SettingConfigOverlay)
+	if err != nil {
+		return nil, err
+	}
+	if raw == "" {
+		return cfg, nil
+	}
+	overlay, err := ParseOverlay([]byte(raw))
+	if err != nil {
+		return nil, &OverlayError{Message: err.Error()}
+	}
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var merged map[string]any
+	if err := json.Unmarshal(b, &merged); err != nil {
+		return nil, err
+	}
+	if err := MergeOverlay(merged, overlay); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// MergeOverlay deep-merges overlay into cfg in-place; both must be decoded JSON.
+// Objects merge recursively and a null value deletes the key, as in a JSON merge
+// patch; arrays are extended with the overlay items so managed blocks are never
+// replaced. An item whose tag is already in use (outbounds and endpoints share one
+// namespace) is an *OverlayError, as is a null for a managed top-level key, object,
+// list or tag reference.
+func MergeOverlay(cfg, overlay map[string]any) error {
+	return mergeObject("", cfg, overlay, cfg)
+}
+
+// overlayTagKeys name the keys holding a tag or a reference to one, such as
+// route.final; deleting them would break the managed config.
+var overlayTagKeys = map[string]bool{
+	"tag":                     true,
+	"final":                   true,
+	"detour":                  true,
+	"download_detour":         true,
+	"default_domain_resolver": true,
+}
+
+// checkOverlayDelete rejects a null overlay value at path p for the existing value cur.
+func checkOverlayDelete(p, key string, cur any, exists, topLevel bool) error {
+	if !exists || cur == nil {
+		return nil
+	}
+	switch {
+	case topLevel:
+		return &OverlayError{Path: p, Message: "cannot delete a managed top-level key"}
+	case overlayTagKeys[key]:
+		return &OverlayError{Path: p, Message: "cannot delete a managed tag"}
+	}
+	switch cur.(type) {
+	case []any:
+		return &OverlayError{Path: p, Message: "cannot delete a managed list"}
+	case map[string]any:
+		return &OverlayError{Path: p, Message: "cannot delete a managed object"}
+	}
+	return nil
+}
+
+func mergeObject(path string, dst, src, root map[string]any) error {
+	for key, val := range src {
+		p := key
+		if path != "" {
+			p = path + "." + key
+		}
+		cur, exists := dst[key]
+		switch v := val.(type) {
+		case nil:
+			if err := checkOverlayDelete(p, key, cur, exists, path == ""); err != nil {
+				return err
+			}
+			delete(dst, key)
+		case map[string]any:
+			if obj, ok := cur.(map[string]any); ok {
+				if err := mergeObject(p, obj, v, root); err != nil {
+					return err
+				}
+				continue
+			}
+			if exists && cur != nil {
+				if _, isList := cur.([]any); isList {
+					return &OverlayError{Path: p, Message: "cannot replace a managed list with an object"}
+				}
+			}
+			dst[key] = v
+		case []any:
+			list, isList := cur.([]any)
+			if exists && cur != nil && !isList {
+				return &OverlayError{Path: p, Message: "must not be a list"}
+			}
+			taken := usedTags(p, list, root)
+			for i, item := range v {
+				obj, ok := item.(map[string]any)
+				if !ok {
+					continue
+				}
+				tag, _ := obj["tag"].(string)
+				if tag == "" {
+					continue
+				}
+				if taken[tag] {
+					return &OverlayError{Path: fmt.Sprintf("%s[%d]", p, i), Message: fmt.Sprintf("tag %q conflicts with a managed tag", tag)}
+				}
+				taken[tag] = true
+			}
+			dst[key] = append(list, v...)
+		default:
+			if _, isList := cur.([]any); isList {
+				return &OverlayError{Path: p, Message: "cannot replace a managed list"}
+			}
+			if _, isObj := cur.(map[string]any); isObj {
+				return &OverlayError{Path: p, Message: "cannot replace a managed object"}
+			}
+			dst[key] = v
+		}
+	}
+	return nil
+}
+
+// usedTags collects the tags of list and, for top-level outbounds and endpoints, of
+// the other list too since sing-box resolves both by the same tag.
+func usedTags(path string, list []any, root map[string]any) map[string]bool {
+	lists := [][]any{list}
+	switch path {
+	case "outbounds":
+		other, _ := root["endpoints"].([]any)
+		lists = append(lists, other)
+	case "endpoints":
+		other, _ := root["outbounds"].([]any)
+		lists = append(lists, other)
+	}
+	taken := map[string]bool{}
+	for _, l := range lists {
+		for _, item := range l {
+			if obj, ok := item.(map[string]any); ok {
+				if tag, _ := obj["tag"].(string); tag != "" {
+					taken[tag] = true
+				}
+			}
+		}
+	}
+	return taken
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/s-ui/s-ui/internal/db"
+)
+
+func TestMergeOverlay(t *testing.T) {
+	base := `{
+	  "log": {"level": "info"},
+	  "inbounds": [{"type": "vless", "tag": "vless-in"}],
+	  "outbounds": [{"type": "direct", "tag": "direct"}],
+	  "endpoints": [{"type": "wireguard", "tag": "warp"}],
+	  "route": {"rules": [{"action": "sniff"}], "final": "direct"}
+	}`
+	cases := []struct {
+		name    string
+		overlay string
+		want    []string
+		wantErr string
+	}{
+		{name: "merge_object", overlay: `{"log": {"level": "debug", "timestamp": true}, "ntp": {"enabled": true, "server": "time.apple.com"}}`,
+			want: []string{`"level":"debug"`, `"timestamp":true`, `"ntp":{"enabled":true,"server":"time.apple.com"}`}},
+		{name: "append_blocks", overlay: `{"inbounds": [{"type": "mixed", "tag": "local"}], "route": {"rules": [{"port": 25, "action": "reject"}]}}`,
+			want: []string{`{"tag":"vless-in","type":"vless"},{"tag":"local","type":"mixed"}`, `{"action":"sniff"},{"action":"reject","port":25}`}},
+		{name: "delete_key", overlay: `{"log": {"level": null}, "ntp": null}`, want: []string{`"log":{}`}},
+		{name: "managed_inbound_tag", overlay: `{"inbounds": [{"type": "mixed", "tag": "vless-in"}]}`,
+			wantErr: `overlay inbounds[0]: tag "vless-in" conflicts with a managed tag`},
+		{name: "outbound_vs_endpoint", overlay: `{"outbounds": [{"type": "socks", "tag": "warp"}]}`,
+			wantErr: `overlay outbounds[0]: tag "warp" conflicts with a managed tag`},
+		{name: "duplicate_in_overlay", overlay: `{"endpoints": [{"type": "wireguard", "tag": "a"}, {"type": "wireguard", "tag": "a"}]}`,
+			wantErr: `overlay endpoints[1]: tag "a" conflicts`},
+		{name: "replace_list", overlay: `{"inbounds": {"type": "mixed"}}`, wantErr: "overlay inbounds: cannot replace a managed list with an object"},
+		{name: "delete_list", overlay: `{"route": {"rules": null}}`, wantErr: "overlay route.rules: cannot delete a managed list"},
+		{name: "delete_top_level", overlay: `{"route": null}`, wantErr: "overlay route: cannot delete a managed top-level key"},
+		{name: "delete_top_level_list", overlay: `{"inbounds": null}`, wantErr: "overlay inbounds: cannot delete a managed top-level key"},
+		{name: "delete_tag_reference", overlay: `{"route": {"final": null}}`, wantErr: "overlay route.final: cannot delete a managed tag"},
+		{name: "replace_object", overlay: `{"route": "x"}`, wantErr: "overlay route: cannot replace a managed object"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var cfg map[string]any
+			if err := json.Unmarshal([]byte(base), &cfg); err != nil {
+				t.Fatalf("unmarshal base: %v", err)
+			}
+			overlay, err := ParseOverlay([]byte(tc.overlay))
+			if err != nil {
+				t.Fatalf("ParseOverlay: %v", err)
+			}
+			err = MergeOverlay(cfg, overlay)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("MergeOverlay() = %v, want error containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MergeOverlay() = %v", err)
+			}
+			out, _ := json.Marshal(cfg)
+			for _, want := range tc.want {
+				if !strings.Contains(string(out), want) {
+					t.Errorf("merged = %s, want %s", out, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateAppliesOverlay(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	if err := db.SetSetting(db.SettingConfigOverlay, `{"outbounds": [{"type": "socks", "tag": "upstream", "server": "10.0.0.1", "server_port": 1080}]}`); err != nil {
+		t.Fatalf("SetSetting: %v", err)
+	}
+	gen := &ConfigGenerator{}
+	out, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if !strings.Contains(string(out), `"tag": "upstream"`) {
+		t.Fatalf("overlay outbound missing:\n%s", out)
+	}
+
+	if err := db.CreateOutbound(&db.Outbound{Tag: "upstream", Type: "direct"}); err != nil {
+		t.Fatalf("CreateOutbound: %v", err)
+	}
+	_, err = gen.Generate()
+	var overlayErr *OverlayError
+	if err == nil || !strings.Contains(err.Error(), `tag "upstream" conflicts with a managed tag`) {
+		t.Fatalf("Generate() = %v, want managed tag conflict", err)
+	}
+	if !errors.As(err, &overlayErr) {
+		t.Fatalf("Generate() error %T, want *OverlayError", err)
+	}
+}
@@ -18,6 +18,7 @@ import (
 type ProcessManager struct {
 	configPath string
 	binaryPath string // explicit path; empty = use LookPath
+	logPath    string // stdout/stderr of started processes; empty = discarded
 }
 
 type ProcessErrorCode string
@@ -33,6 +34,7 @@ const (
 
 const (
 	startupHealthWindow = 500 * time.Millisecond
+	reloadHealthWindow  = 500 * time.Millisecond
 	stopGraceWindow    = 2 * time.Second
 	stopForceWindow    = 1 * time.Second
 	stopPollInterval   = 100 * time.Millisecond
@@ -80,7 +82,7 @@ func NewProcessManagerFromConfig(cfg *config.Config) *ProcessManager {
 		dir := filepath.Dir(binaryPath)
 		_ = os.MkdirAll(dir, 0755)
 	}
-	return &ProcessManager{configPath: configPath, binaryPath: binaryPath}
+	return &ProcessManager{configPath: configPath, binaryPath: binaryPath, logPath: config.LogPath(cfg.DataDir)}
 }
 
 // NewProcessManagerWithBinary creates a ProcessManager with explicit binary path.
@@ -156,8 +158,14 @@ func (p *ProcessManager) IsRunning() bool {
 	return len(pids) > 0
 }
 
-// Start starts sing-box in background and validates startup health.
+// Start starts sing-box in background and validates startup health. The process is
+// then supervised: an exit not caused by Stop is restarted with backoff.
 func (p *ProcessManager) Start(configPath string) error {
+	return p.start(configPath, true)
+}
+
+// start launches sing-box; manual is false for supervisor restarts.
+func (p *ProcessManager) start(configPath string, manual bool) error {
 	if !p.Available() {
 		return newProcessError(ProcessErrorNotInstalled, "sing-box binary is not installed", p.BinaryPath())
 	}
@@ -165,7 +173,18 @@ func (p *ProcessManager) Start(configPath string) error {
 		return newProcessError(ProcessErrorAlreadyRunning, "sing-box is already running", "")
 	}
 
-	cmd := exec.Command(p.binary(), "run", "-c", configPath)
+	cmd := exec.Command(p.binary(), "run", "-c", configPath, "--disable-color")
+	if p.logPath != "" {
+		logFile, err := openCoreLog(p.logPath)
+		if err != nil {
+			log.Printf("[warn] sing-box output not captured: %v", err)
+		} else {
+			// The child keeps its own descriptor.
+			defer logFile.Close()
+			cmd.Stdout = logFile
+			cmd.Stderr = logFile
+		}
+	}
 	if err := cmd.Start(); err != nil {
 		setLastFailure(newFailureContext("failed to start sing-box", "start", err.Error()))
 		return newProcessError(ProcessErrorStartFailed, "failed to start sing-box", err.Error())
@@ -183,13 +202,13 @@ func (p *ProcessManager) Start(configPath string) error {
 	}
 
 	clearLastFailure()
-	// Keep a background waiter to reap process exit and avoid defunct children.
-	go reapProcess(cmd)
+	supervisorFor(p.configPath).track(p, cmd, configPath, manual)
 	return nil
 }
 
 // Stop stops the running sing-box process.
 func (p *ProcessManager) Stop() error {
+	supervisorFor(p.configPath).release()
 	pids, err := p.runningPIDs()
 	if err != nil {
 		return newProcessError(ProcessErrorStopFailed, "failed to stop sing-box", err.Error())
@@ -246,6 +265,49 @@ func (p *ProcessManager) Restart(configPath string) error {
 	return nil
 }
 
+// ReloadMode reports how Reload put a new config into effect.
+type ReloadMode string
+
+const (
+	ReloadModeReload  ReloadMode = "reload"  // SIGHUP, same process
+	ReloadModeRestart ReloadMode = "restart" // full Stop + Start
+)
+
+// Reload sends SIGHUP to the managed sing-box so it re-reads configPath in place,
+// then checks the process survived. When sing-box is not running, cannot be
+// signalled or exits during the health window, it falls back to Restart.
+func (p *ProcessManager) Reload(configPath string) (ReloadMode, error) {
+	pids, err := p.runningPIDs()
+	if err == nil && len(pids) > 0 {
+		if err := signalPIDs("HUP", pids); err == nil {
+			time.Sleep(reloadHealthWindow)
+			if p.pidsRunning(pids) {
+				return ReloadModeReload, nil
+			}
+			setLastFailure(newFailureContext("sing-box exited after reload", "reload", "process exited during reload health check"))
+		}
+	}
+	return ReloadModeRestart, p.Restart(configPath)
+}
+
+// pidsRunning reports whether every pid is still a managed sing-box process.
+func (p *ProcessManager) pidsRunning(pids []int) bool {
+	running, err := p.runningPIDs()
+	if err != nil {
+		return false
+	}
+	alive := make(map[int]bool, len(running))
+	for _, pid := range running {
+		alive[pid] = true
+	}
+	for _, pid := range pids {
+		if !alive[pid] {
+			return false
+		}
+	}
+	return true
+}
+
 func (p *ProcessManager) runningPIDs() ([]int, error) {
 	pids, err := listSingBoxPIDs()
 	if err != nil {
@@ -380,9 +442,3 @@ func waitForProcessStop(isRunning func() bool, timeout time.Duration) bool {
 	}
 }
 
-func reapProcess(cmd *exec.Cmd) {
-	if cmd == nil {
-		return
-	}
-	_ = cmd.Wait()
-}
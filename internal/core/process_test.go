@@ -0,0 +1,60 @@
+package core
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// startFakeSingBox starts a fake sing-box whose run command executes body.
+func startFakeSingBox(t *testing.T, body string) (*ProcessManager, string) {
+	t.Helper()
+	if _, err := exec.LookPath("pgrep"); err != nil {
+		t.Skip("pgrep not available")
+	}
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "sing-box")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\n[ \"$1\" = run ] || exit 0\n"+body+"\n"), 0755); err != nil {
+		t.Fatalf("write fake binary: %v", err)
+	}
+	configPath := filepath.Join(dir, "config.json")
+	pm := NewProcessManagerWithBinary(configPath, binary)
+	if err := pm.Start(configPath); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { pm.Stop() })
+	return pm, configPath
+}
+
+func TestProcessManagerReload(t *testing.T) {
+	cases := []struct {
+		name     string
+		onHUP    string
+		want     ReloadMode
+		wantHUPs bool
+	}{
+		{name: "signal", onHUP: `echo hup >> "$3.hup"`, want: ReloadModeReload, wantHUPs: true},
+		{name: "exit_falls_back_to_restart", onHUP: "exit 1", want: ReloadModeRestart},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, configPath := startFakeSingBox(t, "trap '"+tc.onHUP+"' HUP\nwhile :; do sleep 0.05; done")
+
+			mode, err := pm.Reload(configPath)
+			if err != nil {
+				t.Fatalf("Reload: %v", err)
+			}
+			if mode != tc.want {
+				t.Fatalf("Reload mode = %q, want %q", mode, tc.want)
+			}
+			if !pm.IsRunning() {
+				t.Fatal("sing-box not running after Reload")
+			}
+			_, err = os.Stat(configPath + ".hup")
+			if (err == nil) != tc.wantHUPs {
+				t.Fatalf("SIGHUP handled = %v, want %v", err == nil, tc.wantHUPs)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package core
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/s-ui/s-ui/internal/db"
+)
+
+// DefaultRealityFingerprint is the uTLS fingerprint advertised to clients when unset.
+const DefaultRealityFingerprint = "chrome"
+
+// GenerateRealityKeypair returns a new x25519 private/public key pair in the
+// base64 raw URL encoding used by sing-box and Xray clients.
+func GenerateRealityKeypair() (privateKey, publicKey string, err error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(key.Bytes()),
+		base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()), nil
+}
+
+// RealityPublicKey derives the public key from a base64 raw URL x25519 private key.
+func RealityPublicKey(privateKey string) (string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(privateKey)
+	if err != nil {
+		return "", fmt.Errorf("reality private_key must be base64url: %w", err)
+	}
+	key, err := ecdh.X25519().NewPrivateKey(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid reality private_key: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()), nil
+}
+
+// GenerateRealityShortID returns a random 8-byte short ID as 16 hex characters.
+func GenerateRealityShortID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ValidateRealityShortID checks a short ID is hex with even length and at most 16 characters.
+func ValidateRealityShortID(id string) error {
+	if len(id) > 16 || len(id)%2 != 0 {
+		return errors.New("reality short_id must be an even-length hex string of at most 16 characters")
+	}
+	if _, err := hex.DecodeString(id); err != nil {
+		return errors.New("reality short_id must be hex")
+	}
+	return nil
+}
+
+// PrepareReality fills missing REALITY key material in a tls block in-place:
+// private_key/public_key when absent, public_key when only private_key is set,
+// and one short_id when none exist. Returns true when tls was modified.
+func PrepareReality(tls map[string]any) (bool, error) {
+	reality, ok := tls["reality"].(map[string]any)
+	if !ok || reality == nil {
+		return false, nil
+	}
+	if enabled, ok := reality["enabled"].(bool); ok && !enabled {
+		return false, nil
+	}
+	changed := false
+	priv, _ := reality["private_key"].(string)
+	if priv == "" {
+		newPriv, pub, err := GenerateRealityKeypair()
+		if err != nil {
+			return false, err
+		}
+		reality["private_key"] = newPriv
+		reality["public_key"] = pub
+		changed = true
+	} else {
+		pub, err := RealityPublicKey(priv)
+		if err != nil {
+			return false, err
+		}
+		if existing, _ := reality["public_key"].(string); existing != pub {
+			reality["public_key"] = pub
+			changed = true
+		}
+	}
+	if len(realityShortIDs(reality)) == 0 {
+		id, err := GenerateRealityShortID()
+		if err != nil {
+			return false, err
+		}
+		reality["short_id"] = []any{id}
+		changed = true
+	}
+	return changed, nil
+}
+
+// realityShortIDs returns short_id as a list, accepting string or list forms.
+func realityShortIDs(reality map[string]any) []string {
+	switch v := reality["short_id"].(type) {
+	case string:
+		if v != "" {
+			return []string{v}
+		}
+	case []any:
+		ids := make([]string, 0, len(v))
+		for _, x := range v {
+			if s, ok := x.(string); ok {
+				ids = append(ids, s)
+			}
+		}
+		return ids
+	}
+	return nil
+}
+
+// realityInfo is the client-side view of an inbound's REALITY settings.
+type realityInfo struct {
+	Enabled     bool
+	PublicKey   string
+	ShortID     string
+	ServerName  string
+	Fingerprint string
+}
+
+// inboundReality reads REALITY settings from config_json.tls.reality.
+func inboundReality(ib *db.Inbound) realityInfo {
+	cfg := inboundConfigMap(ib)
+	tls, _ := cfg["tls"].(map[string]any)
+	reality, _ := tls["reality"].(map[string]any)
+	if reality == nil {
+		return realityInfo{}
+	}
+	if enabled, ok := reality["enabled"].(bool); ok && !enabled {
+		return realityInfo{}
+	}
+	info := realityInfo{Enabled: true, Fingerprint: DefaultRealityFingerprint}
+	info.PublicKey, _ = reality["public_key"].(string)
+	if info.PublicKey == "" {
+		if priv, _ := reality["private_key"].(string); priv != "" {
+			info.PublicKey, _ = RealityPublicKey(priv)
+		}
+	}
+	if ids := realityShortIDs(reality); len(ids) > 0 {
+		info.ShortID = ids[0]
+	}
+	if fp, _ := reality["fingerprint"].(string); fp != "" {
+		info.Fingerprint = fp
+	}
+	info.ServerName, _ = tls["server_name"].(string)
+	if info.ServerName == "" {
+		if hs, ok := reality["handshake"].(map[string]any); ok {
+			info.ServerName, _ = hs["server"].(string)
+		}
+	}
+	return info
+}
+
+// realityToSingBox strips panel-only REALITY fields from tls in-place and
+// defaults tls.server_name to the handshake server, which sing-box requires.
+func realityToSingBox(tls map[string]any) {
+	reality, ok := tls["reality"].(map[string]any)
+	if !ok || reality == nil {
+		return
+	}
+	delete(reality, "public_key")
+	delete(reality, "fingerprint")
+	if s, _ := tls["server_name"].(string); s == "" {
+		if hs, ok := reality["handshake"].(map[string]any); ok {
+			if server, _ := hs["server"].(string); server != "" {
+				tls["server_name"] = server
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package core
+
+import "testing"
+
+func TestPrepareRealityGeneratesKeyMaterial(t *testing.T) {
+	tls := map[string]any{
+		"enabled": true,
+		"reality": map[string]any{
+			"enabled":   true,
+			"handshake": map[string]any{"server": "www.example.com", "server_port": 443},
+		},
+	}
+	changed, err := PrepareReality(tls)
+	if err != nil {
+		t.Fatalf("PrepareReality: %v", err)
+	}
+	if !changed {
+		t.Fatal("PrepareReality should report a change when keys are missing")
+	}
+	reality := tls["reality"].(map[string]any)
+	priv, _ := reality["private_key"].(string)
+	pub, _ := reality["public_key"].(string)
+	derived, err := RealityPublicKey(priv)
+	if err != nil {
+		t.Fatalf("RealityPublicKey: %v", err)
+	}
+	if derived != pub {
+		t.Fatalf("public_key = %q, derived %q", pub, derived)
+	}
+	ids := realityShortIDs(reality)
+	if len(ids) != 1 || ValidateRealityShortID(ids[0]) != nil {
+		t.Fatalf("short_id = %v, want one valid id", reality["short_id"])
+	}
+
+	changed, err = PrepareReality(tls)
+	if err != nil || changed {
+		t.Fatalf("second PrepareReality changed=%v err=%v, want no change", changed, err)
+	}
+}
+
+func TestRealityToSingBoxStripsPanelFields(t *testing.T) {
+	tls := map[string]any{
+		"enabled": true,
+		"reality": map[string]any{
+			"enabled":     true,
+			"handshake":   map[string]any{"server": "www.example.com"},
+			"private_key": "x",
+			"public_key":  "y",
+			"fingerprint": "firefox",
+		},
+	}
+	realityToSingBox(tls)
+	reality := tls["reality"].(map[string]any)
+	if _, ok := reality["public_key"]; ok {
+		t.Error("public_key should be stripped")
+	}
+	if _, ok := reality["fingerprint"]; ok {
+		t.Error("fingerprint should be stripped")
+	}
+	if tls["server_name"] != "www.example.com" {
+		t.Errorf("server_name = %v, want handshake server", tls["server_name"])
+	}
+}
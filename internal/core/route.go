@@ -0,0 +1,195 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+
+	"github.com/s-ui/s-ui/internal/db"
+)
+
+// routeMatchStringFields are sing-box rule fields holding lists of free-form strings.
+var routeMatchStringFields = map[string]bool{
+	"inbound":        true,
+	"auth_user":      true,
+	"domain":         true,
+	"domain_suffix":  true,
+	"domain_keyword": true,
+	"domain_regex":   true,
+	"ip_cidr":        true,
+	"port_range":     true,
+	"protocol":       true,
+	"network":        true,
+	"rule_set":       true,
+}
+
+// sniffProtocols lists protocol names sing-box sniffing can report.
+var sniffProtocols = map[string]bool{
+	"http": true, "tls": true, "quic": true, "stun": true, "dns": true,
+	"bittorrent": true, "dtls": true, "ssh": true, "rdp": true, "ntp": true,
+}
+
+// ValidateRouteMatch checks the match fields of a route rule. Every field must be a
+// non-empty list; port takes numbers, all other fields take strings.
+func ValidateRouteMatch(match map[string]any) error {
+	if len(match) == 0 {
+		return errors.New("route rule requires at least one match field")
+	}
+	for key, raw := range match {
+		list, ok := raw.([]any)
+		if !ok || len(list) == 0 {
+			return fmt.Errorf("match %s must be a non-empty list", key)
+		}
+		if key == "port" {
+			for _, v := range list {
+				if p, ok := toUint(v); !ok || p == 0 || p > 65535 {
+					return fmt.Errorf("invalid port: %v", v)
+				}
+			}
+			continue
+		}
+		if !routeMatchStringFields[key] {
+			return fmt.Errorf("unsupported match field: %s", key)
+		}
+		for _, v := range list {
+			s, ok := v.(string)
+			if !ok || s == "" {
+				return fmt.Errorf("match %s must contain non-empty strings", key)
+			}
+			if err := validateRouteMatchValue(key, s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateRouteMatchValue(key, s string) error {
+	switch key {
+	case "domain_regex":
+		if _, err := regexp.Compile(s); err != nil {
+			return fmt.Errorf("invalid domain_regex %q: %w", s, err)
+		}
+	case "ip_cidr":
+		if _, err := netip.ParsePrefix(s); err != nil {
+			if _, err := netip.ParseAddr(s); err != nil {
+				return fmt.Errorf("invalid ip_cidr: %s", s)
+			}
+		}
+	case "port_range":
+		start, end, ok := strings.Cut(s, ":")
+		if !ok {
+			return fmt.Errorf("invalid port_range: %s", s)
+		}
+		if start != "" {
+			if _, err := parsePort(start); err != nil {
+				return fmt.Errorf("invalid port_range: %s", s)
+			}
+		}
+		if end != "" {
+			if _, err := parsePort(end); err != nil {
+				return fmt.Errorf("invalid port_range: %s", s)
+			}
+		}
+	case "protocol":
+		if !sniffProtocols[s] {
+			return fmt.Errorf("unsupported protocol: %s", s)
+		}
+	case "network":
+		if s != "tcp" && s != "udp" {
+			return fmt.Errorf("unsupported network: %s", s)
+		}
+	}
+	return nil
+}
+
+// routeRuleNeedsSniff reports whether match inspects data only known after sniffing.
+// Rule-sets are assumed to contain domain rules.
+func routeRuleNeedsSniff(match map[string]any) bool {
+	for _, key := range []string{"protocol", "domain", "domain_suffix", "domain_keyword", "domain_regex", "rule_set"} {
+		if _, ok := match[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// routeRuleToSingBox converts db.RouteRule to a sing-box rule.
+func routeRuleToSingBox(rule *db.RouteRule) map[string]any {
+	out := map[string]any{}
+	if len(rule.Match) > 0 {
+		_ = json.Unmarshal(rule.Match, &out)
+		if out == nil {
+			out = map[string]any{}
+		}
+	}
+	return withRouteAction(out, rule.Outbound)
+}
+
+// withRouteAction sets the action routing match to outbound. The built-in block
+// outbound uses the reject action.
+func withRouteAction(match map[string]any, outbound string) map[string]any {
+	if outbound == OutboundBlock {
+		match["action"] = "reject"
+	} else {
+		match["action"] = "route"
+		match["outbound"] = outbound
+	}
+	return match
+}
+
+// routeToSingBox builds the route block from per-user policies, enabled db.RouteRule
+// rows, rule-set definitions and the route_final setting. User policies come first so
+// a bound user always leaves via their outbound. A sniff action is prepended when any
+// rule matches on domain, protocol or a rule-set so those fields are populated.
+func routeToSingBox(conn db.Conn) (map[string]any, error) {
+	policies, err := userPolicyRules(conn)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := conn.ListRouteRules()
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]any, 0, len(policies)+len(rows)+1)
+	sniff := false
+	for _, rule := range policies {
+		if routeRuleNeedsSniff(rule) {
+			sniff = true
+		}
+		rules = append(rules, rule)
+	}
+	for i := range rows {
+		if rows[i].Disabled {
+			continue
+		}
+		rule := routeRuleToSingBox(&rows[i])
+		if routeRuleNeedsSniff(rule) {
+			sniff = true
+		}
+		rules = append(rules, rule)
+	}
+	if sniff {
+		rules = append([]any{map[string]any{"action": "sniff"}}, rules...)
+	}
+
+	route := map[string]any{"rules": rules}
+	ruleSets, err := ruleSetsToSingBox(conn)
+	if err != nil {
+		return nil, err
+	}
+	if len(ruleSets) > 0 {
+		route["rule_set"] = ruleSets
+	}
+	final, err := conn.GetSetting(db.SettingRouteFinal)
+	if err != nil {
+		return nil, err
+	}
+	if final != "" {
+		route["final"] = final
+	}
+	return route, nil
+}
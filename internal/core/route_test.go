@@ -0,0 +1,155 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/s-ui/s-ui/internal/db"
+	"gorm.io/datatypes"
+)
+
+func TestValidateRouteMatch(t *testing.T) {
+	cases := []struct {
+		name    string
+		match   string
+		wantErr bool
+	}{
+		{name: "empty", match: `{}`, wantErr: true},
+		{name: "domain_suffix", match: `{"domain_suffix":["netflix.com","nflxvideo.net"]}`, wantErr: false},
+		{name: "scalar_value", match: `{"domain":"openai.com"}`, wantErr: true},
+		{name: "unknown_field", match: `{"geosite":["cn"]}`, wantErr: true},
+		{name: "bad_regex", match: `{"domain_regex":["("]}`, wantErr: true},
+		{name: "ip_cidr", match: `{"ip_cidr":["10.0.0.0/8","1.1.1.1","2001:db8::/32"]}`, wantErr: false},
+		{name: "bad_ip_cidr", match: `{"ip_cidr":["10.0.0.0/33"]}`, wantErr: true},
+		{name: "port", match: `{"port":[80,443]}`, wantErr: false},
+		{name: "bad_port", match: `{"port":[70000]}`, wantErr: true},
+		{name: "port_range", match: `{"port_range":["1000:2000",":3000"]}`, wantErr: false},
+		{name: "bad_port_range", match: `{"port_range":["1000-2000"]}`, wantErr: true},
+		{name: "protocol", match: `{"protocol":["bittorrent"]}`, wantErr: false},
+		{name: "bad_protocol", match: `{"protocol":["smtp"]}`, wantErr: true},
+		{name: "bad_network", match: `{"network":["icmp"]}`, wantErr: true},
+		{name: "inbound_and_user", match: `{"inbound":["vless-in"],"auth_user":["alice"]}`, wantErr: false},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var match map[string]any
+			if err := json.Unmarshal([]byte(tc.match), &match); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			err := ValidateRouteMatch(match)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ValidateRouteMatch() err = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerateRoute(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	if err := db.CreateOutbound(&db.Outbound{Tag: "warp", Type: "direct"}); err != nil {
+		t.Fatalf("CreateOutbound: %v", err)
+	}
+	for _, rule := range []*db.RouteRule{
+		{Match: datatypes.JSON(`{"protocol":["bittorrent"]}`), Outbound: OutboundBlock},
+		{Match: datatypes.JSON(`{"domain_suffix":["openai.com"]}`), Outbound: "warp"},
+		{Match: datatypes.JSON(`{"network":["udp"]}`), Outbound: "warp", Disabled: true},
+	} {
+		if err := db.CreateRouteRule(rule); err != nil {
+			t.Fatalf("CreateRouteRule: %v", err)
+		}
+	}
+	if err := db.SetSetting(db.SettingRouteFinal, "warp"); err != nil {
+		t.Fatalf("SetSetting: %v", err)
+	}
+
+	gen := &ConfigGenerator{}
+	out, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	var cfg struct {
+		Route struct {
+			Rules []map[string]any `json:"rules"`
+			Final string           `json:"final"`
+		} `json:"route"`
+	}
+	if err := json.Unmarshal(out, &cfg); err != nil {
+		t.Fatalf("unmarshal config: %v", err)
+	}
+
+	rules := cfg.Route.Rules
+	if len(rules) != 3 {
+		t.Fatalf("rules = %v, want sniff + 2 enabled rules", rules)
+	}
+	if rules[0]["action"] != "sniff" {
+		t.Fatalf("rules[0] = %v, want sniff action", rules[0])
+	}
+	if rules[1]["action"] != "reject" {
+		t.Fatalf("rules[1] = %v, want reject for block outbound", rules[1])
+	}
+	if rules[2]["action"] != "route" || rules[2]["outbound"] != "warp" {
+		t.Fatalf("rules[2] = %v, want route to warp", rules[2])
+	}
+	if cfg.Route.Final != "warp" {
+		t.Fatalf("final = %q, want warp", cfg.Route.Final)
+	}
+}
+
+func TestGenerateUserPolicies(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	for _, tag := range []string{"residential", "warp"} {
+		if err := db.CreateOutbound(&db.Outbound{Tag: tag, Type: "direct"}); err != nil {
+			t.Fatalf("CreateOutbound: %v", err)
+		}
+	}
+	profile := &db.RoutingProfile{
+		Name:     "streaming",
+		Rules:    datatypes.JSON(`[{"match":{"domain_suffix":["netflix.com"]},"outbound":"residential"}]`),
+		Outbound: "warp",
+	}
+	if err := db.CreateRoutingProfile(profile); err != nil {
+		t.Fatalf("CreateRoutingProfile: %v", err)
+	}
+	for _, u := range []*db.User{
+		{Name: "alice", Outbound: "residential"},
+		{Name: "bob", RoutingProfileID: &profile.ID},
+		{Name: "carol", Outbound: "residential", RoutingProfileID: &profile.ID},
+		{Name: "dave"},
+	} {
+		if err := db.CreateUser(u); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+	}
+	if err := db.CreateRouteRule(&db.RouteRule{Match: datatypes.JSON(`{"network":["udp"]}`), Outbound: OutboundBlock}); err != nil {
+		t.Fatalf("CreateRouteRule: %v", err)
+	}
+
+	gen := &ConfigGenerator{}
+	out, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	var cfg struct {
+		Route struct {
+			Rules []map[string]any `json:"rules"`
+		} `json:"route"`
+	}
+	if err := json.Unmarshal(out, &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	rules, _ := json.Marshal(cfg.Route.Rules)
+	want := `[{"action":"sniff"},` +
+		`{"action":"route","auth_user":["alice","carol"],"outbound":"residential"},` +
+		`{"action":"route","auth_user":["bob"],"domain_suffix":["netflix.com"],"outbound":"residential"},` +
+		`{"action":"route","auth_user":["bob"],"outbound":"warp"},` +
+		`{"action":"reject","network":["udp"]}]`
+	if string(rules) != want {
+		t.Fatalf("route.rules = %s\nwant %s", rules, want)
+	}
+}
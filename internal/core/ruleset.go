@@ -0,0 +1,254 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/s-ui/s-ui/internal/db"
+)
+
+// Rule-set formats as named by sing-box.
+const (
+	RuleSetFormatBinary = "binary"
+	RuleSetFormatSource = "source"
+)
+
+// RuleSetMaxSize caps uploaded and fetched rule-set files.
+const RuleSetMaxSize = 64 << 20
+
+// srsMagic prefixes compiled sing-box rule-set (.srs) files.
+var srsMagic = []byte("SRS")
+
+// RuleSetDefaultUpdateInterval is how often remote rule-sets without update_interval
+// are refreshed, matching sing-box's default.
+const RuleSetDefaultUpdateInterval = 24 * time.Hour
+
+// RuleSetDir returns the directory holding uploaded and downloaded rule-set files.
+func RuleSetDir(dataDir string) string {
+	return filepath.Join(dataDir, "rule-sets")
+}
+
+// RuleSetFormatFromName infers the format from a .srs or .json file name or URL path.
+func RuleSetFormatFromName(name string) string {
+	if i := strings.IndexAny(name, "?#"); i >= 0 {
+		name = name[:i]
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".srs":
+		return RuleSetFormatBinary
+	case ".json":
+		return RuleSetFormatSource
+	}
+	return ""
+}
+
+// RuleSetFileExt returns the file extension used when storing a rule-set of format.
+func RuleSetFileExt(format string) string {
+	if format == RuleSetFormatSource {
+		return ".json"
+	}
+	return ".srs"
+}
+
+// ValidateRuleSetContent checks that data looks like a rule-set of format:
+// binary files start with the SRS magic, source files are JSON with a rules list.
+func ValidateRuleSetContent(format string, data []byte) error {
+	if len(data) == 0 {
+		return errors.New("rule-set is empty")
+	}
+	switch format {
+	case RuleSetFormatBinary:
+		if !bytes.HasPrefix(data, srsMagic) {
+			return errors.New("rule-set is not a compiled .srs file")
+		}
+	case RuleSetFormatSource:
+		var src struct {
+			Version int   `json:"version"`
+			Rules   []any `json:"rules"`
+		}
+		if err := json.Unmarshal(data, &src); err != nil {
+			return fmt.Errorf("rule-set is not valid JSON: %w", err)
+		}
+		if src.Version == 0 || src.Rules == nil {
+			return errors.New("rule-set JSON requires version and rules")
+		}
+	default:
+		return fmt.Errorf("unsupported rule-set format: %s", format)
+	}
+	return nil
+}
+
+// ValidateUpdateInterval checks a sing-box duration such as "12h" or "1d".
+func ValidateUpdateInterval(s string) error {
+	_, err := ParseUpdateInterval(s)
+	return err
+}
+
+// ParseUpdateInterval parses a sing-box duration such as "12h" or "1d".
+func ParseUpdateInterval(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+		return 0, fmt.Errorf("invalid update_interval: %s", s)
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid update_interval: %s", s)
+	}
+	return d, nil
+}
+
+// RuleSetRefreshDue reports whether remote rule-set rs has not been downloaded yet or
+// its update_interval has passed since the last download.
+func RuleSetRefreshDue(rs *db.RuleSet, now time.Time) bool {
+	if rs.Type != "remote" {
+		return false
+	}
+	if rs.LastUpdatedAt == nil {
+		return true
+	}
+	interval, err := ParseUpdateInterval(rs.UpdateInterval)
+	if err != nil {
+		interval = RuleSetDefaultUpdateInterval
+	}
+	return !now.Before(rs.LastUpdatedAt.Add(interval))
+}
+
+// HashRuleSet returns the hex SHA-256 of rule-set content.
+func HashRuleSet(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ruleSetHTTPClient is used for remote refreshes; tests may swap it.
+var ruleSetHTTPClient = &http.Client{Timeout: 60 * time.Second}
+
+// fetchRuleSet downloads url and validates it against format.
+func fetchRuleSet(url, format string) ([]byte, error) {
+	resp, err := ruleSetHTTPClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download rule-set: HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, RuleSetMaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > RuleSetMaxSize {
+		return nil, errors.New("rule-set exceeds 64 MiB")
+	}
+	if err := ValidateRuleSetContent(format, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// RefreshRuleSet re-reads a rule-set and records its hash and update time.
+// Remote rule-sets are downloaded by the panel and stored under dataDir as Path, which
+// the generated config loads, so the recorded hash is what sing-box uses; local ones
+// are re-hashed from Path. On failure LastError is recorded and returned. changed
+// reports a new hash or a newly stored download.
+func RefreshRuleSet(rs *db.RuleSet, dataDir string) (changed bool, err error) {
+	var data []byte
+	prevPath := rs.Path
+	if rs.Type == "remote" {
+		data, err = fetchRuleSet(rs.URL, rs.Format)
+		if err == nil {
+			err = storeRuleSetDownload(rs, dataDir, data)
+		}
+	} else {
+		data, err = os.ReadFile(rs.Path)
+		if err == nil {
+			err = ValidateRuleSetContent(rs.Format, data)
+		}
+	}
+	if err != nil {
+		rs.LastError = err.Error()
+		if saveErr := db.UpdateRuleSetRefresh(rs); saveErr != nil {
+			return false, saveErr
+		}
+		return false, err
+	}
+	sum := HashRuleSet(data)
+	changed = sum != rs.SHA256 || rs.Path != prevPath
+	now := time.Now()
+	rs.SHA256 = sum
+	rs.LastUpdatedAt = &now
+	rs.LastError = ""
+	return changed, db.UpdateRuleSetRefresh(rs)
+}
+
+// storeRuleSetDownload writes downloaded rule-set content to DataDir/rule-sets/<tag>.<ext>
+// and points rs.Path at it, removing a previous download stored under another name.
+func storeRuleSetDownload(rs *db.RuleSet, dataDir string, data []byte) error {
+	dir := RuleSetDir(dataDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, rs.Tag+RuleSetFileExt(rs.Format))
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if rs.Path != "" && rs.Path != path {
+		os.Remove(rs.Path)
+	}
+	rs.Path = path
+	return nil
+}
+
+// ruleSetToSingBox converts db.RuleSet to a sing-box rule_set definition. A remote
+// rule-set the panel has downloaded is emitted as a local one reading that copy;
+// until then sing-box downloads it itself, through download_detour when set.
+func ruleSetToSingBox(rs *db.RuleSet) map[string]any {
+	out := map[string]any{
+		"type":   "local",
+		"tag":    rs.Tag,
+		"format": rs.Format,
+	}
+	if rs.Type == "remote" && rs.Path == "" {
+		out["type"] = "remote"
+		out["url"] = rs.URL
+		if rs.DownloadDetour != "" {
+			out["download_detour"] = rs.DownloadDetour
+		}
+		if rs.UpdateInterval != "" {
+			out["update_interval"] = rs.UpdateInterval
+		}
+	} else {
+		out["path"] = rs.Path
+	}
+	return out
+}
+
+// ruleSetsToSingBox returns all rule-set definitions for route.rule_set.
+func ruleSetsToSingBox(conn db.Conn) ([]any, error) {
+	rows, err := conn.ListRuleSets()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]any, 0, len(rows))
+	for i := range rows {
+		out = append(out, ruleSetToSingBox(&rows[i]))
+	}
+	return out, nil
+}
@@ -0,0 +1,100 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/s-ui/s-ui/internal/db"
+)
+
+func TestValidateRuleSetContent(t *testing.T) {
+	cases := []struct {
+		name    string
+		format  string
+		data    string
+		wantErr bool
+	}{
+		{name: "binary", format: RuleSetFormatBinary, data: "SRS\x01payload", wantErr: false},
+		{name: "binary_bad_magic", format: RuleSetFormatBinary, data: "not-srs", wantErr: true},
+		{name: "source", format: RuleSetFormatSource, data: `{"version":2,"rules":[{"domain_suffix":["ads.example"]}]}`, wantErr: false},
+		{name: "source_no_rules", format: RuleSetFormatSource, data: `{"version":2}`, wantErr: true},
+		{name: "source_invalid_json", format: RuleSetFormatSource, data: `{`, wantErr: true},
+		{name: "empty", format: RuleSetFormatBinary, data: "", wantErr: true},
+		{name: "unknown_format", format: "yaml", data: "x", wantErr: true},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateRuleSetContent(tc.format, []byte(tc.data))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ValidateRuleSetContent() err = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestRefreshRemoteRuleSet(t *testing.T) {
+	if err := db.Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	body := `{"version":2,"rules":[{"domain_suffix":["ads.example"]}]}`
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	rs := &db.RuleSet{Tag: "ads", Type: "remote", Format: RuleSetFormatSource, URL: srv.URL + "/ads.json", UpdateInterval: "1d"}
+	if err := db.CreateRuleSet(rs); err != nil {
+		t.Fatalf("CreateRuleSet: %v", err)
+	}
+
+	if def := ruleSetToSingBox(rs); def["type"] != "remote" || def["url"] != rs.URL || def["update_interval"] != "1d" {
+		t.Fatalf("rule_set definition before download = %v", def)
+	}
+	if !RuleSetRefreshDue(rs, time.Now()) {
+		t.Fatal("rule-set never downloaded should be due")
+	}
+
+	dataDir := t.TempDir()
+	changed, err := RefreshRuleSet(rs, dataDir)
+	if err != nil || !changed {
+		t.Fatalf("first refresh = (%v, %v), want changed", changed, err)
+	}
+	if rs.SHA256 != HashRuleSet([]byte(body)) || rs.LastUpdatedAt == nil {
+		t.Fatalf("rule-set after refresh = %+v, want hash and last update recorded", rs)
+	}
+	if got, err := os.ReadFile(rs.Path); err != nil || string(got) != body {
+		t.Fatalf("downloaded file %s = %q, %v", rs.Path, got, err)
+	}
+	if RuleSetRefreshDue(rs, time.Now()) || !RuleSetRefreshDue(rs, time.Now().Add(25*time.Hour)) {
+		t.Fatal("refresh due should follow update_interval")
+	}
+
+	changed, err = RefreshRuleSet(rs, dataDir)
+	if err != nil || changed {
+		t.Fatalf("second refresh = (%v, %v), want unchanged", changed, err)
+	}
+
+	status = http.StatusNotFound
+	if _, err := RefreshRuleSet(rs, dataDir); err == nil {
+		t.Fatal("expected error for HTTP 404")
+	}
+	stored, err := db.GetRuleSetByID(rs.ID)
+	if err != nil {
+		t.Fatalf("GetRuleSetByID: %v", err)
+	}
+	if stored.LastError == "" || stored.SHA256 != HashRuleSet([]byte(body)) {
+		t.Fatalf("stored = %+v, want last_error set and previous hash kept", stored)
+	}
+
+	// sing-box keeps loading the last download.
+	def := ruleSetToSingBox(stored)
+	if def["type"] != "local" || def["path"] != rs.Path || def["url"] != nil {
+		t.Fatalf("rule_set definition = %v", def)
+	}
+}
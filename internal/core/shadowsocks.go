@@ -0,0 +1,81 @@
+package core
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/s-ui/s-ui/internal/db"
+)
+
+// shadowsocks2022KeySizes maps multi-user capable 2022-blake3 methods to PSK length in bytes.
+// 2022-blake3-chacha20-poly1305 is omitted: sing-box does not support it with multiple users.
+var shadowsocks2022KeySizes = map[string]int{
+	"2022-blake3-aes-128-gcm": 16,
+	"2022-blake3-aes-256-gcm": 32,
+}
+
+// DefaultShadowsocksMethod is used when an inbound omits config_json.method.
+const DefaultShadowsocksMethod = "2022-blake3-aes-128-gcm"
+
+// ShadowsocksKeySize returns the PSK length for a supported 2022-blake3 method.
+func ShadowsocksKeySize(method string) (int, bool) {
+	n, ok := shadowsocks2022KeySizes[method]
+	return n, ok
+}
+
+// GenerateShadowsocksPSK returns a random base64 PSK sized for method.
+func GenerateShadowsocksPSK(method string) (string, error) {
+	n, ok := ShadowsocksKeySize(method)
+	if !ok {
+		return "", fmt.Errorf("unsupported shadowsocks method: %s", method)
+	}
+	key := make([]byte, n)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ValidateShadowsocksPSK checks that psk is base64 and matches the key size of method.
+func ValidateShadowsocksPSK(method, psk string) error {
+	n, ok := ShadowsocksKeySize(method)
+	if !ok {
+		return fmt.Errorf("unsupported shadowsocks method: %s", method)
+	}
+	key, err := base64.StdEncoding.DecodeString(psk)
+	if err != nil {
+		return fmt.Errorf("shadowsocks password must be base64: %w", err)
+	}
+	if len(key) != n {
+		return fmt.Errorf("shadowsocks password must be %d bytes for %s, got %d", n, method, len(key))
+	}
+	return nil
+}
+
+// shadowsocksUserKey returns User.ShadowsocksKey sized for method: as-is when it has the
+// right length, its leading bytes when longer (generated keys are 32 bytes), and a key
+// derived from it when an imported key is too short for method.
+func shadowsocksUserKey(u *db.User, method string) string {
+	n, ok := ShadowsocksKeySize(method)
+	if !ok {
+		n = 16
+	}
+	key, err := base64.StdEncoding.DecodeString(u.ShadowsocksKey)
+	if err == nil && len(key) >= n {
+		return base64.StdEncoding.EncodeToString(key[:n])
+	}
+	sum := sha256.Sum256([]byte("shadowsocks-2022:" + u.ShadowsocksKey))
+	return base64.StdEncoding.EncodeToString(sum[:n])
+}
+
+// shadowsocksSettings returns method and server PSK from inbound config_json.
+func shadowsocksSettings(cfg map[string]any) (method, psk string) {
+	method, _ = cfg["method"].(string)
+	if method == "" {
+		method = DefaultShadowsocksMethod
+	}
+	psk, _ = cfg["password"].(string)
+	return method, psk
+}
@@ -0,0 +1,107 @@
+package core
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/s-ui/s-ui/internal/db"
+)
+
+// shadowTLSDetourSuffix names the internal shadowsocks inbound a shadowtls inbound detours to.
+const shadowTLSDetourSuffix = "-shadowtls-ss"
+
+// ShadowTLSDetourTag returns the tag of the internal shadowsocks inbound behind a shadowtls inbound.
+func ShadowTLSDetourTag(tag string) string {
+	return tag + shadowTLSDetourSuffix
+}
+
+// IsReservedInboundTag reports whether tag collides with generator-managed internal inbounds.
+func IsReservedInboundTag(tag string) bool {
+	return strings.HasSuffix(tag, shadowTLSDetourSuffix)
+}
+
+// shadowTLSInfo holds handshake settings for a ShadowTLS v3 inbound.
+type shadowTLSInfo struct {
+	Server     string
+	ServerPort uint
+	StrictMode bool
+}
+
+// inboundShadowTLS reads handshake server settings from config_json.
+// handshake.server_port defaults to 443.
+func inboundShadowTLS(ib *db.Inbound) shadowTLSInfo {
+	cfg := inboundConfigMap(ib)
+	info := shadowTLSInfo{ServerPort: 443}
+	if hs, ok := cfg["handshake"].(map[string]any); ok {
+		info.Server, _ = hs["server"].(string)
+		if p, ok := toUint(hs["server_port"]); ok && p > 0 {
+			info.ServerPort = p
+		}
+	}
+	info.StrictMode, _ = cfg["strict_mode"].(bool)
+	return info
+}
+
+// shadowTLSToSingBox produces the public ShadowTLS v3 inbound and the internal
+// shadowsocks inbound it detours to. ShadowTLS users authenticate with User.Password;
+// the shadowsocks layer reuses the 2022 method and PSK from config_json.
+func (g *ConfigGenerator) shadowTLSToSingBox(ib *db.Inbound) []map[string]any {
+	info := inboundShadowTLS(ib)
+	method, psk := shadowsocksSettings(inboundConfigMap(ib))
+	users, _ := g.DB.GetUsersForInbound(ib.ID)
+	stlsUsers := make([]any, 0, len(users))
+	ssUsers := make([]any, 0, len(users))
+	for _, u := range users {
+		stlsUsers = append(stlsUsers, map[string]any{
+			"name":     u.Name,
+			"password": u.Password,
+		})
+		ssUsers = append(ssUsers, map[string]any{
+			"name":     u.Name,
+			"password": shadowsocksUserKey(&u, method),
+		})
+	}
+
+	detour := ShadowTLSDetourTag(ib.Tag)
+	return []map[string]any{
+		{
+			"type":        "shadowtls",
+			"tag":         ib.Tag,
+			"listen":      ib.Listen,
+			"listen_port": ib.ListenPort,
+			"version":     3,
+			"users":       stlsUsers,
+			"handshake": map[string]any{
+				"server":      info.Server,
+				"server_port": info.ServerPort,
+			},
+			"strict_mode": info.StrictMode,
+			"detour":      detour,
+		},
+		{
+			"type":     "shadowsocks",
+			"tag":      detour,
+			"listen":   "127.0.0.1",
+			"network":  "tcp",
+			"method":   method,
+			"password": psk,
+			"users":    ssUsers,
+		},
+	}
+}
+
+// shadowTLSLink builds a SIP002 ss:// link carrying the shadow-tls plugin options.
+func shadowTLSLink(u *db.User, ib *db.Inbound, host string) string {
+	info := inboundShadowTLS(ib)
+	method, password := shadowsocksClientPassword(u, ib)
+	plugin := strings.Join([]string{
+		"shadow-tls",
+		"host=" + info.Server,
+		"password=" + url.QueryEscape(u.Password),
+		"version=3",
+	}, ";")
+	params := url.Values{"plugin": {plugin}}
+	return fmt.Sprintf("ss://%s:%s@%s:%d/?%s#%s",
+		method, url.QueryEscape(password), host, ib.ListenPort, params.Encode(), url.PathEscape(ib.Tag))
+}
@@ -31,6 +31,7 @@ func BuildUserinfoHeader(u *db.User) string {
 
 // extractHostFromInbound extracts host from inbound ConfigJSON for share links.
 // Uses tls.server_name; falls back to config["host"]. Returns empty string if absent.
+// REALITY inbounds skip tls.server_name since it names the camouflage site, not this server.
 func extractHostFromInbound(ib *db.Inbound) string {
 	if len(ib.ConfigJSON) == 0 {
 		return ""
@@ -41,8 +42,11 @@ func extractHostFromInbound(ib *db.Inbound) string {
 	}
 	if tls, ok := cfg["tls"]; ok && tls != nil {
 		if t, ok := tls.(map[string]any); ok {
-			if s, ok := t["server_name"].(string); ok && s != "" {
-				return s
+			// REALITY server_name is the camouflage site, not this server.
+			if !inboundReality(ib).Enabled {
+				if s, ok := t["server_name"].(string); ok && s != "" {
+					return s
+				}
 			}
 		}
 	}
@@ -91,30 +95,188 @@ func GetNodeLinks(u *db.User, fallbackHost string) []NodeLink {
 		}
 		if ib.Protocol == "vless" {
 			tlsEnabled := isTLSEnabled(&ib)
-			params := url.Values{
-				"type": {"tcp"},
-			}
-			if tlsEnabled {
+			reality := inboundReality(&ib)
+			params := transportLinkParams(inboundTransport(&ib))
+			if reality.Enabled {
+				params.Set("security", "reality")
+				params.Set("sni", reality.ServerName)
+				params.Set("pbk", reality.PublicKey)
+				params.Set("sid", reality.ShortID)
+				params.Set("fp", reality.Fingerprint)
+			} else if tlsEnabled {
 				params.Set("security", "tls")
 				params.Set("sni", host)
-				params.Set("flow", "xtls-rprx-vision")
 			} else {
 				params.Set("security", "none")
 			}
+			if flow := vlessFlow(&ib); flow != "" {
+				params.Set("flow", flow)
+			}
 			raw := fmt.Sprintf("vless://%s@%s:%d?%s#%s",
 				u.UUID, host, ib.ListenPort, params.Encode(), url.PathEscape(ib.Tag))
 			links = append(links, NodeLink{Name: ib.Tag, Link: raw})
 		} else if ib.Protocol == "hysteria2" {
-			raw := fmt.Sprintf("hysteria2://%s@%s:%d/?sni=%s#%s",
-				url.PathEscape(u.Password), host, ib.ListenPort, host, url.PathEscape(ib.Tag))
-			links = append(links, NodeLink{Name: ib.Tag, Link: raw})
+			links = append(links, NodeLink{Name: ib.Tag, Link: hysteria2Link(u, &ib, host)})
+		} else if ib.Protocol == "trojan" {
+			links = append(links, NodeLink{Name: ib.Tag, Link: trojanLink(u, &ib, host)})
+		} else if ib.Protocol == "shadowsocks" {
+			links = append(links, NodeLink{Name: ib.Tag, Link: shadowsocksLink(u, &ib, host)})
+		} else if ib.Protocol == "shadowtls" {
+			links = append(links, NodeLink{Name: ib.Tag, Link: shadowTLSLink(u, &ib, host)})
+		} else if ib.Protocol == "vmess" {
+			links = append(links, NodeLink{Name: ib.Tag, Link: vmessLink(u, &ib, host)})
+		} else if ib.Protocol == "tuic" {
+			links = append(links, NodeLink{Name: ib.Tag, Link: tuicLink(u, &ib, host)})
+		} else if ib.Protocol == "anytls" {
+			links = append(links, NodeLink{Name: ib.Tag, Link: anyTLSLink(u, &ib, host)})
+		} else if ib.Protocol == "naive" {
+			links = append(links, NodeLink{Name: ib.Tag, Link: naiveLink(u, &ib, host)})
 		}
 	}
 	return links
 }
 
+// hysteria2Link builds a hysteria2:// link with obfs and port hopping (mport) when configured.
+func hysteria2Link(u *db.User, ib *db.Inbound, host string) string {
+	hy := inboundHysteria2(ib)
+	params := url.Values{
+		"sni": {host},
+	}
+	if hy.ObfsType != "" {
+		params.Set("obfs", hy.ObfsType)
+		params.Set("obfs-password", hy.ObfsPassword)
+	}
+	if hy.HopPorts != "" {
+		params.Set("mport", hy.HopPorts)
+	}
+	return fmt.Sprintf("hysteria2://%s@%s:%d/?%s#%s",
+		url.PathEscape(u.Password), host, ib.ListenPort, params.Encode(), url.PathEscape(ib.Tag))
+}
+
+// transportLinkParams returns the type/path/host/serviceName query parameters used by
+// vless:// and trojan:// links. sing-box "http" (HTTP/2) is advertised as type=http.
+func transportLinkParams(tr transportInfo) url.Values {
+	params := url.Values{
+		"type": {tr.Type},
+	}
+	switch tr.Type {
+	case "ws", "httpupgrade", "http":
+		if tr.Path != "" {
+			params.Set("path", tr.Path)
+		}
+		if tr.Host != "" {
+			params.Set("host", tr.Host)
+		}
+	case "grpc":
+		params.Set("serviceName", tr.ServiceName)
+		params.Set("mode", "gun")
+	}
+	return params
+}
+
+// trojanLink builds a trojan:// share link authenticated by User.Password.
+func trojanLink(u *db.User, ib *db.Inbound, host string) string {
+	params := transportLinkParams(inboundTransport(ib))
+	if isTLSEnabled(ib) {
+		params.Set("security", "tls")
+		params.Set("sni", host)
+	} else {
+		params.Set("security", "none")
+	}
+	return fmt.Sprintf("trojan://%s@%s:%d?%s#%s",
+		url.PathEscape(u.Password), host, ib.ListenPort, params.Encode(), url.PathEscape(ib.Tag))
+}
+
+// anyTLSLink builds an anytls:// share link authenticated by User.Password.
+func anyTLSLink(u *db.User, ib *db.Inbound, host string) string {
+	params := url.Values{"sni": {host}}
+	return fmt.Sprintf("anytls://%s@%s:%d/?%s#%s",
+		url.User(u.Password).String(), host, ib.ListenPort, params.Encode(), url.PathEscape(ib.Tag))
+}
+
+// naiveLink builds a naive+https:// share link with User.Name and User.Password as credentials.
+func naiveLink(u *db.User, ib *db.Inbound, host string) string {
+	cred := url.UserPassword(u.Name, u.Password)
+	return fmt.Sprintf("naive+https://%s@%s:%d#%s",
+		cred.String(), host, ib.ListenPort, url.PathEscape(ib.Tag))
+}
+
+// shadowsocksClientPassword returns the "serverPSK:userPSK" pair used by 2022 multi-user clients.
+func shadowsocksClientPassword(u *db.User, ib *db.Inbound) (method, password string) {
+	method, psk := shadowsocksSettings(inboundConfigMap(ib))
+	return method, psk + ":" + shadowsocksUserKey(u, method)
+}
+
+// shadowsocksLink builds a SIP002 ss:// link. 2022 methods use percent-encoded
+// userinfo instead of base64, as required by SIP002.
+func shadowsocksLink(u *db.User, ib *db.Inbound, host string) string {
+	method, password := shadowsocksClientPassword(u, ib)
+	return fmt.Sprintf("ss://%s:%s@%s:%d#%s",
+		method, url.QueryEscape(password), host, ib.ListenPort, url.PathEscape(ib.Tag))
+}
+
+// vmessShare is the v2rayN "v2" JSON payload carried in vmess:// links.
+type vmessShare struct {
+	V    string `json:"v"`
+	PS   string `json:"ps"`
+	Add  string `json:"add"`
+	Port string `json:"port"`
+	ID   string `json:"id"`
+	Aid  string `json:"aid"`
+	Scy  string `json:"scy"`
+	Net  string `json:"net"`
+	Type string `json:"type"`
+	Host string `json:"host"`
+	Path string `json:"path"`
+	TLS  string `json:"tls"`
+	SNI  string `json:"sni"`
+}
+
+// vmessLink builds a base64 JSON vmess:// link; net/host/path follow config_json.transport.
+func vmessLink(u *db.User, ib *db.Inbound, host string) string {
+	tr := inboundTransport(ib)
+	share := vmessShare{
+		V:    "2",
+		PS:   ib.Tag,
+		Add:  host,
+		Port: fmt.Sprintf("%d", ib.ListenPort),
+		ID:   u.UUID,
+		Aid:  "0",
+		Scy:  "auto",
+		Net:  tr.Type,
+		Type: "none",
+		Host: tr.Host,
+		Path: tr.Path,
+	}
+	switch tr.Type {
+	case "grpc":
+		share.Path = tr.ServiceName
+	case "http":
+		share.Net = "h2"
+	}
+	if isTLSEnabled(ib) {
+		share.TLS = "tls"
+		share.SNI = host
+	}
+	b, _ := json.Marshal(share)
+	return "vmess://" + base64.StdEncoding.EncodeToString(b)
+}
+
+// tuicLink builds a tuic:// (v5) link with uuid:password credentials.
+func tuicLink(u *db.User, ib *db.Inbound, host string) string {
+	cc, alpn := tuicSettings(inboundConfigMap(ib))
+	params := url.Values{
+		"congestion_control": {cc},
+		"alpn":               {strings.Join(alpn, ",")},
+		"sni":                {host},
+		"udp_relay_mode":     {"native"},
+	}
+	return fmt.Sprintf("tuic://%s:%s@%s:%d?%s#%s",
+		u.UUID, url.PathEscape(u.Password), host, ib.ListenPort, params.Encode(), url.PathEscape(ib.Tag))
+}
+
 // GenerateBase64 returns Base64-encoded subscription body (V2Ray format).
-// For each inbound: VLESS or Hysteria2 links; join with newline; Base64 encode.
+// For each inbound: VLESS, VMess, Hysteria2, TUIC, Trojan, Shadowsocks, ShadowTLS, AnyTLS or Naive links; join with newline; Base64 encode.
 func GenerateBase64(u *db.User, fallbackHost string) ([]byte, error) {
 	nodeLinks := GetNodeLinks(u, fallbackHost)
 	if len(nodeLinks) == 0 {
@@ -130,20 +292,101 @@ func GenerateBase64(u *db.User, fallbackHost string) ([]byte, error) {
 
 // clashProxy represents a ClashMeta proxy entry.
 type clashProxy struct {
-	Name      string `yaml:"name"`
-	Type      string `yaml:"type"`
-	Server    string `yaml:"server"`
-	Port      uint   `yaml:"port"`
-	UUID      string `yaml:"uuid,omitempty"`
-	Network   string `yaml:"network,omitempty"`
-	Servername string `yaml:"servername,omitempty"`
-	Flow      string `yaml:"flow,omitempty"`
-	TLS       bool   `yaml:"tls,omitempty"`
-	Password  string `yaml:"password,omitempty"`
-	SNI       string `yaml:"sni,omitempty"`
+	Name       string         `yaml:"name"`
+	Type       string         `yaml:"type"`
+	Server     string         `yaml:"server"`
+	Port       uint           `yaml:"port"`
+	UUID       string         `yaml:"uuid,omitempty"`
+	Network    string         `yaml:"network,omitempty"`
+	Servername string         `yaml:"servername,omitempty"`
+	Flow       string         `yaml:"flow,omitempty"`
+	TLS        bool           `yaml:"tls,omitempty"`
+	Password   string         `yaml:"password,omitempty"`
+	SNI        string         `yaml:"sni,omitempty"`
+	Cipher     string         `yaml:"cipher,omitempty"`
+	UDP        bool           `yaml:"udp,omitempty"`
+	AlterID    *int           `yaml:"alterId,omitempty"`
+	WSOpts     *clashWSOpts   `yaml:"ws-opts,omitempty"`
+	H2Opts     *clashH2Opts   `yaml:"h2-opts,omitempty"`
+	GRPCOpts   *clashGRPCOpts `yaml:"grpc-opts,omitempty"`
+	ALPN       []string       `yaml:"alpn,omitempty"`
+	// TUIC
+	CongestionController string `yaml:"congestion-controller,omitempty"`
+	UDPRelayMode         string `yaml:"udp-relay-mode,omitempty"`
+	// Hysteria2
+	Obfs         string `yaml:"obfs,omitempty"`
+	ObfsPassword string `yaml:"obfs-password,omitempty"`
+	Ports        string `yaml:"ports,omitempty"`
+	Up           string `yaml:"up,omitempty"`
+	Down         string `yaml:"down,omitempty"`
+	// Shadowsocks plugins
+	Plugin     string           `yaml:"plugin,omitempty"`
+	PluginOpts *clashPluginOpts `yaml:"plugin-opts,omitempty"`
+	// REALITY
+	ClientFingerprint string            `yaml:"client-fingerprint,omitempty"`
+	RealityOpts       *clashRealityOpts `yaml:"reality-opts,omitempty"`
+}
+
+// clashRealityOpts is ClashMeta reality-opts.
+type clashRealityOpts struct {
+	PublicKey string `yaml:"public-key"`
+	ShortID   string `yaml:"short-id,omitempty"`
+}
+
+// clashPluginOpts is ClashMeta plugin-opts for the shadow-tls plugin.
+type clashPluginOpts struct {
+	Host     string `yaml:"host"`
+	Password string `yaml:"password"`
+	Version  int    `yaml:"version"`
+}
+
+// clashWSOpts is ClashMeta ws-opts (also used for httpupgrade with v2ray-http-upgrade).
+type clashWSOpts struct {
+	Path             string            `yaml:"path,omitempty"`
+	Headers          map[string]string `yaml:"headers,omitempty"`
+	V2rayHTTPUpgrade bool              `yaml:"v2ray-http-upgrade,omitempty"`
+}
+
+// clashH2Opts is ClashMeta h2-opts.
+type clashH2Opts struct {
+	Host []string `yaml:"host,omitempty"`
+	Path string   `yaml:"path,omitempty"`
+}
+
+// clashGRPCOpts is ClashMeta grpc-opts.
+type clashGRPCOpts struct {
+	ServiceName string `yaml:"grpc-service-name,omitempty"`
+}
+
+// applyClashTransport sets network and per-transport options from config_json.transport.
+func applyClashTransport(p *clashProxy, ib *db.Inbound) {
+	tr := inboundTransport(ib)
+	p.Network = tr.Type
+	switch tr.Type {
+	case "ws", "httpupgrade":
+		opts := &clashWSOpts{Path: tr.Path}
+		if tr.Host != "" {
+			opts.Headers = map[string]string{"Host": tr.Host}
+		}
+		if tr.Type == "httpupgrade" {
+			p.Network = "ws"
+			opts.V2rayHTTPUpgrade = true
+		}
+		p.WSOpts = opts
+	case "grpc":
+		p.GRPCOpts = &clashGRPCOpts{ServiceName: tr.ServiceName}
+	case "http":
+		p.Network = "h2"
+		opts := &clashH2Opts{Path: tr.Path}
+		if tr.Host != "" {
+			opts.Host = []string{tr.Host}
+		}
+		p.H2Opts = opts
+	}
 }
 
 // GenerateClash returns ClashMeta YAML bytes.
+// Naive inbounds are skipped since ClashMeta has no naive client.
 func GenerateClash(u *db.User, fallbackHost string) ([]byte, error) {
 	proxies := make([]clashProxy, 0, len(u.Inbounds))
 	for _, ib := range u.Inbounds {
@@ -156,28 +399,128 @@ func GenerateClash(u *db.User, fallbackHost string) ([]byte, error) {
 		}
 		tlsEnabled := isTLSEnabled(&ib)
 		if ib.Protocol == "vless" {
+			p := clashProxy{
+				Name:   ib.Tag,
+				Type:   "vless",
+				Server: host,
+				Port:   ib.ListenPort,
+				UUID:   u.UUID,
+				TLS:    tlsEnabled,
+			}
+			applyClashTransport(&p, &ib)
+			if reality := inboundReality(&ib); reality.Enabled {
+				p.Servername = reality.ServerName
+				p.ClientFingerprint = reality.Fingerprint
+				p.RealityOpts = &clashRealityOpts{PublicKey: reality.PublicKey, ShortID: reality.ShortID}
+			} else if tlsEnabled {
+				p.Servername = host
+			}
+			p.Flow = vlessFlow(&ib)
+			proxies = append(proxies, p)
+		} else if ib.Protocol == "hysteria2" {
+			hy := inboundHysteria2(&ib)
+			p := clashProxy{
+				Name:         ib.Tag,
+				Type:         "hysteria2",
+				Server:       host,
+				Port:         ib.ListenPort,
+				Password:     u.Password,
+				SNI:          host,
+				Obfs:         hy.ObfsType,
+				ObfsPassword: hy.ObfsPassword,
+				Ports:        hy.HopPorts,
+			}
+			// Client upload is bounded by server download and vice versa.
+			if hy.DownMbps > 0 {
+				p.Up = fmt.Sprintf("%d Mbps", hy.DownMbps)
+			}
+			if hy.UpMbps > 0 {
+				p.Down = fmt.Sprintf("%d Mbps", hy.UpMbps)
+			}
+			proxies = append(proxies, p)
+		} else if ib.Protocol == "trojan" {
+			p := clashProxy{
+				Name:     ib.Tag,
+				Type:     "trojan",
+				Server:   host,
+				Port:     ib.ListenPort,
+				Password: u.Password,
+			}
+			applyClashTransport(&p, &ib)
+			if tlsEnabled {
+				p.SNI = host
+			}
+			proxies = append(proxies, p)
+		} else if ib.Protocol == "vmess" {
+			alterID := 0
 			p := clashProxy{
 				Name:    ib.Tag,
-				Type:    "vless",
+				Type:    "vmess",
 				Server:  host,
 				Port:    ib.ListenPort,
 				UUID:    u.UUID,
-				Network: "tcp",
+				AlterID: &alterID,
+				Cipher:  "auto",
 				TLS:     tlsEnabled,
+				UDP:     true,
 			}
 			if tlsEnabled {
 				p.Servername = host
-				p.Flow = "xtls-rprx-vision"
 			}
+			applyClashTransport(&p, &ib)
 			proxies = append(proxies, p)
-		} else if ib.Protocol == "hysteria2" {
+		} else if ib.Protocol == "tuic" {
+			cc, alpn := tuicSettings(inboundConfigMap(&ib))
+			proxies = append(proxies, clashProxy{
+				Name:                 ib.Tag,
+				Type:                 "tuic",
+				Server:               host,
+				Port:                 ib.ListenPort,
+				UUID:                 u.UUID,
+				Password:             u.Password,
+				SNI:                  host,
+				ALPN:                 alpn,
+				CongestionController: cc,
+				UDPRelayMode:         "native",
+			})
+		} else if ib.Protocol == "shadowsocks" {
+			method, password := shadowsocksClientPassword(u, &ib)
 			proxies = append(proxies, clashProxy{
 				Name:     ib.Tag,
-				Type:     "hysteria2",
+				Type:     "ss",
 				Server:   host,
 				Port:     ib.ListenPort,
-				Password: u.Password,
-				SNI:      host,
+				Cipher:   method,
+				Password: password,
+				UDP:      true,
+			})
+		} else if ib.Protocol == "anytls" {
+			proxies = append(proxies, clashProxy{
+				Name:              ib.Tag,
+				Type:              "anytls",
+				Server:            host,
+				Port:              ib.ListenPort,
+				Password:          u.Password,
+				SNI:               host,
+				ClientFingerprint: "chrome",
+				UDP:               true,
+			})
+		} else if ib.Protocol == "shadowtls" {
+			method, password := shadowsocksClientPassword(u, &ib)
+			proxies = append(proxies, clashProxy{
+				Name:              ib.Tag,
+				Type:              "ss",
+				Server:            host,
+				Port:              ib.ListenPort,
+				Cipher:            method,
+				Password:          password,
+				Plugin:            "shadow-tls",
+				ClientFingerprint: "chrome",
+				PluginOpts: &clashPluginOpts{
+					Host:     inboundShadowTLS(&ib).Server,
+					Password: u.Password,
+					Version:  3,
+				},
 			})
 		}
 	}
@@ -0,0 +1,177 @@
+package core
+
+import (
+	"fmt"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// Crash restart policy; vars so tests can shorten them.
+var (
+	superviseBackoffMin   = time.Second
+	superviseBackoffMax   = time.Minute
+	superviseStableWindow = 5 * time.Minute // a run this long resets the backoff
+	superviseMaxRestarts  = 5               // consecutive crash restarts before giving up
+)
+
+// SupervisorStatus is the crash-restart view of the managed sing-box process.
+type SupervisorStatus struct {
+	Restarts      int                 `json:"restarts"`  // automatic restarts since the panel started
+	CrashLoop     bool                `json:"crashLoop"` // gave up after superviseMaxRestarts consecutive crashes
+	StartedAt     *time.Time          `json:"startedAt,omitempty"`
+	UptimeSeconds int64               `json:"uptimeSeconds"` // 0 when not started by this panel
+	LastExit      *LastFailureContext `json:"lastExit,omitempty"`
+	NextRestartAt *time.Time          `json:"nextRestartAt,omitempty"`
+}
+
+// supervisor owns the exec.Cmd of a sing-box started by ProcessManager.Start and
+// restarts it with exponential backoff when it exits without Stop being called.
+type supervisor struct {
+	mu          sync.Mutex
+	cmd         *exec.Cmd
+	startedAt   time.Time
+	restarts    int
+	failures    int // consecutive crashes without a stable run
+	crashLoop   bool
+	lastExit    *LastFailureContext
+	timer       *time.Timer
+	nextRestart time.Time
+	gen         int // bumped on every ownership change; stale timers check it
+}
+
+var (
+	supervisorsMu sync.Mutex
+	supervisors   = map[string]*supervisor{}
+)
+
+// supervisorFor returns the supervisor of the sing-box that uses configPath.
+func supervisorFor(configPath string) *supervisor {
+	key := normalizePath(configPath)
+	supervisorsMu.Lock()
+	defer supervisorsMu.Unlock()
+	s, ok := supervisors[key]
+	if !ok {
+		s = &supervisor{}
+		supervisors[key] = s
+	}
+	return s
+}
+
+// Supervision returns restart counts, uptime and the last unexpected exit.
+func (p *ProcessManager) Supervision() SupervisorStatus {
+	s := supervisorFor(p.configPath)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := SupervisorStatus{Restarts: s.restarts, CrashLoop: s.crashLoop}
+	if s.cmd != nil {
+		startedAt := s.startedAt
+		status.StartedAt = &startedAt
+		status.UptimeSeconds = int64(time.Since(startedAt) / time.Second)
+	}
+	if s.lastExit != nil {
+		lastExit := *s.lastExit
+		status.LastExit = &lastExit
+	}
+	if s.timer != nil {
+		next := s.nextRestart
+		status.NextRestartAt = &next
+	}
+	return status
+}
+
+// track takes ownership of a started cmd. A manual start clears the crash history.
+func (s *supervisor) track(p *ProcessManager, cmd *exec.Cmd, configPath string, manual bool) {
+	s.mu.Lock()
+	s.cancelLocked()
+	s.cmd = cmd
+	s.startedAt = time.Now().UTC()
+	if manual {
+		s.failures = 0
+		s.crashLoop = false
+	}
+	gen := s.gen
+	s.mu.Unlock()
+	go s.wait(p, cmd, configPath, gen)
+}
+
+// release marks the owned process as stopping on purpose, so its exit is not a crash.
+func (s *supervisor) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cancelLocked()
+	s.cmd = nil
+	s.failures = 0
+	s.crashLoop = false
+}
+
+// cancelLocked drops a pending restart and invalidates in-flight waiters.
+func (s *supervisor) cancelLocked() {
+	s.gen++
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+}
+
+// wait reaps cmd and treats an exit while it is still the owned process as a crash.
+func (s *supervisor) wait(p *ProcessManager, cmd *exec.Cmd, configPath string, gen int) {
+	err := cmd.Wait()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.gen != gen || s.cmd != cmd {
+		return
+	}
+	detail := "exit status 0"
+	if err != nil {
+		detail = err.Error()
+	}
+	if time.Since(s.startedAt) >= superviseStableWindow {
+		s.failures = 0
+	}
+	s.cmd = nil
+	s.crashedLocked(p, configPath, newFailureContext("sing-box exited unexpectedly", "run", detail))
+}
+
+// crashedLocked records a crash and schedules the next restart, or gives up once
+// superviseMaxRestarts consecutive restarts have failed.
+func (s *supervisor) crashedLocked(p *ProcessManager, configPath string, exit *LastFailureContext) {
+	s.lastExit = exit
+	s.failures++
+	if s.failures > superviseMaxRestarts {
+		s.crashLoop = true
+		setLastFailure(newFailureContext(fmt.Sprintf("sing-box crash loop: gave up after %d restarts", superviseMaxRestarts), "supervise", exit.Source))
+		return
+	}
+	setLastFailure(exit)
+	delay := superviseBackoffMin << (s.failures - 1)
+	if delay > superviseBackoffMax || delay <= 0 {
+		delay = superviseBackoffMax
+	}
+	s.cancelLocked()
+	gen := s.gen
+	s.nextRestart = time.Now().UTC().Add(delay)
+	s.timer = time.AfterFunc(delay, func() { s.restart(p, configPath, gen) })
+}
+
+// restart runs a scheduled restart unless it was cancelled meanwhile.
+func (s *supervisor) restart(p *ProcessManager, configPath string, gen int) {
+	s.mu.Lock()
+	if s.gen != gen {
+		s.mu.Unlock()
+		return
+	}
+	s.timer = nil
+	s.restarts++
+	s.mu.Unlock()
+
+	err := p.start(configPath, false)
+	if err == nil || IsProcessErrorCode(err, ProcessErrorAlreadyRunning) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.gen == gen {
+		s.crashedLocked(p, configPath, newFailureContext("sing-box restart failed", "restart", err.Error()))
+	}
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before timeout")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestSupervisorRestartsCrashedProcess(t *testing.T) {
+	defer func(d time.Duration) { superviseBackoffMin = d }(superviseBackoffMin)
+	superviseBackoffMin = 10 * time.Millisecond
+
+	pm, _ := startFakeSingBox(t, "while :; do sleep 0.05; done")
+	if st := pm.Supervision(); st.StartedAt == nil || st.Restarts != 0 {
+		t.Fatalf("status after start = %+v", st)
+	}
+	pids, err := pm.runningPIDs()
+	if err != nil || len(pids) != 1 {
+		t.Fatalf("runningPIDs = %v, %v", pids, err)
+	}
+	if err := signalPIDs("KILL", pids); err != nil {
+		t.Fatalf("kill: %v", err)
+	}
+	waitFor(t, 5*time.Second, func() bool { return pm.Supervision().Restarts == 1 && pm.IsRunning() })
+	st := pm.Supervision()
+	if st.LastExit == nil || st.LastExit.Stage != "run" || !strings.Contains(st.LastExit.Source, "killed") {
+		t.Fatalf("last exit = %+v", st.LastExit)
+	}
+
+	if err := pm.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if st := pm.Supervision(); st.Restarts != 1 || st.StartedAt != nil || pm.IsRunning() {
+		t.Fatalf("Stop was treated as a crash: %+v", st)
+	}
+}
+
+func TestSupervisorGivesUpOnCrashLoop(t *testing.T) {
+	defer func(d time.Duration, n int) { superviseBackoffMin, superviseMaxRestarts = d, n }(superviseBackoffMin, superviseMaxRestarts)
+	superviseBackoffMin = 10 * time.Millisecond
+	superviseMaxRestarts = 2
+
+	pm, _ := startFakeSingBox(t, "sleep 0.7\nexit 3")
+	waitFor(t, 8*time.Second, func() bool { return pm.Supervision().CrashLoop })
+	st := pm.Supervision()
+	if st.Restarts != 2 || st.NextRestartAt != nil {
+		t.Fatalf("status = %+v", st)
+	}
+	snapshot := ResolveCoreState(pm)
+	if snapshot.State != CoreStateError || snapshot.LastError == nil || !strings.Contains(snapshot.LastError.Message, "crash loop") {
+		t.Fatalf("snapshot = %+v", snapshot)
+	}
+}
@@ -0,0 +1,61 @@
+package core
+
+import "github.com/s-ui/s-ui/internal/db"
+
+// transportInfo is the client-relevant subset of a sing-box V2Ray transport block.
+type transportInfo struct {
+	Type        string // "tcp" when no transport is configured
+	Path        string // ws, httpupgrade, http
+	Host        string // ws Host header, httpupgrade host, first http host
+	ServiceName string // grpc
+}
+
+// inboundTransport extracts transport settings from inbound config_json.transport.
+func inboundTransport(ib *db.Inbound) transportInfo {
+	info := transportInfo{Type: "tcp"}
+	cfg := inboundConfigMap(ib)
+	tr, ok := cfg["transport"].(map[string]any)
+	if !ok || len(tr) == 0 {
+		return info
+	}
+	if t, ok := tr["type"].(string); ok && t != "" {
+		info.Type = t
+	}
+	info.Path, _ = tr["path"].(string)
+	info.ServiceName, _ = tr["service_name"].(string)
+	switch info.Type {
+	case "ws":
+		if headers, ok := tr["headers"].(map[string]any); ok {
+			info.Host = headerHost(headers)
+		}
+	case "httpupgrade":
+		info.Host, _ = tr["host"].(string)
+	case "http":
+		// sing-box accepts host as a string list
+		switch h := tr["host"].(type) {
+		case []any:
+			if len(h) > 0 {
+				info.Host, _ = h[0].(string)
+			}
+		case string:
+			info.Host = h
+		}
+	}
+	return info
+}
+
+// headerHost returns the Host header value, accepting string or string list forms.
+func headerHost(headers map[string]any) string {
+	for _, key := range []string{"Host", "host"} {
+		switch h := headers[key].(type) {
+		case string:
+			return h
+		case []any:
+			if len(h) > 0 {
+				s, _ := h[0].(string)
+				return s
+			}
+		}
+	}
+	return ""
+}
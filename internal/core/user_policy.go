@@ -0,0 +1,62 @@
+package core
+
+import (
+	"github.com/s-ui/s-ui/internal/db"
+)
+
+// userPolicyRules builds auth_user route rules from per-user egress outbounds and
+// routing profiles. Users with their own outbound are matched first and skip their
+// profile; each profile then emits its rules restricted to its members, followed by
+// a catch-all to the profile outbound when set.
+func userPolicyRules(conn db.Conn) ([]map[string]any, error) {
+	users, err := conn.ListUsersWithPolicy()
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, nil
+	}
+	var outboundOrder []string
+	byOutbound := map[string][]any{}
+	byProfile := map[uint][]any{}
+	for _, u := range users {
+		if u.Outbound != "" {
+			if _, ok := byOutbound[u.Outbound]; !ok {
+				outboundOrder = append(outboundOrder, u.Outbound)
+			}
+			byOutbound[u.Outbound] = append(byOutbound[u.Outbound], u.Name)
+			continue
+		}
+		byProfile[*u.RoutingProfileID] = append(byProfile[*u.RoutingProfileID], u.Name)
+	}
+
+	var rules []map[string]any
+	for _, tag := range outboundOrder {
+		rules = append(rules, withRouteAction(map[string]any{"auth_user": byOutbound[tag]}, tag))
+	}
+	if len(byProfile) == 0 {
+		return rules, nil
+	}
+	profiles, err := conn.ListRoutingProfiles()
+	if err != nil {
+		return nil, err
+	}
+	for i := range profiles {
+		members := byProfile[profiles[i].ID]
+		if len(members) == 0 {
+			continue
+		}
+		for _, pr := range profiles[i].ParseRules() {
+			match := make(map[string]any, len(pr.Match)+1)
+			for k, v := range pr.Match {
+				match[k] = v
+			}
+			match["auth_user"] = members
+			rules = append(rules, withRouteAction(match, pr.Outbound))
+		}
+		if profiles[i].Outbound != "" {
+			rules = append(rules, withRouteAction(map[string]any{"auth_user": members}, profiles[i].Outbound))
+		}
+	}
+	return rules, nil
+}
@@ -25,12 +25,17 @@ func ListCertificates() ([]Certificate, error) {
 }
 
 func GetCertificateByID(id uint) (*Certificate, error) {
-	var c Certificate
-	err := DB.First(&c, id).Error
+	return Conn{}.GetCertificateByID(id)
+}
+
+// GetCertificateByID runs GetCertificateByID on c.
+func (c Conn) GetCertificateByID(id uint) (*Certificate, error) {
+	var cert Certificate
+	err := c.db().First(&cert, id).Error
 	if err != nil {
 		return nil, err
 	}
-	return &c, nil
+	return &cert, nil
 }
 
 func CreateCertificate(c *Certificate) error {
@@ -38,7 +43,12 @@ func CreateCertificate(c *Certificate) error {
 }
 
 func UpdateCertificate(c *Certificate) error {
-	return DB.Save(c).Error
+	return Conn{}.UpdateCertificate(c)
+}
+
+// UpdateCertificate runs UpdateCertificate on c.
+func (c Conn) UpdateCertificate(cert *Certificate) error {
+	return c.db().Save(cert).Error
 }
 
 func DeleteCertificate(id uint) error {
@@ -0,0 +1,49 @@
+package db
+
+import "time"
+
+// ConfigRevision is one successfully applied sing-box config together with the file
+// it replaced and what triggered the apply.
+type ConfigRevision struct {
+	ID             uint      `gorm:"primaryKey"`
+	Source         string    `gorm:"size:100;not null"` // e.g. "inbound create", "user batch", "cert update", "manual"
+	PreviousConfig string    `gorm:"type:text"`         // empty when no config file existed
+	Config         string    `gorm:"type:text;not null"`
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+}
+
+func (ConfigRevision) TableName() string {
+	return "config_revisions"
+}
+
+// ListConfigRevisions returns revisions newest first without loading the config bodies.
+func ListConfigRevisions() ([]ConfigRevision, error) {
+	var revs []ConfigRevision
+	err := DB.Select("id", "source", "created_at").Order("id DESC").Find(&revs).Error
+	return revs, err
+}
+
+// GetConfigRevisionByID returns a revision with both config bodies.
+func GetConfigRevisionByID(id uint) (*ConfigRevision, error) {
+	var rev ConfigRevision
+	if err := DB.First(&rev, id).Error; err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// CreateConfigRevision records rev and deletes all but the newest keep revisions.
+func CreateConfigRevision(rev *ConfigRevision, keep int) error {
+	if err := DB.Create(rev).Error; err != nil {
+		return err
+	}
+	if keep <= 0 {
+		return nil
+	}
+	var cutoff ConfigRevision
+	err := DB.Select("id").Order("id DESC").Offset(keep).Limit(1).Find(&cutoff).Error
+	if err != nil || cutoff.ID == 0 {
+		return err
+	}
+	return DB.Where("id <= ?", cutoff.ID).Delete(&ConfigRevision{}).Error
+}
@@ -0,0 +1,31 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCreateConfigRevisionPrunes(t *testing.T) {
+	if err := Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	for i := 1; i <= 5; i++ {
+		rev := &ConfigRevision{Source: fmt.Sprintf("test %d", i), Config: "{}"}
+		if err := CreateConfigRevision(rev, 3); err != nil {
+			t.Fatalf("CreateConfigRevision: %v", err)
+		}
+	}
+	revs, err := ListConfigRevisions()
+	if err != nil {
+		t.Fatalf("ListConfigRevisions: %v", err)
+	}
+	if len(revs) != 3 || revs[0].Source != "test 5" || revs[2].Source != "test 3" {
+		t.Fatalf("revisions = %+v", revs)
+	}
+	if revs[0].Config != "" {
+		t.Error("ListConfigRevisions should not load config bodies")
+	}
+	if _, err := GetConfigRevisionByID(revs[2].ID - 1); err == nil {
+		t.Error("pruned revision should not be found")
+	}
+}
@@ -13,10 +13,13 @@ func Init(path string) error {
 	if err != nil {
 		return err
 	}
-	if err := DB.AutoMigrate(&Admin{}, &Inbound{}, &Certificate{}, &User{}); err != nil {
+	if err := DB.AutoMigrate(&Admin{}, &Inbound{}, &Certificate{}, &User{}, &Outbound{}, &RouteRule{}, &RuleSet{}, &DNSServer{}, &DNSRule{}, &RoutingProfile{}, &ConfigRevision{}, &Setting{}); err != nil {
 		return err
 	}
-	return backfillSubscriptionTokens()
+	if err := backfillSubscriptionTokens(); err != nil {
+		return err
+	}
+	return backfillShadowsocksKeys()
 }
 
 // backfillSubscriptionTokens sets SubscriptionToken for existing users with empty token.
@@ -33,3 +36,43 @@ func backfillSubscriptionTokens() error {
 	}
 	return nil
 }
+
+// backfillShadowsocksKeys stores the password-derived Shadowsocks key for users created
+// before keys were stored, so their existing subscriptions keep working.
+func backfillShadowsocksKeys() error {
+	var users []User
+	if err := DB.Where("COALESCE(shadowsocks_key, '') = ?", "").Find(&users).Error; err != nil {
+		return err
+	}
+	for i := range users {
+		if err := DB.Model(&users[i]).Update("shadowsocks_key", legacyShadowsocksKey(users[i].Password)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Conn runs queries on a specific handle, such as a transaction that is rolled back
+// for a config preview. The zero Conn uses DB; package-level helpers with a Conn
+// method of the same name are shorthands for Conn{}.
+type Conn struct {
+	tx *gorm.DB
+}
+
+func (c Conn) db() *gorm.DB {
+	if c.tx == nil {
+		return DB
+	}
+	return c.tx
+}
+
+// WithRollback runs fn on a new transaction that is always rolled back, so fn can
+// stage changes and read them back without committing anything.
+func WithRollback(fn func(c Conn) error) error {
+	tx := DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer tx.Rollback()
+	return fn(Conn{tx: tx})
+}
@@ -0,0 +1,198 @@
+package db
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Setting keys for the dns block.
+const (
+	SettingDNSFinal        = "dns_final"         // dns server tag for dns.final; empty = first server
+	SettingDNSStrategy     = "dns_strategy"      // prefer_ipv4, prefer_ipv6, ipv4_only, ipv6_only; empty = sing-box default
+	SettingDNSClientSubnet = "dns_client_subnet" // EDNS client subnet prefix or address; empty = none
+)
+
+// DNSServer is one sing-box dns server (udp, tls, https, quic or fakeip).
+type DNSServer struct {
+	ID             uint      `gorm:"primaryKey"`
+	Tag            string    `gorm:"uniqueIndex;not null"`
+	Type           string    `gorm:"not null"`
+	Server         string    // address or domain name; unused for fakeip
+	ServerPort     uint      // 0 = protocol default
+	Path           string    // https only; empty = /dns-query
+	Detour         string    // outbound tag queries are sent through; empty = direct
+	DomainResolver string    // dns server tag resolving Server when it is a domain name
+	Inet4Range     string    // fakeip only
+	Inet6Range     string    // fakeip only
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime"`
+}
+
+func (DNSServer) TableName() string {
+	return "dns_servers"
+}
+
+// ListDNSServers returns dns servers in creation order.
+func ListDNSServers() ([]DNSServer, error) {
+	return Conn{}.ListDNSServers()
+}
+
+// ListDNSServers runs ListDNSServers on c.
+func (c Conn) ListDNSServers() ([]DNSServer, error) {
+	var servers []DNSServer
+	err := c.db().Order("id ASC").Find(&servers).Error
+	return servers, err
+}
+
+func GetDNSServerByID(id uint) (*DNSServer, error) {
+	var s DNSServer
+	err := DB.First(&s, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// DNSServerExistsByTag returns true if a dns server with the given tag exists.
+func DNSServerExistsByTag(tag string) (bool, error) {
+	var count int64
+	err := DB.Model(&DNSServer{}).Where("tag = ?", tag).Count(&count).Error
+	return count > 0, err
+}
+
+func CreateDNSServer(s *DNSServer) error {
+	return DB.Create(s).Error
+}
+
+func UpdateDNSServer(s *DNSServer) error {
+	return DB.Save(s).Error
+}
+
+func DeleteDNSServer(id uint) error {
+	return DB.Delete(&DNSServer{}, id).Error
+}
+
+// DNSServersByDetour returns tags of dns servers sending queries through outbound tag.
+func DNSServersByDetour(tag string) ([]string, error) {
+	var tags []string
+	err := DB.Model(&DNSServer{}).Where("detour = ?", tag).Order("id ASC").Pluck("tag", &tags).Error
+	return tags, err
+}
+
+// DNSServersByDomainResolver returns tags of dns servers resolved through dns server tag.
+func DNSServersByDomainResolver(tag string) ([]string, error) {
+	var tags []string
+	err := DB.Model(&DNSServer{}).Where("domain_resolver = ?", tag).Order("id ASC").Pluck("tag", &tags).Error
+	return tags, err
+}
+
+// DNSRule is one sing-box dns rule. Rules are emitted ordered by Priority (ascending).
+type DNSRule struct {
+	ID        uint           `gorm:"primaryKey"`
+	Priority  int            `gorm:"not null;default:0;index"`
+	Remark    string         `gorm:"size:255"`
+	Match     datatypes.JSON `gorm:"type:text"` // sing-box match fields: domain*, rule_set, outbound, query_type
+	Server    string         `gorm:"not null"`  // dns server tag answering matched queries
+	Disabled  bool           `gorm:"default:false"`
+	CreatedAt time.Time      `gorm:"autoCreateTime"`
+	UpdatedAt time.Time      `gorm:"autoUpdateTime"`
+}
+
+func (DNSRule) TableName() string {
+	return "dns_rules"
+}
+
+// ListDNSRules returns all dns rules in evaluation order.
+func ListDNSRules() ([]DNSRule, error) {
+	return Conn{}.ListDNSRules()
+}
+
+// ListDNSRules runs ListDNSRules on c.
+func (c Conn) ListDNSRules() ([]DNSRule, error) {
+	var rules []DNSRule
+	err := c.db().Order("priority ASC, id ASC").Find(&rules).Error
+	return rules, err
+}
+
+func GetDNSRuleByID(id uint) (*DNSRule, error) {
+	var rule DNSRule
+	err := DB.First(&rule, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// CreateDNSRule appends rule after the current last dns rule.
+func CreateDNSRule(rule *DNSRule) error {
+	var maxPriority *int
+	if err := DB.Model(&DNSRule{}).Select("MAX(priority)").Scan(&maxPriority).Error; err != nil {
+		return err
+	}
+	rule.Priority = 0
+	if maxPriority != nil {
+		rule.Priority = *maxPriority + 1
+	}
+	return DB.Create(rule).Error
+}
+
+func UpdateDNSRule(rule *DNSRule) error {
+	return DB.Save(rule).Error
+}
+
+func DeleteDNSRule(id uint) error {
+	return DB.Delete(&DNSRule{}, id).Error
+}
+
+// ReorderDNSRules assigns priorities following ids; ids must list every rule exactly once.
+func ReorderDNSRules(ids []uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for i, id := range ids {
+			res := tx.Model(&DNSRule{}).Where("id = ?", id).Update("priority", i)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return gorm.ErrRecordNotFound
+			}
+		}
+		return nil
+	})
+}
+
+// DNSRulesReferencingServer returns IDs of dns rules answered by dns server tag.
+func DNSRulesReferencingServer(tag string) ([]uint, error) {
+	var ids []uint
+	err := DB.Model(&DNSRule{}).Where("server = ?", tag).Order("priority ASC").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// DNSRulesReferencingMatch returns IDs of dns rules whose match field key contains tag.
+// It is used for the rule_set and outbound fields.
+func DNSRulesReferencingMatch(key, tag string) ([]uint, error) {
+	rules, err := ListDNSRules()
+	if err != nil {
+		return nil, err
+	}
+	var ids []uint
+	for i := range rules {
+		var match map[string]json.RawMessage
+		if len(rules[i].Match) == 0 || json.Unmarshal(rules[i].Match, &match) != nil {
+			continue
+		}
+		var values []string
+		if json.Unmarshal(match[key], &values) != nil {
+			continue
+		}
+		for _, v := range values {
+			if v == tag {
+				ids = append(ids, rules[i].ID)
+				break
+			}
+		}
+	}
+	return ids, nil
+}
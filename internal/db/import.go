@@ -0,0 +1,257 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ImportData is a batch of panel rows produced by a config importer.
+type ImportData struct {
+	Inbounds []ImportInbound
+	Users    []ImportUser
+}
+
+// ImportInbound is an inbound to create. When Cert is set, its paths are registered as
+// a Certificate (reusing one with the same paths) and referenced from tls.certificate_id.
+type ImportInbound struct {
+	Inbound Inbound
+	Cert    *Certificate
+}
+
+// ImportUser is a user to create, linked to the imported inbounds listed by tag.
+type ImportUser struct {
+	User        User
+	InboundTags []string
+}
+
+// ImportResult lists what ImportRecords created, for reporting and UndoImport.
+type ImportResult struct {
+	CertificateIDs []uint
+	InboundIDs     []uint
+	UserIDs        []uint
+	MergedUsers    []string // imported users matched to existing users by name or credential
+	// KeptShadowsocksKeys lists merged users whose existing shadowsocks key was kept
+	// over a different imported one, because their current subscriptions use it.
+	KeptShadowsocksKeys []string
+
+	replacedKeys map[uint]string // previous shadowsocks keys of merged users, for UndoImport
+}
+
+// ImportRecords creates the certificates, inbounds and users of data in one transaction.
+// Users matching an existing user by name or credential keep the existing row and
+// credentials and are only linked to the new inbounds. The exception is an imported
+// shadowsocks key, which a merged user takes when no shadowsocks inbound uses its own.
+func ImportRecords(data *ImportData) (*ImportResult, error) {
+	res := &ImportResult{}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		inbounds := make(map[string]*Inbound, len(data.Inbounds))
+		for i := range data.Inbounds {
+			item := &data.Inbounds[i]
+			ib := item.Inbound
+			if item.Cert != nil {
+				certID, created, err := findOrCreateCertificate(tx, item.Cert)
+				if err != nil {
+					return err
+				}
+				if created {
+					res.CertificateIDs = append(res.CertificateIDs, certID)
+				}
+				cfg, err := withCertificateID(ib.ConfigJSON, certID)
+				if err != nil {
+					return fmt.Errorf("inbound %s: %w", ib.Tag, err)
+				}
+				ib.ConfigJSON = cfg
+			}
+			if err := tx.Create(&ib).Error; err != nil {
+				return fmt.Errorf("inbound %s: %w", ib.Tag, err)
+			}
+			res.InboundIDs = append(res.InboundIDs, ib.ID)
+			inbounds[ib.Tag] = &ib
+		}
+		for i := range data.Users {
+			item := &data.Users[i]
+			u, err := findImportedUser(tx, &item.User)
+			if err != nil {
+				return err
+			}
+			if u != nil {
+				res.MergedUsers = append(res.MergedUsers, item.User.Name)
+				if err := mergeShadowsocksKey(tx, u, item.User.ShadowsocksKey, res); err != nil {
+					return fmt.Errorf("user %s: %w", u.Name, err)
+				}
+			} else {
+				u = &item.User
+				if u.UUID == "" {
+					u.UUID = uuid.NewString()
+				}
+				if u.Password == "" {
+					u.Password = uuid.NewString()
+				}
+				if u.ShadowsocksKey == "" {
+					u.ShadowsocksKey = GenerateShadowsocksKey()
+				}
+				if u.SubscriptionToken == "" {
+					u.SubscriptionToken = GenerateSubscriptionToken()
+				}
+				enabled := u.Enabled
+				if err := tx.Omit("Inbounds").Create(u).Error; err != nil {
+					return fmt.Errorf("user %s: %w", u.Name, err)
+				}
+				if !enabled {
+					// enabled defaults to true, so a false value is not written by Create.
+					if err := tx.Model(u).Update("enabled", false).Error; err != nil {
+						return fmt.Errorf("user %s: %w", u.Name, err)
+					}
+				}
+				res.UserIDs = append(res.UserIDs, u.ID)
+			}
+			links := make([]Inbound, 0, len(item.InboundTags))
+			for _, tag := range item.InboundTags {
+				if ib, ok := inbounds[tag]; ok {
+					links = append(links, *ib)
+				}
+			}
+			if len(links) > 0 {
+				if err := tx.Model(u).Association("Inbounds").Append(links); err != nil {
+					return fmt.Errorf("user %s: %w", u.Name, err)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// UndoImport deletes the rows ImportRecords created, including every user link to the
+// imported inbounds.
+func UndoImport(res *ImportResult) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for id, key := range res.replacedKeys {
+			if err := tx.Model(&User{}).Where("id = ?", id).Update("shadowsocks_key", key).Error; err != nil {
+				return err
+			}
+		}
+		if len(res.InboundIDs) > 0 {
+			if err := tx.Exec("DELETE FROM user_inbounds WHERE inbound_id IN ?", res.InboundIDs).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&Inbound{}, res.InboundIDs).Error; err != nil {
+				return err
+			}
+		}
+		if len(res.UserIDs) > 0 {
+			if err := tx.Exec("DELETE FROM user_inbounds WHERE user_id IN ?", res.UserIDs).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&User{}, res.UserIDs).Error; err != nil {
+				return err
+			}
+		}
+		if len(res.CertificateIDs) > 0 {
+			if err := tx.Delete(&Certificate{}, res.CertificateIDs).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// mergeShadowsocksKey gives the existing user u the imported shadowsocks key unless u
+// is linked to a shadowsocks or shadowtls inbound, whose clients hold its current key.
+func mergeShadowsocksKey(tx *gorm.DB, u *User, key string, res *ImportResult) error {
+	if key == "" || key == u.ShadowsocksKey {
+		return nil
+	}
+	var linked int64
+	err := tx.Table("user_inbounds").
+		Joins("JOIN inbounds ON inbounds.id = user_inbounds.inbound_id").
+		Where("user_inbounds.user_id = ? AND inbounds.protocol IN ?", u.ID, []string{"shadowsocks", "shadowtls"}).
+		Count(&linked).Error
+	if err != nil {
+		return err
+	}
+	if linked > 0 {
+		res.KeptShadowsocksKeys = append(res.KeptShadowsocksKeys, u.Name)
+		return nil
+	}
+	old := u.ShadowsocksKey
+	if err := tx.Model(u).Update("shadowsocks_key", key).Error; err != nil {
+		return err
+	}
+	if res.replacedKeys == nil {
+		res.replacedKeys = map[uint]string{}
+	}
+	res.replacedKeys[u.ID] = old
+	return nil
+}
+
+// findOrCreateCertificate returns the certificate with c's paths, creating it when absent.
+func findOrCreateCertificate(tx *gorm.DB, c *Certificate) (id uint, created bool, err error) {
+	var existing Certificate
+	err = tx.Where("fullchain_path = ? AND privkey_path = ?", c.FullchainPath, c.PrivkeyPath).First(&existing).Error
+	if err == nil {
+		return existing.ID, false, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return 0, false, err
+	}
+	cert := *c
+	if err := tx.Create(&cert).Error; err != nil {
+		return 0, false, err
+	}
+	return cert.ID, true, nil
+}
+
+// withCertificateID replaces tls certificate_path/key_path with certificate_id.
+func withCertificateID(configJSON datatypes.JSON, certID uint) (datatypes.JSON, error) {
+	cfg := map[string]any{}
+	if len(configJSON) > 0 {
+		if err := json.Unmarshal(configJSON, &cfg); err != nil {
+			return nil, err
+		}
+	}
+	tls, _ := cfg["tls"].(map[string]any)
+	if tls == nil {
+		tls = map[string]any{"enabled": true}
+		cfg["tls"] = tls
+	}
+	delete(tls, "certificate_path")
+	delete(tls, "key_path")
+	tls["certificate_id"] = certID
+	out, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return datatypes.JSON(out), nil
+}
+
+// findImportedUser returns the existing user matching u by name, uuid, password or
+// shadowsocks key, or nil.
+func findImportedUser(tx *gorm.DB, u *User) (*User, error) {
+	for _, q := range []struct{ col, val string }{
+		{"name", u.Name},
+		{"uuid", u.UUID},
+		{"password", u.Password},
+		{"shadowsocks_key", u.ShadowsocksKey},
+	} {
+		if q.val == "" {
+			continue
+		}
+		var existing User
+		err := tx.Where(q.col+" = ?", q.val).First(&existing).Error
+		if err == nil {
+			return &existing, nil
+		}
+		if err != gorm.ErrRecordNotFound {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
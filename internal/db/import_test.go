@@ -0,0 +1,45 @@
+package db
+
+import "testing"
+
+func TestImportRecordsAndUndo(t *testing.T) {
+	if err := Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	data := &ImportData{
+		Inbounds: []ImportInbound{{
+			Inbound: Inbound{Tag: "in", Protocol: "trojan", ListenPort: 443, ConfigJSON: []byte(`{"tls":{"enabled":true,"certificate_path":"/c.crt","key_path":"/c.key"}}`)},
+			Cert:    &Certificate{Name: "in", FullchainPath: "/c.crt", PrivkeyPath: "/c.key"},
+		}},
+		Users: []ImportUser{{User: User{Name: "bob", Password: "pw", Enabled: false}, InboundTags: []string{"in"}}},
+	}
+	res, err := ImportRecords(data)
+	if err != nil {
+		t.Fatalf("ImportRecords: %v", err)
+	}
+	u, err := GetUserByName("bob")
+	if err != nil {
+		t.Fatalf("GetUserByName: %v", err)
+	}
+	if u.Enabled || u.Password != "pw" || u.SubscriptionToken == "" || len(u.Inbounds) != 1 {
+		t.Fatalf("user = %+v, want disabled with preserved password and one inbound", u)
+	}
+	if tags, _ := InboundsReferencingCert(res.CertificateIDs[0]); len(tags) != 1 {
+		t.Fatalf("certificate referenced by %v", tags)
+	}
+
+	if err := UndoImport(res); err != nil {
+		t.Fatalf("UndoImport: %v", err)
+	}
+	var count int64
+	DB.Table("user_inbounds").Count(&count)
+	if count != 0 {
+		t.Fatalf("user_inbounds rows = %d after undo", count)
+	}
+	if _, err := GetUserByName("bob"); err == nil {
+		t.Fatal("user survived UndoImport")
+	}
+	if certs, _ := ListCertificates(); len(certs) != 0 {
+		t.Fatalf("certificates = %d after undo", len(certs))
+	}
+}
@@ -9,7 +9,7 @@ import (
 type Inbound struct {
 	ID         uint           `gorm:"primaryKey"`
 	Tag        string         `gorm:"uniqueIndex;not null"`
-	Protocol   string         `gorm:"not null"` // "vless" or "hysteria2"
+	Protocol   string         `gorm:"not null"` // "vless", "vmess", "hysteria2", "tuic", "trojan", "shadowsocks", "shadowtls", "anytls" or "naive"
 	Listen     string         `gorm:"default:'::'"` // listen address
 	ListenPort     uint           `gorm:"not null"`
 	ConfigJSON     datatypes.JSON `gorm:"type:text"` // tls, transport, users, up_mbps, down_mbps, obfs per protocol
@@ -25,8 +25,13 @@ func (Inbound) TableName() string {
 
 // ListInbounds returns inbounds. sort: "traffic_asc", "traffic_desc", or empty (default created_at desc).
 func ListInbounds(sort string) ([]Inbound, error) {
+	return Conn{}.ListInbounds(sort)
+}
+
+// ListInbounds runs ListInbounds on c.
+func (c Conn) ListInbounds(sort string) ([]Inbound, error) {
 	var inbounds []Inbound
-	q := DB
+	q := c.db()
 	switch sort {
 	case "traffic_asc":
 		q = q.Order("(traffic_uplink + traffic_downlink) ASC")
@@ -50,20 +55,35 @@ func GetInboundByID(id uint) (*Inbound, error) {
 
 // GetInboundsByIDs returns inbounds by IDs for user-inbound association.
 func GetInboundsByIDs(ids []uint) ([]Inbound, error) {
+	return Conn{}.GetInboundsByIDs(ids)
+}
+
+// GetInboundsByIDs runs GetInboundsByIDs on c.
+func (c Conn) GetInboundsByIDs(ids []uint) ([]Inbound, error) {
 	if len(ids) == 0 {
 		return nil, nil
 	}
 	var inbounds []Inbound
-	err := DB.Where("id IN ?", ids).Find(&inbounds).Error
+	err := c.db().Where("id IN ?", ids).Find(&inbounds).Error
 	return inbounds, err
 }
 
 func CreateInbound(in *Inbound) error {
-	return DB.Create(in).Error
+	return Conn{}.CreateInbound(in)
+}
+
+// CreateInbound runs CreateInbound on c.
+func (c Conn) CreateInbound(in *Inbound) error {
+	return c.db().Create(in).Error
 }
 
 func UpdateInbound(in *Inbound) error {
-	return DB.Save(in).Error
+	return Conn{}.UpdateInbound(in)
+}
+
+// UpdateInbound runs UpdateInbound on c.
+func (c Conn) UpdateInbound(in *Inbound) error {
+	return c.db().Save(in).Error
 }
 
 func DeleteInbound(id uint) error {
@@ -0,0 +1,132 @@
+package db
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Outbound is a panel-managed sing-box outbound (or wireguard endpoint).
+type Outbound struct {
+	ID         uint           `gorm:"primaryKey"`
+	Tag        string         `gorm:"uniqueIndex;not null"`
+	Type       string         `gorm:"not null"`  // "direct", "socks", "http", "shadowsocks", "vless", "wireguard", "selector" or "urltest"
+	ConfigJSON datatypes.JSON `gorm:"type:text"` // type-specific sing-box fields (server, server_port, detour, outbounds, ...)
+	CreatedAt  time.Time      `gorm:"autoCreateTime"`
+	UpdatedAt  time.Time      `gorm:"autoUpdateTime"`
+}
+
+func (Outbound) TableName() string {
+	return "outbounds"
+}
+
+// ListOutbounds returns outbounds in creation order so generated config is stable.
+func ListOutbounds() ([]Outbound, error) {
+	return Conn{}.ListOutbounds()
+}
+
+// ListOutbounds runs ListOutbounds on c.
+func (c Conn) ListOutbounds() ([]Outbound, error) {
+	var outbounds []Outbound
+	err := c.db().Order("id ASC").Find(&outbounds).Error
+	return outbounds, err
+}
+
+func GetOutboundByID(id uint) (*Outbound, error) {
+	var ob Outbound
+	err := DB.First(&ob, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &ob, nil
+}
+
+// GetOutboundByTag returns an outbound by tag.
+func GetOutboundByTag(tag string) (*Outbound, error) {
+	var ob Outbound
+	err := DB.Where("tag = ?", tag).First(&ob).Error
+	if err != nil {
+		return nil, err
+	}
+	return &ob, nil
+}
+
+// OutboundExistsByTag returns true if an outbound with the given tag exists.
+func OutboundExistsByTag(tag string) (bool, error) {
+	var count int64
+	err := DB.Model(&Outbound{}).Where("tag = ?", tag).Count(&count).Error
+	return count > 0, err
+}
+
+func CreateOutbound(ob *Outbound) error {
+	return DB.Create(ob).Error
+}
+
+func UpdateOutbound(ob *Outbound) error {
+	return DB.Save(ob).Error
+}
+
+func DeleteOutbound(id uint) error {
+	return DB.Delete(&Outbound{}, id).Error
+}
+
+// OutboundDetour returns config_json.detour of an outbound, or "" when unset.
+func OutboundDetour(ob *Outbound) string {
+	if len(ob.ConfigJSON) == 0 {
+		return ""
+	}
+	var cfg map[string]any
+	if err := json.Unmarshal(ob.ConfigJSON, &cfg); err != nil {
+		return ""
+	}
+	detour, _ := cfg["detour"].(string)
+	return detour
+}
+
+// OutboundsReferencingDetour returns tags of outbounds chained through tag via config_json.detour.
+func OutboundsReferencingDetour(tag string) ([]string, error) {
+	outbounds, err := ListOutbounds()
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	for i := range outbounds {
+		if OutboundDetour(&outbounds[i]) == tag {
+			tags = append(tags, outbounds[i].Tag)
+		}
+	}
+	return tags, nil
+}
+
+// OutboundGroupMembers returns config_json.outbounds of a selector or urltest group.
+func OutboundGroupMembers(ob *Outbound) []string {
+	if len(ob.ConfigJSON) == 0 {
+		return nil
+	}
+	var cfg struct {
+		Outbounds []string `json:"outbounds"`
+	}
+	if err := json.Unmarshal(ob.ConfigJSON, &cfg); err != nil {
+		return nil
+	}
+	return cfg.Outbounds
+}
+
+// OutboundsReferencingMember returns tags of groups listing tag as a member.
+func OutboundsReferencingMember(tag string) ([]string, error) {
+	outbounds, err := ListOutbounds()
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	for i := range outbounds {
+		for _, m := range OutboundGroupMembers(&outbounds[i]) {
+			if m == tag {
+				tags = append(tags, outbounds[i].Tag)
+				break
+			}
+		}
+	}
+	return tags, nil
+}
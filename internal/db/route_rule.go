@@ -0,0 +1,89 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// RouteRule is one sing-box route rule. Rules are emitted ordered by Priority (ascending).
+type RouteRule struct {
+	ID        uint           `gorm:"primaryKey"`
+	Priority  int            `gorm:"not null;default:0;index"`
+	Remark    string         `gorm:"size:255"`
+	Match     datatypes.JSON `gorm:"type:text"` // sing-box match fields: inbound, auth_user, domain*, ip_cidr, port, protocol, network
+	Outbound  string         `gorm:"not null"`  // outbound tag; "block" rejects the connection
+	Disabled  bool           `gorm:"default:false"`
+	CreatedAt time.Time      `gorm:"autoCreateTime"`
+	UpdatedAt time.Time      `gorm:"autoUpdateTime"`
+}
+
+func (RouteRule) TableName() string {
+	return "route_rules"
+}
+
+// ListRouteRules returns all rules in evaluation order.
+func ListRouteRules() ([]RouteRule, error) {
+	return Conn{}.ListRouteRules()
+}
+
+// ListRouteRules runs ListRouteRules on c.
+func (c Conn) ListRouteRules() ([]RouteRule, error) {
+	var rules []RouteRule
+	err := c.db().Order("priority ASC, id ASC").Find(&rules).Error
+	return rules, err
+}
+
+func GetRouteRuleByID(id uint) (*RouteRule, error) {
+	var rule RouteRule
+	err := DB.First(&rule, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// CreateRouteRule appends rule after the current last rule.
+func CreateRouteRule(rule *RouteRule) error {
+	var maxPriority *int
+	if err := DB.Model(&RouteRule{}).Select("MAX(priority)").Scan(&maxPriority).Error; err != nil {
+		return err
+	}
+	rule.Priority = 0
+	if maxPriority != nil {
+		rule.Priority = *maxPriority + 1
+	}
+	return DB.Create(rule).Error
+}
+
+func UpdateRouteRule(rule *RouteRule) error {
+	return DB.Save(rule).Error
+}
+
+func DeleteRouteRule(id uint) error {
+	return DB.Delete(&RouteRule{}, id).Error
+}
+
+// ReorderRouteRules assigns priorities following ids; ids must list every rule exactly once.
+func ReorderRouteRules(ids []uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for i, id := range ids {
+			res := tx.Model(&RouteRule{}).Where("id = ?", id).Update("priority", i)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return gorm.ErrRecordNotFound
+			}
+		}
+		return nil
+	})
+}
+
+// RouteRulesReferencingOutbound returns IDs of rules routing to tag.
+func RouteRulesReferencingOutbound(tag string) ([]uint, error) {
+	var ids []uint
+	err := DB.Model(&RouteRule{}).Where("outbound = ?", tag).Order("priority ASC").Pluck("id", &ids).Error
+	return ids, err
+}
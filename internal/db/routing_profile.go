@@ -0,0 +1,127 @@
+package db
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// RoutingProfile is a named routing policy shared by the users bound to it. Its rules
+// are evaluated for those users before the global route rules; traffic matching none
+// of them leaves via Outbound when set.
+type RoutingProfile struct {
+	ID        uint           `gorm:"primaryKey"`
+	Name      string         `gorm:"uniqueIndex;not null"`
+	Remark    string         `gorm:"size:255"`
+	Rules     datatypes.JSON `gorm:"type:text"` // ordered []RoutingProfileRule
+	Outbound  string         // default egress for profile users; empty = fall through to global rules
+	CreatedAt time.Time      `gorm:"autoCreateTime"`
+	UpdatedAt time.Time      `gorm:"autoUpdateTime"`
+}
+
+func (RoutingProfile) TableName() string {
+	return "routing_profiles"
+}
+
+// RoutingProfileRule is one entry of RoutingProfile.Rules. Match takes the same
+// fields as RouteRule.Match except auth_user, which is filled from profile members.
+type RoutingProfileRule struct {
+	Match    map[string]any `json:"match"`
+	Outbound string         `json:"outbound"`
+}
+
+// ParseRules decodes Rules; malformed JSON yields no rules.
+func (p *RoutingProfile) ParseRules() []RoutingProfileRule {
+	var rules []RoutingProfileRule
+	if len(p.Rules) > 0 {
+		_ = json.Unmarshal(p.Rules, &rules)
+	}
+	return rules
+}
+
+// ListRoutingProfiles returns routing profiles in creation order.
+func ListRoutingProfiles() ([]RoutingProfile, error) {
+	return Conn{}.ListRoutingProfiles()
+}
+
+// ListRoutingProfiles runs ListRoutingProfiles on c.
+func (c Conn) ListRoutingProfiles() ([]RoutingProfile, error) {
+	var profiles []RoutingProfile
+	err := c.db().Order("id ASC").Find(&profiles).Error
+	return profiles, err
+}
+
+func GetRoutingProfileByID(id uint) (*RoutingProfile, error) {
+	var p RoutingProfile
+	err := DB.First(&p, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// RoutingProfileExistsByName returns true if a routing profile with the given name exists.
+func RoutingProfileExistsByName(name string) (bool, error) {
+	var count int64
+	err := DB.Model(&RoutingProfile{}).Where("name = ?", name).Count(&count).Error
+	return count > 0, err
+}
+
+func CreateRoutingProfile(p *RoutingProfile) error {
+	return DB.Create(p).Error
+}
+
+func UpdateRoutingProfile(p *RoutingProfile) error {
+	return DB.Save(p).Error
+}
+
+func DeleteRoutingProfile(id uint) error {
+	return DB.Delete(&RoutingProfile{}, id).Error
+}
+
+// RoutingProfilesReferencingOutbound returns names of routing profiles using outbound
+// tag as their default outbound or in a rule.
+func RoutingProfilesReferencingOutbound(tag string) ([]string, error) {
+	return routingProfilesMatching(func(p *RoutingProfile) bool {
+		if p.Outbound == tag {
+			return true
+		}
+		for _, rule := range p.ParseRules() {
+			if rule.Outbound == tag {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// RoutingProfilesReferencingRuleSet returns names of routing profiles with a rule
+// whose match.rule_set contains tag.
+func RoutingProfilesReferencingRuleSet(tag string) ([]string, error) {
+	return routingProfilesMatching(func(p *RoutingProfile) bool {
+		for _, rule := range p.ParseRules() {
+			sets, _ := rule.Match["rule_set"].([]any)
+			for _, s := range sets {
+				if s == tag {
+					return true
+				}
+			}
+		}
+		return false
+	})
+}
+
+func routingProfilesMatching(pred func(*RoutingProfile) bool) ([]string, error) {
+	profiles, err := ListRoutingProfiles()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for i := range profiles {
+		if pred(&profiles[i]) {
+			names = append(names, profiles[i].Name)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// RuleSet is a sing-box rule-set referenced by route rules via match.rule_set.
+// Local rule-sets live under DataDir; remote ones are downloaded there by the panel,
+// or by sing-box until the panel's first download succeeds.
+type RuleSet struct {
+	ID             uint       `gorm:"primaryKey"`
+	Tag            string     `gorm:"uniqueIndex;not null"`
+	Type           string     `gorm:"not null"` // "local" or "remote"
+	Format         string     `gorm:"not null"` // "binary" (.srs) or "source" (.json)
+	Path           string     // local file path; for remote rule-sets, the panel's download
+	URL            string     // remote download URL
+	UpdateInterval string     // remote update interval, e.g. "1d"; empty = sing-box default
+	DownloadDetour string     // remote download outbound tag; empty = direct
+	SHA256         string     `gorm:"column:sha256;size:64"` // hash of the last stored or fetched content
+	LastUpdatedAt  *time.Time // last successful upload or refresh
+	LastError      string     // last refresh error; cleared on success
+	CreatedAt      time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime"`
+}
+
+func (RuleSet) TableName() string {
+	return "rule_sets"
+}
+
+// ListRuleSets returns rule-sets in creation order.
+func ListRuleSets() ([]RuleSet, error) {
+	return Conn{}.ListRuleSets()
+}
+
+// ListRuleSets runs ListRuleSets on c.
+func (c Conn) ListRuleSets() ([]RuleSet, error) {
+	var sets []RuleSet
+	err := c.db().Order("id ASC").Find(&sets).Error
+	return sets, err
+}
+
+func GetRuleSetByID(id uint) (*RuleSet, error) {
+	var rs RuleSet
+	err := DB.First(&rs, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rs, nil
+}
+
+// GetRuleSetByTag returns a rule-set by tag.
+func GetRuleSetByTag(tag string) (*RuleSet, error) {
+	var rs RuleSet
+	err := DB.Where("tag = ?", tag).First(&rs).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rs, nil
+}
+
+// RuleSetExistsByTag returns true if a rule-set with the given tag exists.
+func RuleSetExistsByTag(tag string) (bool, error) {
+	var count int64
+	err := DB.Model(&RuleSet{}).Where("tag = ?", tag).Count(&count).Error
+	return count > 0, err
+}
+
+func CreateRuleSet(rs *RuleSet) error {
+	return DB.Create(rs).Error
+}
+
+func UpdateRuleSet(rs *RuleSet) error {
+	return DB.Save(rs).Error
+}
+
+// UpdateRuleSetRefresh saves the refresh results of rs (path, hash, update time and
+// error). A refresh that raced with an edit of the URL or format is not saved.
+func UpdateRuleSetRefresh(rs *RuleSet) error {
+	return DB.Model(&RuleSet{}).
+		Where("id = ? AND url = ? AND format = ?", rs.ID, rs.URL, rs.Format).
+		Select("path", "sha256", "last_updated_at", "last_error").
+		Updates(rs).Error
+}
+
+func DeleteRuleSet(id uint) error {
+	return DB.Delete(&RuleSet{}, id).Error
+}
+
+// RuleSetsByDownloadDetour returns tags of remote rule-sets downloaded through outbound tag.
+func RuleSetsByDownloadDetour(tag string) ([]string, error) {
+	var tags []string
+	err := DB.Model(&RuleSet{}).Where("download_detour = ?", tag).Order("id ASC").Pluck("tag", &tags).Error
+	return tags, err
+}
+
+// RouteRulesReferencingRuleSet returns IDs of route rules whose match.rule_set contains tag.
+func RouteRulesReferencingRuleSet(tag string) ([]uint, error) {
+	rules, err := ListRouteRules()
+	if err != nil {
+		return nil, err
+	}
+	var ids []uint
+	for i := range rules {
+		var match struct {
+			RuleSet []string `json:"rule_set"`
+		}
+		if len(rules[i].Match) == 0 || json.Unmarshal(rules[i].Match, &match) != nil {
+			continue
+		}
+		for _, t := range match.RuleSet {
+			if t == tag {
+				ids = append(ids, rules[i].ID)
+				break
+			}
+		}
+	}
+	return ids, nil
+}
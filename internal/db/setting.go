@@ -0,0 +1,54 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Setting keys for panel-wide values that do not warrant their own table.
+const (
+	SettingRouteFinal    = "route_final"    // outbound tag for route.final; empty = sing-box default (first outbound)
+	SettingConfigOverlay = "config_overlay" // JSON object merged into every generated config; empty = none
+	SettingLogLevel      = "log_level"      // sing-box log.level; empty = info
+	SettingLogTimestamp  = "log_timestamp"  // "false" drops log line timestamps; empty = on
+)
+
+// Setting is a key/value pair for panel-wide configuration.
+type Setting struct {
+	Key       string    `gorm:"primaryKey;size:100"`
+	Value     string    `gorm:"type:text"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+func (Setting) TableName() string {
+	return "settings"
+}
+
+// GetSetting returns the value for key, or "" when unset.
+func GetSetting(key string) (string, error) {
+	return Conn{}.GetSetting(key)
+}
+
+// GetSetting runs GetSetting on c.
+func (c Conn) GetSetting(key string) (string, error) {
+	var s Setting
+	err := c.db().Where("key = ?", key).First(&s).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return s.Value, nil
+}
+
+// SetSetting upserts the value for key.
+func SetSetting(key, value string) error {
+	return DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&Setting{Key: key, Value: value}).Error
+}
@@ -2,6 +2,8 @@ package db
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,8 +15,9 @@ type User struct {
 	ID                 uint      `gorm:"primaryKey"`
 	Name               string    `gorm:"size:100;not null"`
 	Remark             string    `gorm:"size:255"`
-	UUID               string    `gorm:"size:36;uniqueIndex"` // VLESS; auto-generated
-	Password           string    `gorm:"size:255"`            // Hysteria2; auto-generated
+	UUID               string    `gorm:"size:36;uniqueIndex"` // VLESS/VMess/TUIC; auto-generated
+	Password           string    `gorm:"size:255"`            // Hysteria2/Trojan/TUIC; auto-generated
+	ShadowsocksKey     string    `gorm:"size:64"`             // Shadowsocks 2022 user key (base64); auto-generated or imported
 	SubscriptionToken  string    `gorm:"size:32;uniqueIndex"`  // short token for /sub/{token}
 	TrafficLimit       int64     `gorm:"default:0"`            // bytes; 0 = unlimited
 	TrafficUsed        int64     `gorm:"default:0"`            // bytes; StatsClient sets = TrafficUplink + TrafficDownlink
@@ -22,6 +25,8 @@ type User struct {
 	TrafficDownlink    int64     `gorm:"default:0"`            // bytes
 	ExpireAt           *time.Time                            // nil = no expiry
 	Enabled            bool      `gorm:"default:true"`
+	Outbound           string                                // egress outbound tag; empty = routing profile or global rules
+	RoutingProfileID   *uint     `gorm:"index"`              // nil = no routing profile
 	CreatedAt          time.Time `gorm:"autoCreateTime"`
 	UpdatedAt          time.Time `gorm:"autoUpdateTime"`
 	Inbounds           []Inbound `gorm:"many2many:user_inbounds;"`
@@ -41,6 +46,23 @@ func GenerateSubscriptionToken() string {
 	return string(b)
 }
 
+// GenerateShadowsocksKey returns a random 32-byte base64 key; inbounds whose method
+// needs a shorter key use its leading bytes.
+func GenerateShadowsocksKey() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// legacyShadowsocksKey is the key earlier versions derived from the password on every
+// config generation; backfilling it keeps existing Shadowsocks clients working.
+func legacyShadowsocksKey(password string) string {
+	sum := sha256.Sum256([]byte("shadowsocks-2022:" + password))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
 func (User) TableName() string {
 	return "users"
 }
@@ -59,8 +81,13 @@ func ListUsers(keyword string) ([]User, error) {
 
 // GetUserByID returns a user by ID or gorm.ErrRecordNotFound.
 func GetUserByID(id uint) (*User, error) {
+	return Conn{}.GetUserByID(id)
+}
+
+// GetUserByID runs GetUserByID on c.
+func (c Conn) GetUserByID(id uint) (*User, error) {
 	var u User
-	err := DB.Preload("Inbounds").First(&u, id).Error
+	err := c.db().Preload("Inbounds").First(&u, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -93,7 +120,7 @@ func GetUserBySubscriptionToken(token string) (*User, error) {
 	return &u, nil
 }
 
-// CreateUser creates a user. Auto-generates UUID, Password, and SubscriptionToken if empty.
+// CreateUser creates a user. Auto-generates UUID, Password, ShadowsocksKey and SubscriptionToken if empty.
 func CreateUser(u *User) error {
 	if u.UUID == "" {
 		u.UUID = uuid.NewString()
@@ -101,6 +128,9 @@ func CreateUser(u *User) error {
 	if u.Password == "" {
 		u.Password = uuid.NewString()
 	}
+	if u.ShadowsocksKey == "" {
+		u.ShadowsocksKey = GenerateShadowsocksKey()
+	}
 	if u.SubscriptionToken == "" {
 		u.SubscriptionToken = GenerateSubscriptionToken()
 	}
@@ -114,18 +144,23 @@ func UpdateUser(u *User) error {
 
 // ReplaceUserInbounds replaces user's inbound associations.
 func ReplaceUserInbounds(userID uint, inboundIDs []uint) error {
-	user, err := GetUserByID(userID)
+	return Conn{}.ReplaceUserInbounds(userID, inboundIDs)
+}
+
+// ReplaceUserInbounds runs ReplaceUserInbounds on c.
+func (c Conn) ReplaceUserInbounds(userID uint, inboundIDs []uint) error {
+	user, err := c.GetUserByID(userID)
 	if err != nil {
 		return err
 	}
 	var inbounds []Inbound
 	if len(inboundIDs) > 0 {
-		inbounds, err = GetInboundsByIDs(inboundIDs)
+		inbounds, err = c.GetInboundsByIDs(inboundIDs)
 		if err != nil {
 			return err
 		}
 	}
-	return DB.Model(user).Association("Inbounds").Replace(inbounds)
+	return c.db().Model(user).Association("Inbounds").Replace(inbounds)
 }
 
 // DeleteUser deletes a user.
@@ -142,8 +177,13 @@ func DeleteUser(id uint) error {
 // Enabled == true, TrafficLimit == 0 OR TrafficUsed < TrafficLimit,
 // ExpireAt == nil OR ExpireAt.After(time.Now().UTC()).
 func GetUsersForInbound(inboundID uint) ([]User, error) {
+	return Conn{}.GetUsersForInbound(inboundID)
+}
+
+// GetUsersForInbound runs GetUsersForInbound on c.
+func (c Conn) GetUsersForInbound(inboundID uint) ([]User, error) {
 	var users []User
-	err := DB.Preload("Inbounds").Joins(
+	err := c.db().Preload("Inbounds").Joins(
 		"JOIN user_inbounds ON user_inbounds.user_id = users.id AND user_inbounds.inbound_id = ?",
 		inboundID,
 	).Find(&users).Error
@@ -166,3 +206,29 @@ func GetUsersForInbound(inboundID uint) ([]User, error) {
 	}
 	return valid, nil
 }
+
+// ListUsersWithPolicy returns users bound to an egress outbound or a routing profile, by ID.
+func ListUsersWithPolicy() ([]User, error) {
+	return Conn{}.ListUsersWithPolicy()
+}
+
+// ListUsersWithPolicy runs ListUsersWithPolicy on c.
+func (c Conn) ListUsersWithPolicy() ([]User, error) {
+	var users []User
+	err := c.db().Where("COALESCE(outbound, '') <> '' OR routing_profile_id IS NOT NULL").Order("id ASC").Find(&users).Error
+	return users, err
+}
+
+// UserNamesByOutbound returns names of users whose egress is outbound tag.
+func UserNamesByOutbound(tag string) ([]string, error) {
+	var names []string
+	err := DB.Model(&User{}).Where("outbound = ?", tag).Order("id ASC").Pluck("name", &names).Error
+	return names, err
+}
+
+// UserNamesByRoutingProfile returns names of users bound to routing profile id.
+func UserNamesByRoutingProfile(id uint) ([]string, error) {
+	var names []string
+	err := DB.Model(&User{}).Where("routing_profile_id = ?", id).Order("id ASC").Pluck("name", &names).Error
+	return names, err
+}
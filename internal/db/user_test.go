@@ -32,3 +32,40 @@ func TestCreateUser_SubscriptionToken(t *testing.T) {
 		t.Errorf("GetUserBySubscriptionToken: got user %v, want %v", found, u)
 	}
 }
+
+func TestShadowsocksKeyStored(t *testing.T) {
+	if err := Init(":memory:"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	u := &User{Name: "ss-key-user"}
+	if err := CreateUser(u); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if u.ShadowsocksKey == "" {
+		t.Fatal("CreateUser should set ShadowsocksKey")
+	}
+	key := u.ShadowsocksKey
+	u.Password = "rotated"
+	if err := UpdateUser(u); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	got, err := GetUserByID(u.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if got.ShadowsocksKey != key {
+		t.Errorf("ShadowsocksKey after password change = %q, want %q", got.ShadowsocksKey, key)
+	}
+
+	// Users stored before the column existed get the key earlier versions derived.
+	if err := DB.Model(&User{}).Where("id = ?", u.ID).Update("shadowsocks_key", "").Error; err != nil {
+		t.Fatalf("clear key: %v", err)
+	}
+	if err := backfillShadowsocksKeys(); err != nil {
+		t.Fatalf("backfillShadowsocksKeys: %v", err)
+	}
+	if got, _ = GetUserByID(u.ID); got.ShadowsocksKey != legacyShadowsocksKey("rotated") {
+		t.Errorf("backfilled ShadowsocksKey = %q, want legacy key", got.ShadowsocksKey)
+	}
+}
@@ -693,7 +693,7 @@ export function InboundFormModal({
                     <div className="space-y-2 sm:col-span-2">
                       <FieldLabel
                         label="private_key"
-                        tooltip="Reality 私钥。由 sing-box generate reality-keypair 生成。"
+                        tooltip="Reality 私钥。留空时由面板自动生成 x25519 密钥对，公钥随入站保存并写入订阅。"
                       />
                       <Input
                         id="reality_private_key"
@@ -705,7 +705,7 @@ export function InboundFormModal({
                     <div className="space-y-2">
                       <FieldLabel
                         label="short_id"
-                        tooltip="Reality short_id。16 位十六进制。"
+                        tooltip="Reality short_id。最多 16 位十六进制，留空时自动生成。"
                       />
                       <Input
                         id="reality_short_id"